@@ -0,0 +1,33 @@
+package academic
+
+import (
+	"fmt"
+	"time"
+)
+
+// StartMonth is the month in which an academic year begins
+const StartMonth = time.September
+
+// Year returns the academic year containing t in the form "2024/25"
+func Year(t time.Time) string {
+	start := t.Year()
+	if t.Month() < StartMonth {
+		start--
+	}
+	return fmt.Sprintf("%d/%02d", start, (start+1)%100)
+}
+
+// Current returns the academic year containing the current date
+func Current() string {
+	return Year(time.Now())
+}
+
+// Start returns the first day of the academic year given in the form "2024/25"
+func Start(year string) (time.Time, error) {
+	var start, end int
+	_, err := fmt.Sscanf(year, "%d/%d", &start, &end)
+	if err != nil || (start+1)%100 != end {
+		return time.Time{}, fmt.Errorf("invalid academic year %q, expected the form 2024/25", year)
+	}
+	return time.Date(start, StartMonth, 1, 0, 0, 0, 0, time.UTC), nil
+}
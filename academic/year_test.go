@@ -0,0 +1,25 @@
+package academic
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestYear(t *testing.T) {
+	assert.Equal(t, "2024/25", Year(time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2024/25", Year(time.Date(2025, time.August, 31, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2023/24", Year(time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "1999/00", Year(time.Date(1999, time.December, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestStart(t *testing.T) {
+	start, err := Start("2024/25")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC), start)
+
+	_, err = Start("2024/26")
+	assert.NotNil(t, err)
+	_, err = Start("next year")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,136 @@
+package allocation
+
+import (
+	"slices"
+	"sort"
+)
+
+// Student is an allocation participant ranking supervisors in order of preference
+type Student struct {
+	ID          string
+	Preferences []string // supervisor IDs, most preferred first
+}
+
+// Supervisor accepts up to Capacity students, preferring them in Ranking order. Students missing from the
+// ranking are still acceptable but come after every ranked student, ordered by ID.
+type Supervisor struct {
+	ID       string
+	Capacity int
+	Ranking  []string // student IDs, most preferred first
+}
+
+type Assignment struct {
+	StudentID    string
+	SupervisorID string
+	StudentRank  int // 1 based position of the supervisor in the student's preferences
+}
+
+type Result struct {
+	Assignments []Assignment
+	Unassigned  []string // students who could not be placed with any supervisor they ranked
+}
+
+// Match computes the student optimal stable matching using deferred acceptance: students propose to
+// supervisors in preference order and supervisors provisionally hold their best proposals up to capacity.
+// No student and supervisor would both rather be matched with each other than with their result.
+// The outcome is deterministic for a given input.
+func Match(students []Student, supervisors []Supervisor) Result {
+	type holder struct {
+		capacity int
+		rank     map[string]int
+		held     []string
+	}
+
+	holders := make(map[string]*holder, len(supervisors))
+	for _, supervisor := range supervisors {
+		h := &holder{
+			capacity: supervisor.Capacity,
+			rank:     make(map[string]int, len(supervisor.Ranking)),
+		}
+		for i, studentID := range supervisor.Ranking {
+			if _, ok := h.rank[studentID]; !ok {
+				h.rank[studentID] = i
+			}
+		}
+		holders[supervisor.ID] = h
+	}
+
+	prefers := func(h *holder, a, b string) bool {
+		rankA, okA := h.rank[a]
+		rankB, okB := h.rank[b]
+		switch {
+		case okA && okB:
+			return rankA < rankB
+		case okA != okB:
+			return okA
+		default:
+			return a < b
+		}
+	}
+
+	preferences := make(map[string][]string, len(students))
+	next := make(map[string]int, len(students))
+	free := make([]string, 0, len(students))
+	for _, student := range students {
+		preferences[student.ID] = student.Preferences
+		free = append(free, student.ID)
+	}
+	slices.Sort(free)
+
+	var unassigned []string
+	for len(free) > 0 {
+		studentID := free[0]
+		free = free[1:]
+
+		if next[studentID] >= len(preferences[studentID]) {
+			unassigned = append(unassigned, studentID)
+			continue
+		}
+		supervisorID := preferences[studentID][next[studentID]]
+		next[studentID]++
+
+		h, ok := holders[supervisorID]
+		if !ok || h.capacity <= 0 {
+			free = append(free, studentID)
+			continue
+		}
+
+		h.held = append(h.held, studentID)
+		sort.SliceStable(h.held, func(i, j int) bool {
+			return prefers(h, h.held[i], h.held[j])
+		})
+		if len(h.held) > h.capacity {
+			rejected := h.held[len(h.held)-1]
+			h.held = h.held[:len(h.held)-1]
+			free = append(free, rejected)
+		}
+	}
+
+	result := Result{
+		Assignments: []Assignment{},
+		Unassigned:  []string{},
+	}
+	for _, student := range students {
+		supervisorID := ""
+		for _, candidate := range student.Preferences[:next[student.ID]] {
+			if h, ok := holders[candidate]; ok && slices.Contains(h.held, student.ID) {
+				supervisorID = candidate
+				break
+			}
+		}
+		if supervisorID == "" {
+			continue
+		}
+		result.Assignments = append(result.Assignments, Assignment{
+			StudentID:    student.ID,
+			SupervisorID: supervisorID,
+			StudentRank:  slices.Index(student.Preferences, supervisorID) + 1,
+		})
+	}
+	sort.Slice(result.Assignments, func(i, j int) bool {
+		return result.Assignments[i].StudentID < result.Assignments[j].StudentID
+	})
+	slices.Sort(unassigned)
+	result.Unassigned = append(result.Unassigned, unassigned...)
+	return result
+}
@@ -0,0 +1,136 @@
+package allocation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+)
+
+func TestMatchRespectsCapacityAndRankings(t *testing.T) {
+	students := []Student{
+		{ID: "s1", Preferences: []string{"alice", "bob"}},
+		{ID: "s2", Preferences: []string{"alice", "bob"}},
+		{ID: "s3", Preferences: []string{"alice"}},
+	}
+	supervisors := []Supervisor{
+		{ID: "alice", Capacity: 1, Ranking: []string{"s2", "s1", "s3"}},
+		{ID: "bob", Capacity: 2},
+	}
+
+	result := Match(students, supervisors)
+
+	assert.Equal(t, []Assignment{
+		{StudentID: "s1", SupervisorID: "bob", StudentRank: 2},
+		{StudentID: "s2", SupervisorID: "alice", StudentRank: 1},
+	}, result.Assignments)
+	assert.Equal(t, []string{"s3"}, result.Unassigned)
+	assertStable(t, students, supervisors, result)
+}
+
+func TestMatchPrefersRankedStudents(t *testing.T) {
+	students := []Student{
+		{ID: "a", Preferences: []string{"carol"}},
+		{ID: "b", Preferences: []string{"carol"}},
+		{ID: "c", Preferences: []string{"carol"}},
+	}
+	supervisors := []Supervisor{
+		{ID: "carol", Capacity: 2, Ranking: []string{"c"}},
+	}
+
+	result := Match(students, supervisors)
+
+	assert.Equal(t, []Assignment{
+		{StudentID: "a", SupervisorID: "carol", StudentRank: 1},
+		{StudentID: "c", SupervisorID: "carol", StudentRank: 1},
+	}, result.Assignments)
+	assert.Equal(t, []string{"b"}, result.Unassigned)
+}
+
+func TestMatchIgnoresUnknownAndFullSupervisors(t *testing.T) {
+	students := []Student{
+		{ID: "s1", Preferences: []string{"ghost", "full", "dave"}},
+	}
+	supervisors := []Supervisor{
+		{ID: "full", Capacity: 0},
+		{ID: "dave", Capacity: 1},
+	}
+
+	result := Match(students, supervisors)
+
+	assert.Equal(t, []Assignment{{StudentID: "s1", SupervisorID: "dave", StudentRank: 3}}, result.Assignments)
+	assert.Empty(t, result.Unassigned)
+}
+
+func TestMatchIsStableForLargerInstance(t *testing.T) {
+	students := []Student{
+		{ID: "s1", Preferences: []string{"x", "y", "z"}},
+		{ID: "s2", Preferences: []string{"x", "z"}},
+		{ID: "s3", Preferences: []string{"y", "x", "z"}},
+		{ID: "s4", Preferences: []string{"x", "y"}},
+		{ID: "s5", Preferences: []string{"z", "y", "x"}},
+		{ID: "s6", Preferences: []string{"x", "y", "z"}},
+	}
+	supervisors := []Supervisor{
+		{ID: "x", Capacity: 2, Ranking: []string{"s6", "s3", "s1", "s2", "s4", "s5"}},
+		{ID: "y", Capacity: 1, Ranking: []string{"s1", "s4", "s3"}},
+		{ID: "z", Capacity: 2, Ranking: []string{"s2", "s5", "s1"}},
+	}
+
+	result := Match(students, supervisors)
+
+	assertStable(t, students, supervisors, result)
+	assert.Equal(t, result, Match(students, supervisors))
+}
+
+// assertStable checks capacities and that no student and supervisor would both prefer each other
+func assertStable(t *testing.T, students []Student, supervisors []Supervisor, result Result) {
+	t.Helper()
+
+	assigned := map[string]string{}
+	held := map[string][]string{}
+	for _, assignment := range result.Assignments {
+		assigned[assignment.StudentID] = assignment.SupervisorID
+		held[assignment.SupervisorID] = append(held[assignment.SupervisorID], assignment.StudentID)
+	}
+
+	rank := func(supervisor Supervisor, studentID string) int {
+		if i := slices.Index(supervisor.Ranking, studentID); i >= 0 {
+			return i
+		}
+		return len(supervisor.Ranking)
+	}
+	worse := func(supervisor Supervisor, a, b string) bool {
+		ra, rb := rank(supervisor, a), rank(supervisor, b)
+		if ra != rb {
+			return ra > rb
+		}
+		return a > b
+	}
+
+	for _, supervisor := range supervisors {
+		if len(held[supervisor.ID]) > supervisor.Capacity {
+			t.Errorf("supervisor %s is over capacity", supervisor.ID)
+		}
+	}
+
+	for _, student := range students {
+		for _, supervisorID := range student.Preferences {
+			if assigned[student.ID] == supervisorID {
+				break
+			}
+			i := slices.IndexFunc(supervisors, func(s Supervisor) bool { return s.ID == supervisorID })
+			if i < 0 {
+				continue
+			}
+			supervisor := supervisors[i]
+			if len(held[supervisor.ID]) < supervisor.Capacity {
+				t.Errorf("student %s prefers %s which has free capacity", student.ID, supervisor.ID)
+			}
+			for _, other := range held[supervisor.ID] {
+				if worse(supervisor, other, student.ID) {
+					t.Errorf("student %s and supervisor %s form a blocking pair", student.ID, supervisor.ID)
+				}
+			}
+		}
+	}
+}
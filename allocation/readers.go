@@ -0,0 +1,122 @@
+package allocation
+
+import (
+	"slices"
+	"sort"
+)
+
+// Reader is a staff member who can be given projects to second read
+type Reader struct {
+	ID        string
+	Load      int      // projects the reader already supervises or reads
+	Expertise []string // topic tags, lower case
+	Conflicts []string // users whose projects the reader must not read
+}
+
+// ReadingProject is a project that still needs a second reader
+type ReadingProject struct {
+	ID      string
+	Members []string // everybody on the project, none of them can read it
+	Tags    []string // topic tags of the project, lower case
+}
+
+type ReaderAssignment struct {
+	ProjectID string
+	ReaderID  string
+	Matched   []string // tags shared by the project and the reader's expertise
+}
+
+type ReaderResult struct {
+	Assignments []ReaderAssignment
+	Unassigned  []string // projects no reader is eligible for
+}
+
+// AssignReaders gives every project a second reader, keeping the total load of supervising and reading as
+// even as possible. A reader is never assigned to a project they are a member of or have a conflict with.
+// Projects with the fewest eligible readers are placed first, each going to the reader with the lowest load,
+// where a reader sharing a topic with the project may carry one project more than the others.
+// The outcome is deterministic for a given input.
+func AssignReaders(projects []ReadingProject, readers []Reader) ReaderResult {
+	load := make(map[string]int, len(readers))
+	for _, reader := range readers {
+		load[reader.ID] = reader.Load
+	}
+
+	eligible := make(map[string][]Reader, len(projects))
+	for _, project := range projects {
+		for _, reader := range readers {
+			if !slices.Contains(project.Members, reader.ID) && !conflicts(reader, project) {
+				eligible[project.ID] = append(eligible[project.ID], reader)
+			}
+		}
+	}
+
+	order := slices.Clone(projects)
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := len(eligible[order[i].ID]), len(eligible[order[j].ID])
+		if a != b {
+			return a < b
+		}
+		return order[i].ID < order[j].ID
+	})
+
+	result := ReaderResult{
+		Assignments: []ReaderAssignment{},
+		Unassigned:  []string{},
+	}
+	for _, project := range order {
+		var (
+			best    *Reader
+			matched []string
+			score   int
+		)
+		for i, reader := range eligible[project.ID] {
+			shared := sharedTags(project.Tags, reader.Expertise)
+			candidate := load[reader.ID]
+			if len(shared) > 0 {
+				candidate--
+			}
+			better := best == nil || candidate < score ||
+				candidate == score && len(shared) > len(matched) ||
+				candidate == score && len(shared) == len(matched) && reader.ID < best.ID
+			if better {
+				best, matched, score = &eligible[project.ID][i], shared, candidate
+			}
+		}
+		if best == nil {
+			result.Unassigned = append(result.Unassigned, project.ID)
+			continue
+		}
+		load[best.ID]++
+		result.Assignments = append(result.Assignments, ReaderAssignment{
+			ProjectID: project.ID,
+			ReaderID:  best.ID,
+			Matched:   matched,
+		})
+	}
+
+	sort.Slice(result.Assignments, func(i, j int) bool {
+		return result.Assignments[i].ProjectID < result.Assignments[j].ProjectID
+	})
+	slices.Sort(result.Unassigned)
+	return result
+}
+
+func conflicts(reader Reader, project ReadingProject) bool {
+	for _, userID := range reader.Conflicts {
+		if slices.Contains(project.Members, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+func sharedTags(tags []string, expertise []string) []string {
+	shared := []string{}
+	for _, tag := range tags {
+		if slices.Contains(expertise, tag) {
+			shared = append(shared, tag)
+		}
+	}
+	return shared
+}
@@ -0,0 +1,98 @@
+package allocation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAssignReadersBalancesLoad(t *testing.T) {
+	projects := []ReadingProject{
+		{ID: "p1", Members: []string{"alice", "s1"}},
+		{ID: "p2", Members: []string{"alice", "s2"}},
+		{ID: "p3", Members: []string{"bob", "s3"}},
+		{ID: "p4", Members: []string{"carol", "s4"}},
+	}
+	readers := []Reader{
+		{ID: "alice", Load: 2},
+		{ID: "bob", Load: 1},
+		{ID: "carol", Load: 1},
+		{ID: "dave", Load: 4},
+	}
+
+	result := AssignReaders(projects, readers)
+
+	assert.Equal(t, []ReaderAssignment{
+		{ProjectID: "p1", ReaderID: "bob", Matched: []string{}},
+		{ProjectID: "p2", ReaderID: "carol", Matched: []string{}},
+		{ProjectID: "p3", ReaderID: "alice", Matched: []string{}},
+		{ProjectID: "p4", ReaderID: "bob", Matched: []string{}},
+	}, result.Assignments)
+	assert.Empty(t, result.Unassigned)
+}
+
+func TestAssignReadersPrefersExpertise(t *testing.T) {
+	projects := []ReadingProject{
+		{ID: "p1", Members: []string{"alice"}, Tags: []string{"compilers", "go"}},
+	}
+	readers := []Reader{
+		{ID: "alice"},
+		{ID: "bob", Load: 0, Expertise: []string{"databases"}},
+		{ID: "carol", Load: 1, Expertise: []string{"go"}},
+		{ID: "dave", Load: 1, Expertise: []string{"go", "compilers"}},
+	}
+
+	result := AssignReaders(projects, readers)
+
+	assert.Equal(t, []ReaderAssignment{{ProjectID: "p1", ReaderID: "dave", Matched: []string{"compilers", "go"}}}, result.Assignments)
+}
+
+func TestAssignReadersExpertiseDoesNotOutweighBalance(t *testing.T) {
+	projects := []ReadingProject{
+		{ID: "p1", Members: []string{"alice"}, Tags: []string{"go"}},
+	}
+	readers := []Reader{
+		{ID: "alice"},
+		{ID: "bob", Load: 0},
+		{ID: "carol", Load: 2, Expertise: []string{"go"}},
+	}
+
+	result := AssignReaders(projects, readers)
+
+	assert.Equal(t, "bob", result.Assignments[0].ReaderID)
+}
+
+func TestAssignReadersRespectsConflicts(t *testing.T) {
+	projects := []ReadingProject{
+		{ID: "p1", Members: []string{"alice", "s1"}},
+		{ID: "p2", Members: []string{"bob", "s2"}},
+	}
+	readers := []Reader{
+		{ID: "alice", Conflicts: []string{"s2"}},
+		{ID: "bob", Conflicts: []string{"alice"}},
+	}
+
+	result := AssignReaders(projects, readers)
+
+	assert.Empty(t, result.Assignments)
+	assert.Equal(t, []string{"p1", "p2"}, result.Unassigned)
+}
+
+func TestAssignReadersPlacesConstrainedProjectsFirst(t *testing.T) {
+	projects := []ReadingProject{
+		{ID: "p1", Members: []string{"alice"}},
+		{ID: "p2", Members: []string{"alice", "s2"}},
+	}
+	readers := []Reader{
+		{ID: "alice"},
+		{ID: "bob"},
+		{ID: "carol", Conflicts: []string{"s2"}},
+	}
+
+	result := AssignReaders(projects, readers)
+
+	// p2 can only go to bob, so p1 must not take him first
+	assert.Equal(t, []ReaderAssignment{
+		{ProjectID: "p1", ReaderID: "carol", Matched: []string{}},
+		{ProjectID: "p2", ReaderID: "bob", Matched: []string{}},
+	}, result.Assignments)
+}
@@ -0,0 +1,166 @@
+// Package calendar writes iCalendar (RFC 5545) documents for interviews and deadlines so that they can be
+// imported into any calendar application.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Method is the iTIP method of a calendar, it tells the client how to treat the events
+type Method string
+
+const (
+	Publish Method = "PUBLISH" // feeds and downloads, there are no email addresses to send invites as REQUEST
+)
+
+const (
+	productID  = "-//fyp//project management//EN"
+	lineLength = 75 // octets, longer content lines are folded
+	timeFormat = "20060102T150405Z"
+	dateFormat = "20060102"
+)
+
+// Event is a single VEVENT. All day events use the date of Start and End only, End is exclusive.
+type Event struct {
+	UID         string
+	Sequence    int // increase when the event changes so clients replace their copy
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Cancelled   bool
+	Alarms      []Alarm
+}
+
+// Alarm is a VALARM that reminds of an event, Before is measured from the start of the event or from its end
+type Alarm struct {
+	Before      time.Duration
+	FromEnd     bool
+	Description string
+}
+
+// Calendar is a VCALENDAR holding a list of events
+type Calendar struct {
+	Name   string // shown by clients subscribing to a feed, optional
+	Method Method
+	Events []Event
+}
+
+// Marshal encodes the calendar with CRLF line endings and folded lines as required by RFC 5545
+func (c Calendar) Marshal() []byte {
+	var w writer
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", productID)
+	w.line("CALSCALE", "GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD", string(c.Method))
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, event := range c.Events {
+		w.event(event)
+	}
+	w.line("END", "VCALENDAR")
+	return []byte(w.String())
+}
+
+type writer struct {
+	strings.Builder
+}
+
+func (w *writer) event(event Event) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", event.UID)
+	w.line("SEQUENCE", fmt.Sprint(event.Sequence))
+	w.line("DTSTAMP", event.Stamp.UTC().Format(timeFormat))
+	if event.AllDay {
+		w.line("DTSTART;VALUE=DATE", event.Start.Format(dateFormat))
+		w.line("DTEND;VALUE=DATE", event.End.Format(dateFormat))
+	} else {
+		w.line("DTSTART", event.Start.UTC().Format(timeFormat))
+		w.line("DTEND", event.End.UTC().Format(timeFormat))
+	}
+	w.line("SUMMARY", escape(event.Summary))
+	if event.Description != "" {
+		w.line("DESCRIPTION", escape(event.Description))
+	}
+	if event.Location != "" {
+		w.line("LOCATION", escape(event.Location))
+	}
+	if event.URL != "" {
+		w.line("URL", event.URL)
+	}
+	if event.Cancelled {
+		w.line("STATUS", "CANCELLED")
+	} else {
+		w.line("STATUS", "CONFIRMED")
+	}
+	for _, alarm := range event.Alarms {
+		w.line("BEGIN", "VALARM")
+		w.line("ACTION", "DISPLAY")
+		w.line("DESCRIPTION", escape(alarm.Description))
+		if alarm.FromEnd {
+			w.line("TRIGGER;RELATED=END", "-"+duration(alarm.Before))
+		} else {
+			w.line("TRIGGER", "-"+duration(alarm.Before))
+		}
+		w.line("END", "VALARM")
+	}
+	w.line("END", "VEVENT")
+}
+
+// duration encodes a positive duration as a dur-value such as P1D or PT2H30M, seconds are dropped
+func duration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	value := "P"
+	if days > 0 {
+		value += fmt.Sprintf("%dD", days)
+	}
+	if hours > 0 || minutes > 0 || days == 0 {
+		value += "T"
+		if hours > 0 {
+			value += fmt.Sprintf("%dH", hours)
+		}
+		if minutes > 0 || hours == 0 {
+			value += fmt.Sprintf("%dM", minutes)
+		}
+	}
+	return value
+}
+
+// line writes a content line, folding it after 75 octets without splitting a UTF-8 sequence
+func (w *writer) line(name string, value string) {
+	content := name + ":" + value
+	limit := lineLength
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.WriteString(content[:cut])
+		w.WriteString("\r\n ")
+		content = content[cut:]
+		limit = lineLength - 1 // the leading space of a continuation line counts
+	}
+	w.WriteString(content)
+	w.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape encodes a TEXT value
+func escape(value string) string {
+	return escaper.Replace(value)
+}
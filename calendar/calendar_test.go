@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Marshal(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	c := Calendar{
+		Method: Publish,
+		Events: []Event{{
+			UID:         "interview-1@fyp",
+			Sequence:    2,
+			Stamp:       start.Add(-time.Hour),
+			Start:       start,
+			End:         start.Add(30 * time.Minute),
+			Summary:     "Interview: Graphs, trees; and more",
+			Description: "line one\nline two",
+		}},
+	}
+
+	expected := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//fyp//project management//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"METHOD:PUBLISH\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:interview-1@fyp\r\n" +
+		"SEQUENCE:2\r\n" +
+		"DTSTAMP:20240304T090000Z\r\n" +
+		"DTSTART:20240304T100000Z\r\n" +
+		"DTEND:20240304T103000Z\r\n" +
+		"SUMMARY:Interview: Graphs\\, trees\\; and more\r\n" +
+		"DESCRIPTION:line one\\nline two\r\n" +
+		"STATUS:CONFIRMED\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	assert.Equal(t, expected, string(c.Marshal()))
+}
+
+func TestCalendar_MarshalAllDay(t *testing.T) {
+	c := Calendar{Events: []Event{{
+		UID:    "milestone-1@fyp",
+		Start:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		AllDay: true,
+	}}}
+
+	document := string(c.Marshal())
+	assert.Contains(t, document, "DTSTART;VALUE=DATE:20240501\r\n")
+	assert.Contains(t, document, "DTEND;VALUE=DATE:20240502\r\n")
+	assert.NotContains(t, document, "METHOD")
+}
+
+func TestCalendar_MarshalFoldsLongLines(t *testing.T) {
+	c := Calendar{Events: []Event{{Summary: strings.Repeat("é", 100)}}}
+
+	for _, line := range strings.Split(string(c.Marshal()), "\r\n") {
+		assert.LessOrEqual(t, len(line), lineLength)
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "SUMMARY") {
+			assert.True(t, strings.ToValidUTF8(line, "?") == line, "folded inside a character: %q", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(string(c.Marshal()), "\r\n ", "")
+	assert.Contains(t, unfolded, "SUMMARY:"+strings.Repeat("é", 100)+"\r\n")
+}
+
+func TestCalendar_MarshalAlarms(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	c := Calendar{Events: []Event{{
+		UID:   "gantt-1@fyp",
+		Start: start,
+		End:   start.AddDate(0, 0, 7),
+		URL:   "https://example.org/plan",
+		Alarms: []Alarm{
+			{Before: 24 * time.Hour, FromEnd: true, Description: "Due tomorrow"},
+			{Before: 90 * time.Minute, Description: "Starts soon"},
+		},
+	}}}
+
+	document := string(c.Marshal())
+	assert.Contains(t, document, "URL:https://example.org/plan\r\n")
+	assert.Contains(t, document, "BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Due tomorrow\r\nTRIGGER;RELATED=END:-P1D\r\nEND:VALARM\r\n")
+	assert.Contains(t, document, "TRIGGER:-PT1H30M\r\n")
+}
+
+func TestDuration(t *testing.T) {
+	assert.Equal(t, "P7D", duration(7*24*time.Hour))
+	assert.Equal(t, "P1DT2H", duration(26*time.Hour))
+	assert.Equal(t, "PT15M", duration(15*time.Minute))
+	assert.Equal(t, "PT0M", duration(0))
+}
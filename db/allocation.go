@@ -0,0 +1,360 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/allocation"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// ErrInvalidPreferences is returned when a ranking refers to users who cannot be ranked
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// ErrAllocationRunNotFound is returned when the allocation run does not exist
+var ErrAllocationRunNotFound = errors.New("allocation run not found")
+
+// ErrAllocationCommitted is returned when committing an allocation run a second time
+var ErrAllocationCommitted = errors.New("allocation run already committed")
+
+// ErrStudentAlreadyPlaced is returned when committing an allocation for a student who got a project in the meantime
+var ErrStudentAlreadyPlaced = errors.New("student already has a project")
+
+func (db Client) SetStudentPreferences(ctx context.Context, studentID string, supervisorIDs []string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update preferences: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRowContext(ctx, "SELECT count(*) FROM users WHERE is_supervisor = true AND id = ANY($1)", supervisorIDs).Scan(&found)
+	if err != nil {
+		log.Printf("cannot validate preferred supervisors: %v", err)
+		return err
+	}
+	if found != len(supervisorIDs) {
+		return fmt.Errorf("%w: preferences may only contain supervisors", ErrInvalidPreferences)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM student_preferences WHERE student_id = $1", studentID)
+	if err != nil {
+		log.Printf("failed to clear preferences: %v", err)
+		return err
+	}
+	for i, supervisorID := range supervisorIDs {
+		_, err = tx.ExecContext(ctx, "INSERT INTO student_preferences (student_id, supervisor_id, rank) VALUES ($1, $2, $3)", studentID, supervisorID, i+1)
+		if err != nil {
+			log.Printf("failed to add preference: %v", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db Client) GetStudentPreferences(ctx context.Context, studentID string) ([]model.Preference, error) {
+	query := `SELECT p.rank, p.supervisor_id, u.name
+FROM student_preferences p INNER JOIN users u
+    ON p.supervisor_id = u.id
+WHERE p.student_id = $1
+ORDER BY p.rank`
+	return db.getRanking(ctx, query, studentID)
+}
+
+func (db Client) SetApplicantRanking(ctx context.Context, supervisorID string, studentIDs []string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update ranking: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT count(*) FROM users u
+WHERE u.id = ANY($2)
+  AND (EXISTS (SELECT 1 FROM applications a WHERE a.student_id = u.id AND a.supervisor_id = $1)
+    OR EXISTS (SELECT 1 FROM student_preferences p WHERE p.student_id = u.id AND p.supervisor_id = $1))`
+	var found int
+	err = tx.QueryRowContext(ctx, query, supervisorID, studentIDs).Scan(&found)
+	if err != nil {
+		log.Printf("cannot validate ranked students: %v", err)
+		return err
+	}
+	if found != len(studentIDs) {
+		return fmt.Errorf("%w: only students who applied to or ranked the supervisor can be ranked", ErrInvalidPreferences)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM supervisor_rankings WHERE supervisor_id = $1", supervisorID)
+	if err != nil {
+		log.Printf("failed to clear ranking: %v", err)
+		return err
+	}
+	for i, studentID := range studentIDs {
+		_, err = tx.ExecContext(ctx, "INSERT INTO supervisor_rankings (supervisor_id, student_id, rank) VALUES ($1, $2, $3)", supervisorID, studentID, i+1)
+		if err != nil {
+			log.Printf("failed to add ranking: %v", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db Client) GetApplicantRanking(ctx context.Context, supervisorID string) ([]model.Preference, error) {
+	query := `SELECT r.rank, r.student_id, u.name
+FROM supervisor_rankings r INNER JOIN users u
+    ON r.student_id = u.id
+WHERE r.supervisor_id = $1
+ORDER BY r.rank`
+	return db.getRanking(ctx, query, supervisorID)
+}
+
+func (db Client) getRanking(ctx context.Context, query string, userID string) ([]model.Preference, error) {
+	rows, err := db.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("cannot execute query to get ranking: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Preference{}
+	var (
+		rank int
+		id   string
+		name string
+	)
+	for rows.Next() {
+		err = rows.Scan(&rank, &id, &name)
+		if err != nil {
+			log.Printf("cannot read data while getting ranking: %v", err)
+			return nil, err
+		}
+		result = append(result, model.Preference{
+			Rank:   rank,
+			UserID: id,
+			Name:   name,
+		})
+	}
+	return result, nil
+}
+
+// GetAllocationInput loads the students still looking for a project with their preferences and every
+// supervisor with their ranking and remaining capacity
+func (db Client) GetAllocationInput(ctx context.Context) ([]allocation.Student, []allocation.Supervisor, error) {
+	query := `SELECT p.student_id, p.supervisor_id
+FROM student_preferences p INNER JOIN users u
+    ON p.student_id = u.id
+WHERE u.has_project = false
+ORDER BY p.student_id, p.rank`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		log.Printf("cannot execute query to get student preferences: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	students := []allocation.Student{}
+	var studentID, supervisorID string
+	for rows.Next() {
+		err = rows.Scan(&studentID, &supervisorID)
+		if err != nil {
+			log.Printf("cannot read data while getting student preferences: %v", err)
+			return nil, nil, err
+		}
+		if len(students) == 0 || students[len(students)-1].ID != studentID {
+			students = append(students, allocation.Student{ID: studentID})
+		}
+		last := &students[len(students)-1]
+		last.Preferences = append(last.Preferences, supervisorID)
+	}
+
+	query = fmt.Sprintf("SELECT u.id, %s FROM %s WHERE u.is_supervisor = true ORDER BY u.id", capacityColumns("$1"), capacityJoin("$1"))
+	supervisorRows, err := db.conn.QueryContext(ctx, query, academic.Current())
+	if err != nil {
+		log.Printf("cannot execute query to get supervisor capacity: %v", err)
+		return nil, nil, err
+	}
+	defer supervisorRows.Close()
+
+	supervisors := []allocation.Supervisor{}
+	index := map[string]int{}
+	var capacity, remaining int
+	for supervisorRows.Next() {
+		err = supervisorRows.Scan(&supervisorID, &capacity, &remaining)
+		if err != nil {
+			log.Printf("cannot read data while getting supervisor capacity: %v", err)
+			return nil, nil, err
+		}
+		index[supervisorID] = len(supervisors)
+		supervisors = append(supervisors, allocation.Supervisor{ID: supervisorID, Capacity: max(remaining, 0)})
+	}
+
+	rankingRows, err := db.conn.QueryContext(ctx, "SELECT supervisor_id, student_id FROM supervisor_rankings ORDER BY supervisor_id, rank")
+	if err != nil {
+		log.Printf("cannot execute query to get supervisor rankings: %v", err)
+		return nil, nil, err
+	}
+	defer rankingRows.Close()
+
+	for rankingRows.Next() {
+		err = rankingRows.Scan(&supervisorID, &studentID)
+		if err != nil {
+			log.Printf("cannot read data while getting supervisor rankings: %v", err)
+			return nil, nil, err
+		}
+		if i, ok := index[supervisorID]; ok {
+			supervisors[i].Ranking = append(supervisors[i].Ranking, studentID)
+		}
+	}
+
+	return students, supervisors, nil
+}
+
+func (db Client) SaveAllocationRun(ctx context.Context, createdBy string, result allocation.Result) (string, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to save allocation run: %v", err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	runID := GenerateUUID()
+	_, err = tx.ExecContext(ctx, "INSERT INTO allocation_runs (id, created_by) VALUES ($1, $2)", runID, createdBy)
+	if err != nil {
+		log.Printf("failed to create allocation run: %v", err)
+		return "", err
+	}
+
+	insert := "INSERT INTO allocation_assignments (run_id, student_id, supervisor_id, student_rank) VALUES ($1, $2, $3, $4)"
+	for _, assignment := range result.Assignments {
+		_, err = tx.ExecContext(ctx, insert, runID, assignment.StudentID, assignment.SupervisorID, assignment.StudentRank)
+		if err != nil {
+			log.Printf("failed to save allocation assignment: %v", err)
+			return "", err
+		}
+	}
+	for _, studentID := range result.Unassigned {
+		_, err = tx.ExecContext(ctx, insert, runID, studentID, nil, nil)
+		if err != nil {
+			log.Printf("failed to save unassigned student: %v", err)
+			return "", err
+		}
+	}
+	return runID, tx.Commit()
+}
+
+func (db Client) GetAllocationRun(ctx context.Context, runID string) (*model.AllocationRun, error) {
+	run := &model.AllocationRun{
+		ID:          runID,
+		Assignments: []model.AllocationAssignment{},
+		Unassigned:  []string{},
+	}
+
+	var (
+		createdAt   time.Time
+		committedAt sql.NullTime
+		committedBy sql.NullString
+	)
+	row := db.conn.QueryRowContext(ctx, "SELECT created_by, created_at, committed_by, committed_at FROM allocation_runs WHERE id = $1", runID)
+	err := row.Scan(&run.CreatedBy, &createdAt, &committedBy, &committedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAllocationRunNotFound
+		}
+		log.Printf("cannot read allocation run: %v", err)
+		return nil, err
+	}
+	createdAt = createdAt.UTC()
+	run.CreatedAt = &createdAt
+	if committedAt.Valid {
+		at := committedAt.Time.UTC()
+		run.CommittedAt = &at
+		run.CommittedBy = committedBy.String
+	}
+
+	rows, err := db.conn.QueryContext(ctx, "SELECT student_id, supervisor_id, student_rank FROM allocation_assignments WHERE run_id = $1 ORDER BY student_id", runID)
+	if err != nil {
+		log.Printf("cannot execute query to get allocation assignments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		studentID    string
+		supervisorID sql.NullString
+		rank         sql.NullInt64
+	)
+	for rows.Next() {
+		err = rows.Scan(&studentID, &supervisorID, &rank)
+		if err != nil {
+			log.Printf("cannot read data while getting allocation assignments: %v", err)
+			return nil, err
+		}
+		if !supervisorID.Valid {
+			run.Unassigned = append(run.Unassigned, studentID)
+			continue
+		}
+		run.Assignments = append(run.Assignments, model.AllocationAssignment{
+			StudentID:    studentID,
+			SupervisorID: supervisorID.String,
+			StudentRank:  int(rank.Int64),
+		})
+	}
+	return run, nil
+}
+
+// CommitAllocationRun creates the projects of an allocation run. Either every project is created or none is.
+func (db Client) CommitAllocationRun(ctx context.Context, runID string, committedBy string) error {
+	run, err := db.GetAllocationRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to commit allocation run: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var committedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT committed_at FROM allocation_runs WHERE id = $1 FOR UPDATE", runID).Scan(&committedAt)
+	if err != nil {
+		log.Printf("cannot lock allocation run: %v", err)
+		return err
+	}
+	if committedAt.Valid {
+		return ErrAllocationCommitted
+	}
+
+	for _, assignment := range run.Assignments {
+		var hasProject bool
+		err = tx.QueryRowContext(ctx, "SELECT has_project FROM users WHERE id = $1 FOR UPDATE", assignment.StudentID).Scan(&hasProject)
+		if err != nil {
+			log.Printf("cannot read project status of student: %v", err)
+			return err
+		}
+		if hasProject {
+			return fmt.Errorf("%w: %s", ErrStudentAlreadyPlaced, assignment.StudentID)
+		}
+
+		_, err = insertProject(ctx, tx, assignment.StudentID, assignment.SupervisorID, "")
+		if err != nil {
+			return err
+		}
+		err = closePendingApplications(ctx, tx, assignment.StudentID, "placed by allocation run "+runID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE allocation_runs SET committed_by = $1, committed_at = $2 WHERE id = $3", committedBy, time.Now(), runID)
+	if err != nil {
+		log.Printf("failed to mark allocation run as committed: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
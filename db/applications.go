@@ -0,0 +1,351 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// ErrApplicationNotFound is returned when the application does not exist
+var ErrApplicationNotFound = errors.New("application not found")
+
+// ErrNotApplicationParticipant is returned when the user is neither the student nor the supervisor of the application
+var ErrNotApplicationParticipant = errors.New("user is not a participant of the application")
+
+// TransitionApplication moves an application to a new status on behalf of actorID, an empty actorID means
+// the system. Accepting an application creates the project for it.
+func (db Client) TransitionApplication(ctx context.Context, appID string, to lifecycle.Status, actorID string, reason string) (*Application, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update application status: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	application, err := transitionApplication(ctx, tx, appID, to, actorID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return application, tx.Commit()
+}
+
+func transitionApplication(ctx context.Context, tx *sql.Tx, appID string, to lifecycle.Status, actorID string, reason string) (*Application, error) {
+	application, err := lockApplication(ctx, tx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := lifecycle.System
+	if actorID != "" {
+		switch actorID {
+		case application.StudentID:
+			actor = lifecycle.Student
+		case application.SupervisorID:
+			actor = lifecycle.Supervisor
+		default:
+			return nil, ErrNotApplicationParticipant
+		}
+	}
+
+	if err = lifecycle.Check(application.Status, to, actor); err != nil {
+		return nil, err
+	}
+	if to == lifecycle.Offered || to == lifecycle.Accepted {
+		open, err := openTeamInvites(ctx, tx, appID)
+		if err != nil {
+			return nil, err
+		}
+		if open > 0 {
+			return nil, ErrTeamIncomplete
+		}
+	}
+	switch {
+	case to == lifecycle.Offered:
+		if err = reserveSlot(ctx, tx, application.SupervisorID); err != nil {
+			return nil, err
+		}
+		if application.ProposalID != "" {
+			if err = reserveProposalSlot(ctx, tx, application.ProposalID); err != nil {
+				return nil, err
+			}
+		}
+	case application.Status == lifecycle.Draft && to == lifecycle.Submitted:
+		remaining, err := remainingCapacity(ctx, tx, application.SupervisorID, false)
+		if err != nil {
+			return nil, err
+		}
+		if remaining <= 0 {
+			return nil, ErrSupervisorFull
+		}
+		roundID, err := enterRound(ctx, tx, application.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE applications SET round_id = $1 WHERE id = $2", roundID, appID); err != nil {
+			log.Printf("failed to set the round of the application: %v", err)
+			return nil, err
+		}
+	}
+	if actor == lifecycle.Supervisor && to != lifecycle.Declined && application.ResponseDeadline != nil && time.Now().After(*application.ResponseDeadline) {
+		return nil, fmt.Errorf("%w: applications had to be answered by %s", ErrResponseDeadlinePassed, application.ResponseDeadline.Format(time.RFC3339))
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE applications SET status = $1, updated_at = now() WHERE id = $2", to, appID)
+	if err != nil {
+		log.Printf("failed to update application status: %v", err)
+		return nil, err
+	}
+
+	err = recordTransition(ctx, tx, appID, application.Status, to, actorID, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == lifecycle.Accepted {
+		err = createProjectFromApplication(ctx, tx, application)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to == lifecycle.Withdrawn && actor == lifecycle.Student && application.Status != lifecycle.Draft {
+		err = notify(ctx, tx, application.SupervisorID, "application_withdrawn", fmt.Sprintf("The application \"%s\" was withdrawn by the student", application.Heading))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to.Terminal() && to != lifecycle.Accepted {
+		err = releaseInterview(ctx, tx, appID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if application.Status == lifecycle.Offered && to != lifecycle.Accepted { //the offer no longer holds a slot
+		err = promoteWaitlisted(ctx, tx, application.SupervisorID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	application.Status = to
+	application.Reason = reason
+	return application, nil
+}
+
+func lockApplication(ctx context.Context, tx *sql.Tx, appID string) (*Application, error) {
+	row := tx.QueryRowContext(ctx, `SELECT id, student_id, supervisor_id, heading, description, status, coalesce(proposal_id::text, ''),
+    (SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id)
+FROM applications WHERE id = $1 FOR UPDATE`, appID)
+
+	var (
+		application      Application
+		responseDeadline sql.NullTime
+	)
+	err := row.Scan(&application.ID, &application.StudentID, &application.SupervisorID, &application.Heading, &application.Description, &application.Status,
+		&application.ProposalID, &responseDeadline)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrApplicationNotFound
+		}
+		log.Printf("cannot read application: %v", err)
+		return nil, err
+	}
+	if responseDeadline.Valid {
+		application.ResponseDeadline = &responseDeadline.Time
+	}
+	return &application, nil
+}
+
+func recordTransition(ctx context.Context, tx *sql.Tx, appID string, from, to lifecycle.Status, actorID string, actor lifecycle.Actor, reason string) error {
+	query := "INSERT INTO application_transitions (id, application_id, from_status, to_status, actor_id, actor_role, reason) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	_, err := tx.ExecContext(ctx, query, GenerateUUID(), appID, sql.NullString{String: string(from), Valid: from != ""}, to,
+		sql.NullString{String: actorID, Valid: actorID != ""}, actor, reason)
+	if err != nil {
+		log.Printf("failed to record application transition: %v", err)
+		return err
+	}
+	return nil
+}
+
+// createProjectFromApplication creates the project for the applicant and the confirmed teammates, carries the
+// message thread over and withdraws the other applications of the team, they stay in the history instead of
+// being deleted
+func createProjectFromApplication(ctx context.Context, tx *sql.Tx, application *Application) error {
+	projectID, err := insertProject(ctx, tx, application.StudentID, application.SupervisorID, application.ProposalID)
+	if err != nil {
+		return err
+	}
+	application.ProjectID = projectID
+	if err = moveMessagesToProject(ctx, tx, application.ID, projectID); err != nil {
+		return err
+	}
+	teammates, err := confirmedTeammates(ctx, tx, application.ID)
+	if err != nil {
+		return err
+	}
+	for _, studentID := range teammates {
+		if err = insertMember(ctx, tx, projectID, studentID, RoleStudent); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE users SET has_project = $1 WHERE id = $2", true, studentID); err != nil {
+			log.Printf("failed to update project status of student: %v", err)
+			return err
+		}
+	}
+	for _, studentID := range append([]string{application.StudentID}, teammates...) {
+		if err = closePendingApplications(ctx, tx, studentID, "student accepted another project"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertProject creates a project named after the student in the current academic year and marks the student
+// as having a project, the supervisor must have capacity left and so must the proposal the project comes from.
+// The project starts out with the milestones of the default template.
+func insertProject(ctx context.Context, tx *sql.Tx, studentID string, supervisorID string, proposalID string) (string, error) {
+	err := reserveSlot(ctx, tx, supervisorID)
+	if err != nil {
+		return "", err
+	}
+	if proposalID != "" {
+		if err = reserveProposalSlot(ctx, tx, proposalID); err != nil {
+			return "", err
+		}
+	}
+
+	projectID := GenerateUUID()
+	updateQuery := "INSERT INTO projects (project_id, project_name, student_id, supervisor_id, academic_year, proposal_id) VALUES ($1, (SELECT name FROM users WHERE id = $2), $2, $3, $4, $5)"
+
+	result, err := tx.ExecContext(ctx, updateQuery, projectID, studentID, supervisorID, academic.Current(), nullString(proposalID))
+	if err != nil {
+		log.Printf("failed to create project: %v", err)
+		return "", err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	log.Printf("created %d row.\n", rowsAffected)
+
+	if err = insertMember(ctx, tx, projectID, supervisorID, RolePrimarySupervisor); err != nil {
+		return "", err
+	}
+	if err = insertMember(ctx, tx, projectID, studentID, RoleStudent); err != nil {
+		return "", err
+	}
+	if err = seedTemplate(ctx, tx, projectID, "", false); err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET has_project = $1 WHERE id = $2", true, studentID)
+	if err != nil {
+		log.Printf("failed to update project status of student: %v", err)
+		return "", err
+	}
+	return projectID, nil
+}
+
+// closePendingApplications withdraws the pending applications of a student who has been placed on a project
+// and declines the team invitations the student has not answered
+func closePendingApplications(ctx context.Context, tx *sql.Tx, studentID string, reason string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE application_team SET status = 'declined', responded_at = now() WHERE student_id = $1 AND status = 'invited'", studentID)
+	if err != nil {
+		log.Printf("failed to decline team invitations: %v", err)
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM applications WHERE student_id = $1 AND status NOT IN ($2, $3, $4, $5)",
+		studentID, lifecycle.Accepted, lifecycle.Declined, lifecycle.Withdrawn, lifecycle.Expired)
+	if err != nil {
+		log.Printf("cannot execute query to get pending applications: %v", err)
+		return err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("cannot read pending application: %v", err)
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err = transitionApplication(ctx, tx, id, lifecycle.Withdrawn, "", reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applicationParticipant returns whether userID takes part in the application as its student or supervisor
+func applicationParticipant(ctx context.Context, conn queryer, appID string, userID string) (lifecycle.Actor, error) {
+	row := conn.QueryRowContext(ctx, "SELECT student_id, supervisor_id FROM applications WHERE id = $1", appID)
+	var studentID, supervisorID string
+	err := row.Scan(&studentID, &supervisorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrApplicationNotFound
+		}
+		log.Printf("cannot read application: %v", err)
+		return "", err
+	}
+	switch userID {
+	case studentID:
+		return lifecycle.Student, nil
+	case supervisorID:
+		return lifecycle.Supervisor, nil
+	default:
+		return "", ErrNotApplicationParticipant
+	}
+}
+
+func (db Client) GetApplicationHistory(ctx context.Context, appID string, userID string) ([]model.ApplicationTransition, error) {
+	if _, err := applicationParticipant(ctx, db.conn, appID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, "SELECT id, from_status, to_status, actor_id, actor_role, reason, created_at FROM application_transitions WHERE application_id = $1 ORDER BY created_at, id", appID)
+	if err != nil {
+		log.Printf("cannot execute query to get application history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.ApplicationTransition{}
+	var (
+		id         string
+		fromStatus sql.NullString
+		toStatus   string
+		actorID    sql.NullString
+		actorRole  string
+		reason     string
+		createdAt  time.Time
+	)
+	for rows.Next() {
+		err = rows.Scan(&id, &fromStatus, &toStatus, &actorID, &actorRole, &reason, &createdAt)
+		if err != nil {
+			log.Printf("cannot read data while getting application history: %v", err)
+			return nil, err
+		}
+
+		result = append(result, model.ApplicationTransition{
+			ID:            id,
+			ApplicationID: appID,
+			FromStatus:    fromStatus.String,
+			ToStatus:      toStatus,
+			ActorID:       actorID.String,
+			ActorRole:     actorRole,
+			Reason:        reason,
+			CreatedAt:     createdAt.UTC(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_TransitionApplication(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+		WithArgs("app-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+			AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", "submitted", "", nil))
+	mock.ExpectExec(`UPDATE applications SET status = \$1, updated_at = now\(\) WHERE id = \$2`).
+		WithArgs(lifecycle.Shortlisted, "app-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO application_transitions`).
+		WithArgs(sqlmock.AnyArg(), "app-1", sqlmock.AnyArg(), lifecycle.Shortlisted, sqlmock.AnyArg(), lifecycle.Supervisor, "strong proposal").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	application, err := d.TransitionApplication(context.Background(), "app-1", lifecycle.Shortlisted, "supervisor-1", "strong proposal")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, lifecycle.Shortlisted, application.Status)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_TransitionApplicationRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	passed := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		status   string
+		actorID  string
+		deadline *time.Time
+		err      error
+	}{
+		{"terminal status", "declined", "supervisor-1", nil, lifecycle.ErrInvalidTransition},
+		{"student cannot shortlist", "submitted", "student-1", nil, lifecycle.ErrActorNotAllowed},
+		{"unrelated user", "submitted", "someone-else", nil, ErrNotApplicationParticipant},
+		{"response deadline passed", "submitted", "supervisor-1", &passed, ErrResponseDeadlinePassed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+					AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", test.status, "", test.deadline))
+			mock.ExpectRollback()
+
+			_, err := d.TransitionApplication(context.Background(), "app-1", lifecycle.Shortlisted, test.actorID, "")
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"log"
+	"time"
+)
+
+// MaxAttachments limits how many files can be attached to one application
+const MaxAttachments = 5
+
+// ErrAttachmentNotFound is returned when the attachment does not exist
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// ErrTooManyAttachments is returned when an upload would exceed MaxAttachments
+var ErrTooManyAttachments = fmt.Errorf("at most %d files can be attached to an application", MaxAttachments)
+
+func insertAttachments(ctx context.Context, conn execer, appID string, uploadedBy string, attachments []Attachment) error {
+	if len(attachments) > MaxAttachments {
+		return ErrTooManyAttachments
+	}
+	query := "INSERT INTO application_attachments (id, application_id, uploaded_by, file_name, content_type, size_bytes, sha256, storage_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+	for _, attachment := range attachments {
+		_, err := conn.ExecContext(ctx, query, attachment.ID, appID, uploadedBy, attachment.FileName, attachment.ContentType,
+			attachment.Size, attachment.SHA256, attachment.StorageKey)
+		if err != nil {
+			log.Printf("failed to add attachment: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// AddAttachments attaches already stored files to an application of the student until it has an outcome
+func (db Client) AddAttachments(ctx context.Context, appID string, studentID string, attachments []Attachment) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to add attachments: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	application, err := lockApplication(ctx, tx, appID)
+	if err != nil {
+		return err
+	}
+	if application.StudentID != studentID {
+		return ErrNotApplicationParticipant
+	}
+	if application.Status.Terminal() {
+		return fmt.Errorf("%w: files cannot be attached to %s applications", lifecycle.ErrInvalidTransition, application.Status)
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, "SELECT count(*) FROM application_attachments WHERE application_id = $1", appID).Scan(&count)
+	if err != nil {
+		log.Printf("cannot count attachments: %v", err)
+		return err
+	}
+	if count+len(attachments) > MaxAttachments {
+		return ErrTooManyAttachments
+	}
+
+	if err = insertAttachments(ctx, tx, appID, studentID, attachments); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAttachments lists the files of an application to its student and supervisor
+func (db Client) GetAttachments(ctx context.Context, appID string, userID string) ([]Attachment, error) {
+	if _, err := applicationParticipant(ctx, db.conn, appID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, "SELECT id, application_id, uploaded_by, file_name, content_type, size_bytes, sha256, storage_key, created_at FROM application_attachments WHERE application_id = $1 ORDER BY created_at, id", appID)
+	if err != nil {
+		log.Printf("cannot execute query to get attachments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		err = rows.Scan(&attachment.ID, &attachment.ApplicationID, &attachment.UploadedBy, &attachment.FileName, &attachment.ContentType,
+			&attachment.Size, &attachment.SHA256, &attachment.StorageKey, &attachment.CreatedAt)
+		if err != nil {
+			log.Printf("cannot read data while getting attachments: %v", err)
+			return nil, err
+		}
+		attachment.CreatedAt = attachment.CreatedAt.UTC()
+		result = append(result, attachment)
+	}
+	return result, nil
+}
+
+// GetAttachment returns a single attachment without checking access, callers verify a signed download link first
+func (db Client) GetAttachment(ctx context.Context, attachmentID string) (*Attachment, error) {
+	row := db.conn.QueryRowContext(ctx, "SELECT id, application_id, uploaded_by, file_name, content_type, size_bytes, sha256, storage_key, created_at FROM application_attachments WHERE id = $1", attachmentID)
+
+	var (
+		attachment Attachment
+		createdAt  time.Time
+	)
+	err := row.Scan(&attachment.ID, &attachment.ApplicationID, &attachment.UploadedBy, &attachment.FileName, &attachment.ContentType,
+		&attachment.Size, &attachment.SHA256, &attachment.StorageKey, &createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		log.Printf("cannot read attachment: %v", err)
+		return nil, err
+	}
+	attachment.CreatedAt = createdAt.UTC()
+	return &attachment, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_AddAttachmentsRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	tests := []struct {
+		name      string
+		studentID string
+		status    string
+		existing  int
+		err       error
+	}{
+		{"supervisor cannot upload", "supervisor-1", "submitted", 0, ErrNotApplicationParticipant},
+		{"application has an outcome", "student-1", "declined", 0, lifecycle.ErrInvalidTransition},
+		{"limit reached", "student-1", "submitted", MaxAttachments, ErrTooManyAttachments},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+				WithArgs("app-1").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+					AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", test.status, "", nil))
+			if test.err == ErrTooManyAttachments {
+				mock.ExpectQuery(`SELECT count\(\*\) FROM application_attachments WHERE application_id = \$1`).
+					WithArgs("app-1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(test.existing))
+			}
+			mock.ExpectRollback()
+
+			err := d.AddAttachments(context.Background(), "app-1", test.studentID, []Attachment{{ID: "attachment-1"}})
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// audit records who changed what, such as an edited gantt item or a coordinator overriding the rules
+func audit(ctx context.Context, conn execer, actorID string, action string, subjectID string, detail string) error {
+	_, err := conn.ExecContext(ctx, "INSERT INTO audit_log (id, actor_id, action, subject_id, detail) VALUES ($1, $2, $3, $4, $5)",
+		GenerateUUID(), actorID, action, subjectID, detail)
+	if err != nil {
+		log.Printf("failed to write audit log: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (db Client) GetAuditLog(ctx context.Context, opts ListOptions) (*model.Page[model.AuditEntry], error) {
+	q := listQuery{
+		columns:  "id, actor_id, action, subject_id, detail, created_at",
+		from:     "audit_log",
+		idColumn: "id",
+	}
+	q.dateRange("created_at", opts)
+
+	query, args, err := q.build(opts, auditSorts, "-createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get audit log: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.AuditEntry](opts, "-createdAt")
+	var (
+		entry     model.AuditEntry
+		createdAt time.Time
+		key       string
+	)
+	for rows.Next() {
+		err = rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.SubjectID, &entry.Detail, &createdAt, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting audit log: %v", err)
+			return nil, err
+		}
+		entry.CreatedAt = createdAt.UTC()
+		result.add(entry, entry.ID, key)
+	}
+	return result.page(), nil
+}
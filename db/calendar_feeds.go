@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+)
+
+// ErrFeedNotFound is returned when the user has no calendar feed or the token was revoked or replaced
+var ErrFeedNotFound = errors.New("calendar feed not found")
+
+// hashFeedToken is what is stored for a feed token, the token itself is only shown once
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarFeed gives the user a new feed token, the previous token of the user stops working
+func (db Client) CreateCalendarFeed(ctx context.Context, userID string) (string, *model.CalendarFeed, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	var feed model.CalendarFeed
+	err := db.conn.QueryRowContext(ctx, `INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = now(), last_used_at = NULL
+RETURNING created_at`, userID, hashFeedToken(token)).Scan(&feed.CreatedAt)
+	if err != nil {
+		log.Printf("failed to create calendar feed: %v", err)
+		return "", nil, err
+	}
+	feed.CreatedAt = feed.CreatedAt.UTC()
+	return token, &feed, nil
+}
+
+func (db Client) GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error) {
+	var (
+		feed       model.CalendarFeed
+		lastUsedAt sql.NullTime
+	)
+	err := db.conn.QueryRowContext(ctx, "SELECT created_at, last_used_at FROM calendar_feeds WHERE user_id = $1", userID).Scan(&feed.CreatedAt, &lastUsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFeedNotFound
+		}
+		log.Printf("cannot read calendar feed: %v", err)
+		return nil, err
+	}
+	feed.CreatedAt = feed.CreatedAt.UTC()
+	if lastUsedAt.Valid {
+		at := lastUsedAt.Time.UTC()
+		feed.LastUsedAt = &at
+	}
+	return &feed, nil
+}
+
+func (db Client) RevokeCalendarFeed(ctx context.Context, userID string) error {
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM calendar_feeds WHERE user_id = $1", userID)
+	if err != nil {
+		log.Printf("failed to revoke calendar feed: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrFeedNotFound
+	}
+	return nil
+}
+
+// GetCalendarFeedProjects returns the projects the owner of the feed token takes part in
+func (db Client) GetCalendarFeedProjects(ctx context.Context, token string) ([]model.ProjectData, error) {
+	var userID string
+	err := db.conn.QueryRowContext(ctx, "UPDATE calendar_feeds SET last_used_at = now() WHERE token_hash = $1 RETURNING user_id", hashFeedToken(token)).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFeedNotFound
+		}
+		log.Printf("cannot read calendar feed: %v", err)
+		return nil, err
+	}
+
+	query := `SELECT p.project_id, p.project_name, m.role
+FROM project_members m INNER JOIN projects p ON p.project_id = m.project_id
+WHERE m.user_id = $1
+ORDER BY p.project_name, p.project_id`
+	rows, err := db.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("cannot execute query to get feed projects: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []model.ProjectData{}
+	for rows.Next() {
+		var project model.ProjectData
+		if err = rows.Scan(&project.ID, &project.Name, &project.Role); err != nil {
+			log.Printf("cannot read data while getting feed projects: %v", err)
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
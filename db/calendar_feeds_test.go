@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_GetCalendarFeedProjects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE calendar_feeds SET last_used_at = now\(\) WHERE token_hash = \$1 RETURNING user_id`).
+		WithArgs(hashFeedToken("secret")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("student-1"))
+	mock.ExpectQuery(`FROM project_members m INNER JOIN projects p ON p.project_id = m.project_id`).
+		WithArgs("student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "project_name", "role"}).
+			AddRow("project-1", "Compilers", "student"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	projects, err := d.GetCalendarFeedProjects(context.Background(), "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ProjectData{{ID: "project-1", Name: "Compilers", Role: "student"}}, projects)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_GetCalendarFeedProjectsRevoked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE calendar_feeds SET last_used_at`).
+		WithArgs(hashFeedToken("revoked")).
+		WillReturnError(sql.ErrNoRows)
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.GetCalendarFeedProjects(context.Background(), "revoked")
+	assert.ErrorIs(t, err, ErrFeedNotFound)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
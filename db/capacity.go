@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+)
+
+// ErrSupervisorFull is returned when a supervisor has no capacity left in the academic year
+var ErrSupervisorFull = errors.New("supervisor has no remaining capacity")
+
+// ErrNotSupervisor is returned when capacity is requested for a user who is not a supervisor
+var ErrNotSupervisor = errors.New("user is not a supervisor")
+
+// capacityColumns selects the capacity of supervisor u for the academic year passed as the given argument,
+// falling back to the default capacity on the user. Projects of the year and open offers use up capacity.
+func capacityColumns(year string) string {
+	return fmt.Sprintf(`coalesce(c.capacity, u.capacity),
+    coalesce(c.capacity, u.capacity)
+    - (SELECT count(*) FROM projects p WHERE p.supervisor_id = u.id AND p.academic_year = %[1]s)
+    - (SELECT count(*) FROM applications a WHERE a.supervisor_id = u.id AND a.status = '%[2]s')`, year, lifecycle.Offered)
+}
+
+func capacityJoin(year string) string {
+	return "users u LEFT JOIN supervisor_capacities c ON c.supervisor_id = u.id AND c.academic_year = " + year
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func remainingCapacity(ctx context.Context, conn queryer, supervisorID string, lock bool) (int, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE u.id = $1 AND u.is_supervisor = true", capacityColumns("$2"), capacityJoin("$2"))
+	if lock {
+		query += " FOR UPDATE OF u" //serialises capacity checks for the same supervisor
+	}
+
+	var capacity, remaining int
+	err := conn.QueryRowContext(ctx, query, supervisorID, academic.Current()).Scan(&capacity, &remaining)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotSupervisor
+		}
+		log.Printf("cannot read supervisor capacity: %v", err)
+		return 0, err
+	}
+	return remaining, nil
+}
+
+// reserveSlot locks the supervisor and fails when no capacity is left
+func reserveSlot(ctx context.Context, tx *sql.Tx, supervisorID string) error {
+	remaining, err := remainingCapacity(ctx, tx, supervisorID, true)
+	if err != nil {
+		return err
+	}
+	if remaining <= 0 {
+		return ErrSupervisorFull
+	}
+	return nil
+}
+
+// promoteWaitlisted moves the oldest waitlisted applications of the supervisor back into the queue for as
+// many slots as are free and lets the students know
+func promoteWaitlisted(ctx context.Context, tx *sql.Tx, supervisorID string) error {
+	remaining, err := remainingCapacity(ctx, tx, supervisorID, true)
+	if err != nil || remaining <= 0 {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM applications WHERE supervisor_id = $1 AND status = $2 ORDER BY created_at, id LIMIT $3",
+		supervisorID, lifecycle.Waitlisted, remaining)
+	if err != nil {
+		log.Printf("cannot execute query to get waitlisted applications: %v", err)
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("cannot read waitlisted application: %v", err)
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		application, err := transitionApplication(ctx, tx, id, lifecycle.Submitted, "", "supervisor capacity became available")
+		if err != nil {
+			return err
+		}
+		err = notify(ctx, tx, application.StudentID, "application_promoted",
+			fmt.Sprintf("A place opened up, your application \"%s\" has been moved off the waitlist", application.Heading))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db Client) SetSupervisorCapacity(ctx context.Context, supervisorID string, academicYear string, capacity int) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update capacity: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = remainingCapacity(ctx, tx, supervisorID, true); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO supervisor_capacities (supervisor_id, academic_year, capacity) VALUES ($1, $2, $3)
+ON CONFLICT (supervisor_id, academic_year) DO UPDATE SET capacity = excluded.capacity`
+	_, err = tx.ExecContext(ctx, query, supervisorID, academicYear, capacity)
+	if err != nil {
+		log.Printf("failed to update supervisor capacity: %v", err)
+		return err
+	}
+
+	if academicYear == academic.Current() {
+		if err = promoteWaitlisted(ctx, tx, supervisorID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db Client) GetSupervisorCapacity(ctx context.Context, supervisorID string) ([]model.Capacity, error) {
+	query := `SELECT academic_year, capacity FROM supervisor_capacities WHERE supervisor_id = $1 ORDER BY academic_year`
+	rows, err := db.conn.QueryContext(ctx, query, supervisorID)
+	if err != nil {
+		log.Printf("cannot execute query to get capacity: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Capacity{}
+	var (
+		year     string
+		capacity int
+	)
+	for rows.Next() {
+		err = rows.Scan(&year, &capacity)
+		if err != nil {
+			log.Printf("cannot read data while getting capacity: %v", err)
+			return nil, err
+		}
+		result = append(result, model.Capacity{
+			AcademicYear: year,
+			Capacity:     capacity,
+		})
+	}
+	return result, nil
+}
@@ -3,187 +3,180 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
 	"github.com/Simplyphotons/fyp.git/model"
 	"github.com/google/uuid"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"log"
 )
 
-func (db Client) GetQuestions(ctx context.Context) ([]model.Question, error) {
-	rows, err := db.conn.QueryContext(ctx, "SELECT ticket_id, questionshort from tickets")
+func (db Client) GetQuestions(ctx context.Context, opts ListOptions) (*model.Page[model.Question], error) {
+	q := listQuery{
+		columns:  "ticket_id, questionshort",
+		from:     "tickets",
+		idColumn: "ticket_id",
+	}
+	query, args, err := q.build(opts, questionSorts, "question")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("cannot execute query to get questions: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	result := []model.Question{}
+	result := newPageBuilder[model.Question](opts, "question")
 
 	var (
 		id       string
-		question string
+		question sql.NullString
+		key      string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &question)
+		err = rows.Scan(&id, &question, &key)
 		if err != nil {
 			log.Printf("cannot read data while getting questions: %v", err)
 			return nil, err
 		}
 
-		result = append(result, model.Question{
+		result.add(model.Question{
 			ID:       id,
-			Question: question,
-		})
+			Question: question.String,
+		}, id, key)
 	}
+	return result.page(), nil
+}
 
-	// Nullable fields
-	//var (
-	//	id       string
-	//	question sql.NullString
-	//)
-	//for rows.Next() {
-	//	err = rows.Scan(&id, &question)
-	//	if err != nil {
-	//		log.Printf("cannot read data while getting questions: %v", err)
-	//	}
-	//
-	//	item := model.Question{
-	//		ID: id,
-	//	}
-	//
-	//	if question.Valid {
-	//		item.Question = &question.String
-	//	}
-	//	temp = append(temp)
-	//}
-
-	// Not nullable fields
-	//for rows.Next() {
-	//	item := model.Question{}
-	//
-	//	err := rows.Scan(&item.ID, &item.Question)
-	//	if err != nil {
-	//		log.Printf("cannot read data while getting questions: %v", err)
-	//	}
-	//	temp = append(temp, item)
-	//}
-	return result, nil
+// ganttColumns are read by scanGantt
+const ganttColumns = "item_id, project_id, gantt_name, start_date, end_date, description, links, status, percent_complete, deadline_locked, version"
 
+func scanGantt(rows *sql.Rows) (model.Gantt, error) {
+	var (
+		item            model.Gantt
+		status          GanttStatus
+		percentComplete int
+	)
+	err := rows.Scan(&item.ID, &item.ProjectID, &item.GanttName, &item.StartDate, &item.EndDate, &item.Description, &item.Links,
+		&status, &percentComplete, &item.DeadlineLocked, &item.Version)
+	if err != nil {
+		return item, err
+	}
+	item.StartDate = item.StartDate.UTC()
+	item.EndDate = item.EndDate.UTC()
+	setGanttState(&item, status, percentComplete)
+	return item, nil
 }
 
 func (db Client) GetGantt(ctx context.Context, projectIdentifier string) ([]model.GanttChartRow, error) { //gets all milestones within a project
-	rows, err := db.conn.QueryContext(ctx, "SELECT item_id, project_id, gantt_name, start_date, end_date, description, links, feedback, colour from gantt_items where project_id = $1 order by start_date", projectIdentifier)
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+ganttColumns+" from gantt_items where project_id = $1 order by start_date", projectIdentifier)
 	if err != nil {
 		log.Printf("cannot execute query to get questions: %v", err)
 		return nil, err
 	}
 
 	result := []model.GanttChartRow{}
-	var (
-		id          string
-		projectID   string
-		startDate   string
-		ganttName   string
-		endDate     string
-		description string
-		links       string
-		feedback    string
-		colour      string
-	)
 	for rows.Next() {
-		err = rows.Scan(&id, &projectID, &ganttName, &startDate, &endDate, &description, &links, &feedback, &colour)
+		item, err := scanGantt(rows)
 		if err != nil {
 			log.Printf("cannot read data while getting questions: %v", err)
 			return nil, err
 		}
 
-		row := []model.Gantt{
-			{
-				ID:          id,
-				ProjectID:   projectID,
-				GanttName:   ganttName,
-				StartDate:   startDate,
-				EndDate:     endDate,
-				Description: description,
-				Links:       links,
-				Feedback:    feedback,
-				Colour:      colour,
-			},
-		}
-
 		result = append(result, model.GanttChartRow{
-			Content: row,
+			Content: []model.Gantt{item},
 		})
 	}
-	println(id, projectID, ganttName, startDate, endDate)
+	rows.Close()
+
+	contributions, err := db.ganttContributions(ctx, projectIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := ganttDependencies(ctx, db.conn, projectIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*model.Gantt, len(result))
+	for i, row := range result {
+		row.Content[0].Contributions = contributions[row.Content[0].ID]
+		items[i] = &row.Content[0]
+	}
+	planGantt(items, dependencies)
 	return result, nil
 }
 
 func (db Client) GetGanttItem(ctx context.Context, milestoneIdentifier string) ([]model.Gantt, error) { //gets one milestone
-	rows, err := db.conn.QueryContext(ctx, "SELECT item_id, project_id, gantt_name, start_date, end_date, description, links, feedback from gantt_items where item_id = $1", milestoneIdentifier)
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+ganttColumns+" from gantt_items where item_id = $1", milestoneIdentifier)
 	if err != nil {
 		log.Printf("cannot execute query to get questions: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
 	result := []model.Gantt{}
-	var (
-		id          string
-		projectID   string
-		ganttName   string
-		startDate   string
-		endDate     string
-		description string
-		links       string
-		feedback    string
-	)
 	for rows.Next() {
-		err = rows.Scan(&id, &projectID, &ganttName, &startDate, &endDate, &description, &links, &feedback)
+		item, err := scanGantt(rows)
 		if err != nil {
 			log.Printf("cannot read data while getting questions: %v", err)
 			return nil, err
 		}
-
-		result = append(result, model.Gantt{
-			ID:          id,
-			ProjectID:   projectID,
-			GanttName:   ganttName,
-			StartDate:   startDate,
-			EndDate:     endDate,
-			Description: description,
-			Links:       links,
-			Feedback:    feedback,
-		})
-
+		result = append(result, item)
 	}
 	return result, nil
 }
 
-func (db Client) GetSupervisors(ctx context.Context) ([]model.UserData, error) { //for use in displaying all available supervisors when a student is creating a new project application.
-	rows, err := db.conn.QueryContext(ctx, "SELECT id, name from users where is_supervisor = true")
+func (db Client) GetSupervisors(ctx context.Context, opts ListOptions) (*model.Page[model.UserData], error) { //for use in displaying all available supervisors when a student is creating a new project application.
+	q := listQuery{
+		idColumn: "u.id",
+	}
+	year := q.arg(academic.Current())
+	q.columns = "u.id, u.name, " + capacityColumns(year)
+	q.from = capacityJoin(year)
+	q.where("u.is_supervisor = true")
+
+	query, args, err := q.build(opts, userSorts, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("cannot execute query to get users: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	result := []model.UserData{}
+	result := newPageBuilder[model.UserData](opts, "name")
 
 	var (
-		id   string
-		name string
+		id        string
+		name      sql.NullString
+		capacity  int
+		remaining int
+		key       string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &name)
+		err = rows.Scan(&id, &name, &capacity, &remaining, &key)
 		if err != nil {
 			log.Printf("cannot read data while getting users: %v", err)
+			return nil, err
 		}
 
-		result = append(result, model.UserData{
-			ID:   id,
-			Name: name,
-		})
+		result.add(model.UserData{
+			ID:           id,
+			Name:         name.String,
+			IsSupervisor: true,
+			Capacity:     &capacity,
+			Remaining:    &remaining,
+		}, id, key)
 	}
-	return result, nil
+	return result.page(), nil
 }
 
 func (db Client) GetHasProjectStatus(ctx context.Context, userID string) (bool, error) {
@@ -207,96 +200,128 @@ func (db Client) GetHasProjectStatus(ctx context.Context, userID string) (bool,
 	return false, nil
 }
 
-func (db Client) GetApplications(ctx context.Context, supervisor_ID string) ([]model.ApplicationData, error) {
-	query := `SELECT a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.accepted, a.declined
-FROM applications a INNER JOIN users u
-    ON a.student_id = u.id
-WHERE supervisor_id = $1`
-	rows, err := db.conn.QueryContext(ctx, query, supervisor_ID)
+func (db Client) GetApplications(ctx context.Context, supervisor_ID string, opts ListOptions) (*model.Page[model.ApplicationData], error) {
+	q := listQuery{
+		columns:  "a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.status, coalesce(a.proposal_id::text, '')",
+		from:     "applications a INNER JOIN users u ON a.student_id = u.id",
+		idColumn: "a.id",
+	}
+	q.where("a.supervisor_id = " + q.arg(supervisor_ID))
+	q.where("a.status <> " + q.arg(lifecycle.Draft))
+	if opts.Accepted == nil && opts.Status == "" { //accepted applications are listed as projects unless explicitly requested
+		q.where("a.status <> " + q.arg(lifecycle.Accepted))
+	}
+	q.applicationStatus(opts)
+	q.dateRange("a.created_at", opts)
+
+	query, args, err := q.build(opts, applicationSorts, "createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("cannot execute query to get applications: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	result := []model.ApplicationData{}
+	result := newPageBuilder[model.ApplicationData](opts, "createdAt")
 	var (
 		id           string
 		studentID    string
-		studentName  string
+		studentName  sql.NullString
 		supervisorID string
-		heading      string
-		description  string
-		accepted     bool
-		declined     bool
+		heading      sql.NullString
+		description  sql.NullString
+		status       lifecycle.Status
+		proposalID   string
+		key          string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &accepted, &declined)
+		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &status, &proposalID, &key)
 		if err != nil {
-			log.Printf("cannot read data while getting questions: %v", err)
-		}
-		if !accepted {
-			result = append(result, model.ApplicationData{
-				ID:           id,
-				StudentID:    studentID,
-				StudentName:  studentName,
-				SupervisorID: supervisorID,
-				Heading:      heading,
-				Description:  description,
-				Accepted:     accepted,
-				Declined:     declined,
-			})
+			log.Printf("cannot read data while getting applications: %v", err)
+			return nil, err
 		}
-	}
-	return result, nil
 
+		result.add(model.ApplicationData{
+			ID:           id,
+			StudentID:    studentID,
+			StudentName:  studentName.String,
+			SupervisorID: supervisorID,
+			Heading:      heading.String,
+			Description:  description.String,
+			Status:       string(status),
+			Accepted:     status == lifecycle.Accepted,
+			Declined:     status == lifecycle.Declined,
+			ProposalID:   proposalID,
+		}, id, key)
+	}
+	return result.page(), nil
 }
 
-func (db Client) GetAllAcceptedRequests(ctx context.Context) ([]model.ApplicationData, error) {
-	query := `SELECT a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.accepted, a.declined
-FROM applications a INNER JOIN users u
-    ON a.student_id = u.id
-WHERE a.accepted = true`
-	rows, err := db.conn.QueryContext(ctx, query)
+func (db Client) GetAllAcceptedRequests(ctx context.Context, opts ListOptions) (*model.Page[model.ApplicationData], error) {
+	q := listQuery{
+		columns:  "a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.status, coalesce(a.proposal_id::text, '')",
+		from:     "applications a INNER JOIN users u ON a.student_id = u.id",
+		idColumn: "a.id",
+	}
+	q.where("a.status = " + q.arg(lifecycle.Accepted))
+	if opts.SupervisorID != "" {
+		q.where("a.supervisor_id = " + q.arg(opts.SupervisorID))
+	}
+	q.dateRange("a.created_at", opts)
+
+	query, args, err := q.build(opts, applicationSorts, "createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("cannot execute query to get applications: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	result := []model.ApplicationData{}
+	result := newPageBuilder[model.ApplicationData](opts, "createdAt")
 	var (
 		id           string
 		studentID    string
-		studentName  string
+		studentName  sql.NullString
 		supervisorID string
-		heading      string
-		description  string
-		accepted     bool
-		declined     bool
+		heading      sql.NullString
+		description  sql.NullString
+		status       lifecycle.Status
+		proposalID   string
+		key          string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &accepted, &declined)
+		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &status, &proposalID, &key)
 		if err != nil {
-			log.Printf("cannot read data while getting reading prompts: %v", err)
-		}
-		if accepted {
-			result = append(result, model.ApplicationData{
-				ID:           id,
-				StudentID:    studentID,
-				StudentName:  studentName,
-				SupervisorID: supervisorID,
-				Heading:      heading,
-				Description:  description,
-				Accepted:     accepted,
-				Declined:     declined,
-			})
+			log.Printf("cannot read data while getting accepted requests: %v", err)
+			return nil, err
 		}
-	}
-	return result, nil
 
+		result.add(model.ApplicationData{
+			ID:           id,
+			StudentID:    studentID,
+			StudentName:  studentName.String,
+			SupervisorID: supervisorID,
+			Heading:      heading.String,
+			Description:  description.String,
+			Status:       string(status),
+			Accepted:     status == lifecycle.Accepted,
+			Declined:     status == lifecycle.Declined,
+			ProposalID:   proposalID,
+		}, id, key)
+	}
+	return result.page(), nil
 }
 
 func (db Client) GetApplicationsForStudent(ctx context.Context, student_ID string) ([]model.ApplicationData, error) {
-	query := `SELECT a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.accepted, a.declined
+	query := `SELECT a.id, a.student_id, u.name, a.supervisor_id, a.heading, a.description, a.status, coalesce(a.proposal_id::text, '')
 FROM applications a INNER JOIN users u
     ON a.supervisor_id = u.id
 WHERE student_id = $1`
@@ -310,15 +335,15 @@ WHERE student_id = $1`
 	var (
 		id           string
 		studentID    string
-		studentName  string
+		studentName  sql.NullString
 		supervisorID string
-		heading      string
-		description  string
-		accepted     bool
-		declined     bool
+		heading      sql.NullString
+		description  sql.NullString
+		status       lifecycle.Status
+		proposalID   string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &accepted, &declined)
+		err = rows.Scan(&id, &studentID, &studentName, &supervisorID, &heading, &description, &status, &proposalID)
 		if err != nil {
 			log.Printf("cannot read data while getting questions: %v", err)
 		}
@@ -326,95 +351,79 @@ WHERE student_id = $1`
 		result = append(result, model.ApplicationData{
 			ID:           id,
 			StudentID:    studentID,
-			StudentName:  studentName,
+			StudentName:  studentName.String,
 			SupervisorID: supervisorID,
-			Heading:      heading,
-			Description:  description,
-			Accepted:     accepted,
-			Declined:     declined,
+			Heading:      heading.String,
+			Description:  description.String,
+			Status:       string(status),
+			Accepted:     status == lifecycle.Accepted,
+			Declined:     status == lifecycle.Declined,
+			ProposalID:   proposalID,
 		})
 	}
 	return result, nil
 
 }
-func (db Client) GetProjects(ctx context.Context, supervisor_id string) ([]model.ProjectData, error) {
-	rows, err := db.conn.QueryContext(ctx, "SELECT project_id, project_name, student_id, supervisor_id from projects where supervisor_id = $1", supervisor_id)
-	if err != nil {
-		log.Printf("cannot execute query to get projects: %v", err)
-		return nil, err
-	}
-	result := []model.ProjectData{}
-	var (
-		projectID    string
-		projectName  string
-		studentID    string
-		supervisorID string
-	)
-	for rows.Next() {
-		err = rows.Scan(&projectID, &projectName, &studentID, &supervisorID)
-		if err != nil {
-			log.Printf("cannot read data while getting questions: %v", err)
-		}
 
-		result = append(result, model.ProjectData{
-			ID:           projectID,
-			Name:         projectName,
-			StudentID:    studentID,
-			SupervisorID: supervisorID,
-		})
+// GetProjects lists the projects the user is a member of, optionally only those where the user has opts.Role
+func (db Client) GetProjects(ctx context.Context, userID string, opts ListOptions) (*model.Page[model.ProjectData], error) {
+	q := listQuery{
+		columns:  "p.project_id, p.project_name, p.student_id, p.supervisor_id, coalesce(p.proposal_id::text, ''), m.role",
+		idColumn: "p.project_id",
 	}
-	return result, nil
+	q.from = "projects p INNER JOIN project_members m ON m.project_id = p.project_id AND m.user_id = " + q.arg(userID)
+	if opts.Role != "" {
+		q.where("m.role = " + q.arg(opts.Role))
+	}
+	q.dateRange("p.created_at", opts)
 
-}
+	query, args, err := q.build(opts, projectSorts, "createdAt")
+	if err != nil {
+		return nil, err
+	}
 
-func (db Client) GetSecondProjects(ctx context.Context, supervisor_id string) ([]model.ProjectData, error) {
-	rows, err := db.conn.QueryContext(ctx, "SELECT project_id, project_name, student_id, supervisor_id from projects where second_reader_id = $1", supervisor_id)
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Printf("cannot execute query to get projects: %v", err)
 		return nil, err
 	}
-	result := []model.ProjectData{}
-	var (
-		projectID    string
-		projectName  string
-		studentID    string
-		supervisorID string
-	)
+	defer rows.Close()
+
+	result := newPageBuilder[model.ProjectData](opts, "createdAt")
 	for rows.Next() {
-		err = rows.Scan(&projectID, &projectName, &studentID, &supervisorID)
+		var (
+			project model.ProjectData
+			name    sql.NullString
+			key     string
+		)
+		err = rows.Scan(&project.ID, &name, &project.StudentID, &project.SupervisorID, &project.ProposalID, &project.Role, &key)
 		if err != nil {
-			log.Printf("cannot read data while getting questions: %v", err)
+			log.Printf("cannot read data while getting projects: %v", err)
+			return nil, err
 		}
-
-		result = append(result, model.ProjectData{
-			ID:           projectID,
-			Name:         projectName,
-			StudentID:    studentID,
-			SupervisorID: supervisorID,
-		})
+		project.Name = name.String
+		result.add(project, project.ID, key)
 	}
-	return result, nil
-
-}
+	rows.Close()
 
-func (db Client) GetProjectID(ctx context.Context, userID string) (*model.ProjectData, error) {
-	rows, err := db.conn.QueryContext(ctx, "select project_id from projects where student_id = $1", userID)
+	page := result.page()
+	if len(page.Items) == 0 {
+		return page, nil
+	}
+	projectIDs := make([]string, len(page.Items))
+	for i, project := range page.Items {
+		projectIDs[i] = project.ID
+	}
+	members, err := db.projectMembers(ctx, projectIDs)
 	if err != nil {
-		log.Printf("cannot execute query to get project id: %v", err)
 		return nil, err
 	}
-
-	result := &model.ProjectData{}
-	for rows.Next() {
-		err = rows.Scan(&result.ID)
-		if err != nil {
-			log.Printf("cannot read project ID: %v", err)
-			return nil, err
-		}
+	for i := range page.Items {
+		page.Items[i].Members = members[page.Items[i].ID]
 	}
-	println(result.ID)
-	return result, err
+	return page, nil
 }
+
 func (db Client) GetProjectName(ctx context.Context, ProjectID string) (*model.ProjectData, error) {
 	rows, err := db.conn.QueryContext(ctx, "select project_name from projects where project_id = $1", ProjectID)
 	if err != nil {
@@ -434,32 +443,8 @@ func (db Client) GetProjectName(ctx context.Context, ProjectID string) (*model.P
 	return result, err
 }
 
-func (db Client) GetSecondReaderStatus(ctx context.Context, ProjectID string, userID string) (bool, error) {
-	rows, err := db.conn.QueryContext(ctx, "select second_reader_id from projects where project_id = $1", ProjectID)
-	if err != nil {
-		log.Printf("cannot execute query to get project name: %v", err)
-		return false, err
-	}
-
-	var result string
-	for rows.Next() {
-		err = rows.Scan(&result)
-		if err != nil {
-			log.Printf("cannot read project Name: %v", err)
-			return false, err
-		}
-	}
-	println(result)
-
-	if result == userID {
-		return true, nil
-	}
-
-	return false, nil
-}
-
 func (db Client) GetSpecificApplications(ctx context.Context, appID string) ([]model.ApplicationData, error) {
-	rows, err := db.conn.QueryContext(ctx, "SELECT id, student_id, supervisor_id, heading, description, accepted, declined from applications where id = $1", appID)
+	rows, err := db.conn.QueryContext(ctx, "SELECT id, student_id, supervisor_id, heading, description, status, coalesce(proposal_id::text, '') from applications where id = $1", appID)
 	if err != nil {
 		log.Printf("cannot execute query to get applications: %v", err)
 		return nil, err
@@ -471,11 +456,11 @@ func (db Client) GetSpecificApplications(ctx context.Context, appID string) ([]m
 		supervisorID string
 		heading      string
 		description  string
-		accepted     bool
-		declined     bool
+		status       lifecycle.Status
+		proposalID   string
 	)
 	for rows.Next() {
-		err = rows.Scan(&id, &studentID, &supervisorID, &heading, &description, &accepted, &declined)
+		err = rows.Scan(&id, &studentID, &supervisorID, &heading, &description, &status, &proposalID)
 		if err != nil {
 			log.Printf("cannot read data while getting questions: %v", err)
 		}
@@ -486,8 +471,10 @@ func (db Client) GetSpecificApplications(ctx context.Context, appID string) ([]m
 			SupervisorID: supervisorID,
 			Heading:      heading,
 			Description:  description,
-			Accepted:     accepted,
-			Declined:     declined,
+			Status:       string(status),
+			Accepted:     status == lifecycle.Accepted,
+			Declined:     status == lifecycle.Declined,
+			ProposalID:   proposalID,
 		})
 
 	}
@@ -540,26 +527,26 @@ func (db Client) NewAnswer(ctx context.Context) error { //adds new question to d
 	return nil
 }
 
+// CreateProject accepts the application, the project is seeded from the chosen milestone template instead of the
+// default one when the supervisor picked one or wants the project to follow template updates
 func (db Client) CreateProject(ctx context.Context, application Application, supervisor_id string) error {
-	id := GenerateUUID()
-	name := application.StudentName
-	studentID := application.StudentID
-	supervisorID := supervisor_id //takes the auth userid since only the supervisor can access this
-
-	updateQuery := "INSERT INTO projects (project_id, project_name, student_id, supervisor_id) VALUES ($1, $2, $3, $4)"
-
-	result, err := db.conn.Exec(updateQuery, id, name, studentID, supervisorID)
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("failed to add answer to corresponding ticket in db")
+		log.Printf("cannot start transaction to create project: %v", err)
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("created %d row.\n", rowsAffected)
-	db.updateUser(studentID)
-	db.acceptApplication(application.ID)
-	db.deleteApplication(studentID)
-	return nil
+	defer tx.Rollback()
 
+	accepted, err := transitionApplication(ctx, tx, application.ID, lifecycle.Accepted, supervisor_id, "") //only the supervisor can access this, other applications of the student are withdrawn
+	if err != nil {
+		return err
+	}
+	if application.TemplateID != "" || application.FollowTemplate {
+		if err = seedTemplate(ctx, tx, accepted.ProjectID, application.TemplateID, application.FollowTemplate); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (db Client) CreateSupervisorUser(ctx context.Context, user User) error {
@@ -601,119 +588,153 @@ func (db Client) CreateApplication(ctx context.Context, application Application,
 	supervisorID := application.SupervisorID
 	heading := application.Heading
 	description := application.Description
-	accepted := false
-	declined := false
-
-	updateQuery := "INSERT INTO applications (id, student_id, supervisor_id, heading, description, accepted, declined) VALUES ($1, $2, $3, $4, $5, $6, $7)"
-	//println(applicationID + "\n\n")
-	//println(applicationID + "\n" + student_id + "\n" + supervisorID + "\n" + heading + "\n" + description)
+	status := lifecycle.Submitted
+	if application.Status == lifecycle.Draft {
+		status = lifecycle.Draft
+	}
 
-	result, err := db.conn.Exec(updateQuery, applicationID, studentID, supervisorID, heading, description, accepted, declined)
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("failed to add new appliction")
+		log.Printf("cannot start transaction to add new application: %v", err)
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("created %d row.\n", rowsAffected)
-	return nil
-}
+	defer tx.Rollback()
 
-func (db Client) acceptApplication(id string) error {
-	println(id)
-	println("call succ")
-	updateQuery := "UPDATE applications SET accepted = $1 WHERE id = $2"
+	if application.ProposalID != "" { //the proposal decides the supervisor and names the application unless a heading is given
+		proposalSupervisor, title, err := openProposal(ctx, tx, application.ProposalID)
+		if err != nil {
+			return err
+		}
+		supervisorID = proposalSupervisor
+		if heading == "" {
+			heading = title
+		}
+	}
 
-	result, err := db.conn.Exec(updateQuery, true, id)
+	remaining, err := remainingCapacity(ctx, tx, supervisorID, false)
 	if err != nil {
-		log.Printf("failed to accept application")
 		return err
 	}
+	if remaining <= 0 && status == lifecycle.Submitted {
+		if !application.Waitlist {
+			return ErrSupervisorFull
+		}
+		status = lifecycle.Waitlisted
+	}
 
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("updated %d row.\n", rowsAffected)
-
-	return nil
-}
-
-func (db Client) CompleteGanttItem(ctx context.Context, gantt Gantt) error {
+	var roundID sql.NullString //drafts join a round when they are submitted
+	if status != lifecycle.Draft {
+		if roundID, err = enterRound(ctx, tx, studentID); err != nil {
+			return err
+		}
+	}
 
-	updateQuery := "UPDATE gantt_items SET colour = $1 WHERE item_id = $2"
+	updateQuery := "INSERT INTO applications (id, student_id, supervisor_id, heading, description, status, proposal_id, round_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
 
-	newColour := "#2C59C7"
-	result, err := db.conn.Exec(updateQuery, newColour, gantt.Id)
+	result, err := tx.ExecContext(ctx, updateQuery, applicationID, studentID, supervisorID, heading, description, status, nullString(application.ProposalID), roundID)
 	if err != nil {
-		log.Printf("failed to accept application")
+		log.Printf("failed to add new appliction")
 		return err
 	}
-
 	rowsAffected, _ := result.RowsAffected()
 	log.Printf("created %d row.\n", rowsAffected)
-	return nil
-}
 
-func (db Client) DeclineApplication(ctx context.Context, application Application) error {
-	db.deleteSpecificApplication(application.ID)
-	return nil
-}
-
-func (db Client) deleteApplication(condition string) error {
-	query := "DELETE FROM applications WHERE student_id = $1 AND accepted = $2"
+	if err = insertAttachments(ctx, tx, applicationID, studentID, application.Attachments); err != nil {
+		return err
+	}
+	if err = insertTeam(ctx, tx, applicationID, studentID, heading, application.Teammates); err != nil {
+		return err
+	}
 
-	result, err := db.conn.Exec(query, condition, false)
+	actor := lifecycle.Student
+	if status == lifecycle.Waitlisted {
+		actor = lifecycle.System
+	}
+	err = recordTransition(ctx, tx, applicationID, "", status, studentID, actor, "")
 	if err != nil {
-		log.Printf("failed to delete table")
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("Row %d deleted.\n", condition)
-	log.Printf("%d Rows affected.\n", rowsAffected)
-	return nil
+	if status == lifecycle.Waitlisted {
+		err = notify(ctx, tx, supervisorID, "application_waitlisted", fmt.Sprintf("A student joined your waitlist with \"%s\"", heading))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (db Client) deleteSpecificApplication(condition string) error {
-	query := "DELETE FROM applications WHERE id = $1 AND accepted = $2"
+// CompleteGanttItem marks the item of a project the user is a member of as done
+func (db Client) CompleteGanttItem(ctx context.Context, gantt Gantt, userID string) (int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to complete gantt item: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	result, err := db.conn.Exec(query, condition, false)
+	if _, _, _, err = lockGanttItem(ctx, tx, gantt.Id, userID); err != nil {
+		return 0, err
+	}
+	version, err := bumpGanttVersion(ctx, tx, gantt.Id, gantt.Version)
 	if err != nil {
-		log.Printf("failed to delete table")
-		return err
+		return 0, err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("Row %d deleted.\n", condition)
-	log.Printf("%d Rows affected.\n", rowsAffected)
-	return nil
+
+	updateQuery := "UPDATE gantt_items SET status = $1, percent_complete = 100 WHERE item_id = $2"
+
+	_, err = tx.ExecContext(ctx, updateQuery, GanttComplete, gantt.Id)
+	if err != nil {
+		log.Printf("failed to complete gantt item")
+		return 0, err
+	}
+	return version, tx.Commit()
 }
 
-func (db Client) DeleteGanttItem(id string) error {
-	query := "DELETE FROM gantt_items WHERE item_id = $1"
+func (db Client) DeclineApplication(ctx context.Context, application Application, userID string) error {
+	_, err := db.TransitionApplication(ctx, application.ID, lifecycle.Declined, userID, application.Reason)
+	return err
+}
 
-	result, err := db.conn.Exec(query, id)
+// DeleteGanttItem removes the item of a project the user is a member of, when the client sends the version it read
+// the item is only removed if nobody changed it since
+func (db Client) DeleteGanttItem(ctx context.Context, id string, userID string, version int) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
+		log.Printf("cannot start transaction to delete gantt item: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, _, err = lockGanttItem(ctx, tx, id, userID); err != nil {
+		return err
+	}
+	if _, err = bumpGanttVersion(ctx, tx, id, version); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM gantt_items WHERE item_id = $1", id); err != nil {
 		log.Printf("failed to delete gantt item")
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("Row %d deleted.\n", id)
-	log.Printf("%d Rows affected.\n", rowsAffected)
-	return nil
+	return tx.Commit()
 }
 
-func (db Client) CreateGanttItem(ctx context.Context, gantt Gantt) error {
+// CreateGanttItem adds an item to a project the user is a member of
+func (db Client) CreateGanttItem(ctx context.Context, gantt Gantt, userID string) error {
+	if _, err := projectRole(ctx, db.conn, gantt.ProjectID, userID); err != nil {
+		return err
+	}
 
 	id := GenerateUUID()
 	projectID := gantt.ProjectID
 	description := gantt.Description
 	startDate := gantt.StartDate
 	endDate := gantt.EndDate
-	feedback := ""
 	links := gantt.Links
 	ganttName := gantt.GanttName
-	colour := "#2A9D39"
-	tracker := 0
 
-	updateQuery := "INSERT INTO gantt_Items (item_id, project_id, description, start_date, end_date, feedback, links, gantt_name, colour, feedback_update_tracker) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+	updateQuery := "INSERT INTO gantt_Items (item_id, project_id, description, start_date, end_date, links, gantt_name, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
 
-	result, err := db.conn.Exec(updateQuery, id, projectID, description, startDate, endDate, feedback, links, ganttName, colour, tracker)
+	result, err := db.conn.ExecContext(ctx, updateQuery, id, projectID, description, startDate, endDate, links, ganttName, GanttOnTrack)
 	if err != nil {
 		log.Printf("failed to create new gantt item/milestone to the project")
 		return err
@@ -723,104 +744,31 @@ func (db Client) CreateGanttItem(ctx context.Context, gantt Gantt) error {
 	return nil
 }
 
-func (db Client) UpdateFeedback(ctx context.Context, gantt Gantt, userID string) error {
-	newText := ""
-	updateQuery := "UPDATE gantt_items SET feedback = $1 WHERE item_id = $2"
-	isSupervisor, err := db.getAccountStatus(ctx, userID)
-	if err != nil {
-		log.Printf("failed to retrieve account status")
-		return err
-	}
-	if isSupervisor {
-		newText = gantt.Feedback + "Supervisor: " + gantt.NewFeedBack + "\n\n"
-		db.enableAlert(1, gantt.Id)
-	} else {
-		newText = gantt.Feedback + "Student: " + gantt.NewFeedBack + "\n\n"
-		db.enableAlert(2, gantt.Id)
-	}
+// ErrGanttItemNotFound is returned when the gantt item does not exist
+var ErrGanttItemNotFound = errors.New("gantt item not found")
 
-	result, err := db.conn.Exec(updateQuery, newText, gantt.Id)
+// lockGanttItem returns the project and name of the item together with the role of the user in that project
+func lockGanttItem(ctx context.Context, tx *sql.Tx, itemID string, userID string) (string, string, ProjectRole, error) {
+	var projectID, ganttName string
+	err := tx.QueryRowContext(ctx, "SELECT project_id, gantt_name FROM gantt_items WHERE item_id = $1 FOR UPDATE", itemID).Scan(&projectID, &ganttName)
 	if err != nil {
-		log.Printf("failed to update feedback")
-		return err
+		if err == sql.ErrNoRows {
+			return "", "", "", ErrGanttItemNotFound
+		}
+		log.Printf("cannot read gantt item: %v", err)
+		return "", "", "", err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("created %d row.\nFeedback updated", rowsAffected)
-	return nil
-
-}
-
-func (db Client) enableAlert(number int, itemID string) error {
-	alert, err := db.conn.Exec("UPDATE gantt_items SET feedback_update_tracker = $1, colour = '#e6e600' WHERE item_id = $2", number, itemID)
+	role, err := projectRole(ctx, tx, projectID, userID)
 	if err != nil {
-		log.Printf("failed to update feedback status and colour")
-		return err
+		return "", "", "", err
 	}
-	rowsAffected, _ := alert.RowsAffected()
-	log.Printf("created %d row.\nFeedback status and alert colour updated", rowsAffected)
-	return nil
+	return projectID, ganttName, role, nil
 }
 
-func (db Client) DisableAlert(ctx context.Context, userID string, ganttID string) error {
-	isSupervisor, err := db.getAccountStatus(ctx, userID)
-	if err != nil {
-		log.Printf("failed to retrieve account status")
-		return err
-	}
-	if isSupervisor {
-		row := db.conn.QueryRowContext(ctx, "SELECT feedback_update_tracker FROM gantt_items WHERE item_id = $1", ganttID)
-		var val int
-		err := row.Scan(&val)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return fmt.Errorf("user with ID %s not found", ganttID)
-			}
-			// Handle other errors
-			log.Printf("cannot read data while getting alert: %v", err)
-			return err
-		}
-		if val == 2 {
-			alert, err := db.conn.Exec("UPDATE gantt_items SET feedback_update_tracker = 0, colour = '#2A9D39' WHERE item_id = $1", ganttID)
-			if err != nil {
-				log.Printf("failed to update feedback status and colour")
-				return err
-			}
-			rowsAffected, _ := alert.RowsAffected()
-			log.Printf("created %d row.\nFeedback status and alert colour updated", rowsAffected)
-			return nil
-		} else {
-			log.Printf("failed to remove alert as account is type supervisor when should be type student")
-			return nil
-		}
-	} else {
-
-		row := db.conn.QueryRowContext(ctx, "SELECT feedback_update_tracker FROM gantt_items WHERE item_id = $1", ganttID)
-		var val int
-		err := row.Scan(&val)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return fmt.Errorf("user with ID %s not found", ganttID)
-			}
-			// Handle other errors
-			log.Printf("cannot read data while getting alert: %v", err)
-			return err
-		}
-
-		if val == 1 {
-			alert, err := db.conn.Exec("UPDATE gantt_items SET feedback_update_tracker = 0, colour = '#2A9D39' WHERE item_id = $1", ganttID)
-			if err != nil {
-				log.Printf("failed to update feedback status and colour")
-				return err
-			}
-			rowsAffected, _ := alert.RowsAffected()
-			log.Printf("created %d row.\nFeedback status and alert colour updated", rowsAffected)
-			return nil
-		} else {
-			log.Printf("failed to remove alert as account is type student when should be type supervisor")
-			return nil
-		}
-	}
-
+// UpdateFeedback adds the new feedback of a project member to the item as a comment
+func (db Client) UpdateFeedback(ctx context.Context, gantt Gantt, userID string) (int, error) {
+	_, version, err := db.AddFeedbackComment(ctx, gantt.Id, userID, "", gantt.NewFeedBack, gantt.Version)
+	return version, err
 }
 
 func (db Client) getAccountStatus(ctx context.Context, id string) (bool, error) {
@@ -854,21 +802,6 @@ func (db Client) GetUsername(ctx context.Context, userId string) (string, error)
 	return name, nil
 }
 
-func (db Client) GetFeedback(ctx context.Context, ganttID string) (string, error) {
-	row := db.conn.QueryRowContext(ctx, "SELECT feedback FROM gantt_items WHERE item_id = $1", ganttID)
-	var feedback string
-	err := row.Scan(&feedback)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("feedback for gantt ID %s not found", ganttID)
-		}
-		// Handle other errors
-		log.Printf("cannot read data while getting feedback: %v", err)
-		return "", err
-	}
-	return feedback, nil
-}
-
 func (db Client) Verify(ctx context.Context, userID string) (*model.Verify, error) {
 	result := &model.Verify{}
 	rows, err := db.conn.QueryContext(ctx, "SELECT id, name from users where id = $1", userID)
@@ -905,43 +838,40 @@ func (db Client) updateUser(student_id string) error {
 
 }
 
+// AddSecondReader makes the reader the second reader of the project created from the application, a declared
+// conflict of interest with a member of the project refuses it
 func (db Client) AddSecondReader(ctx context.Context, readerID string, appID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to add second reader: %v", err)
+		return err
+	}
+	defer tx.Rollback()
 
-	query := `SELECT p.project_id
+	query := `SELECT p.project_id, p.supervisor_id
 FROM applications a INNER JOIN projects p
-    ON a.student_id = p.student_id
-WHERE id = $1`
+    ON a.student_id = p.student_id AND a.supervisor_id = p.supervisor_id
+WHERE a.id = $1 AND a.status = $2`
 
-	var projectID string
-	rows, err := db.conn.QueryContext(ctx, query, appID)
+	var projectID, supervisorID string
+	err = tx.QueryRowContext(ctx, query, appID, lifecycle.Accepted).Scan(&projectID, &supervisorID)
 	if err != nil {
-		log.Printf("failed to read application")
+		if err == sql.ErrNoRows {
+			return ErrProjectNotFound
+		}
+		log.Printf("cannot read project of application: %v", err)
 		return err
 	}
-	for rows.Next() {
-		err = rows.Scan(&projectID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return fmt.Errorf("feedback for gantt ID %s not found", projectID)
-			}
-			// Handle other errors
-			log.Printf("cannot read data while getting feedback: %v", err)
-			return err
-		}
-		println("got project id: ", projectID)
+	if readerID == supervisorID {
+		return fmt.Errorf("%w: the supervisor cannot be the second reader", ErrInvalidRole)
 	}
-	updateQuery := "UPDATE projects SET second_reader_id = $1 WHERE project_id = $2"
-
-	result, err := db.conn.Exec(updateQuery, readerID, projectID)
-	if err != nil {
-		log.Printf("failed to accept application")
+	if err = refuseConflicts(ctx, tx, projectID, readerID); err != nil {
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("created %d row.\n", rowsAffected)
-
-	return nil
-
+	if err = insertMember(ctx, tx, projectID, readerID, RoleSecondReader); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func GenerateUUID() string {
@@ -35,32 +35,79 @@ func TestClient_GetQuestions(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT ticket_id, question from tickets").
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets ORDER BY coalesce\(questionshort, ''\) ASC, ticket_id ASC LIMIT 21`).
 		WillReturnRows(sqlmock.NewRows(
 			[]string{
-				"ticket_id", "question",
+				"ticket_id", "questionshort", "questionshort",
 			},
 		).
-			AddRow(documents[0].ID, documents[0].Question).
-			AddRow(documents[1].ID, documents[1].Question)).
+			AddRow(documents[0].ID, documents[0].Question, documents[0].Question).
+			AddRow(documents[1].ID, documents[1].Question, documents[1].Question)).
 		RowsWillBeClosed()
 
 	d := &Client{
 		conn: db,
 	}
 
-	res, err := d.GetQuestions(context.Background())
+	res, err := d.GetQuestions(context.Background(), ListOptions{})
 	if !assert.Nil(t, err) {
 		return
 	}
 
-	assert.Equal(t, 2, len(res))
-	for i, row := range res {
+	assert.Equal(t, 2, len(res.Items))
+	assert.Nil(t, res.NextCursor)
+	for i, row := range res.Items {
 		assert.Equal(t, documents[i].ID, row.ID)
 		assert.Equal(t, documents[i].Question, row.Question)
 	}
 }
 
+func TestClient_GetQuestionsWithNullQuestion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets ORDER BY coalesce\(questionshort, ''\) ASC, ticket_id ASC LIMIT 21`).
+		WillReturnRows(sqlmock.NewRows([]string{"ticket_id", "questionshort", "questionshort"}).
+			AddRow("92d1cb6d-53c6-4bd0-bb10-1d64e98bfc92", nil, "")).
+		RowsWillBeClosed()
+
+	d := &Client{
+		conn: db,
+	}
+
+	res, err := d.GetQuestions(context.Background(), ListOptions{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []model.Question{{ID: "92d1cb6d-53c6-4bd0-bb10-1d64e98bfc92"}}, res.Items)
+}
+
+func TestClient_GetApplicationsWithNullValues(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM applications a INNER JOIN users u ON a.student_id = u.id WHERE a.supervisor_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "name", "supervisor_id", "heading", "description", "status", "proposal_id", "key"}).
+			AddRow("application-1", "student-1", nil, "supervisor-1", nil, nil, "submitted", "", "")).
+		RowsWillBeClosed()
+
+	d := &Client{
+		conn: db,
+	}
+
+	res, err := d.GetApplications(context.Background(), "supervisor-1", ListOptions{Sort: "studentName"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []model.ApplicationData{{ID: "application-1", StudentID: "student-1", SupervisorID: "supervisor-1", Status: "submitted"}}, res.Items)
+}
+
 func TestClient_GetQuestionsWithQueryError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -68,18 +115,18 @@ func TestClient_GetQuestionsWithQueryError(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT ticket_id, question from tickets").WillReturnError(errors.New("cannot query"))
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets ORDER BY coalesce\(questionshort, ''\) ASC, ticket_id ASC LIMIT 21`).WillReturnError(errors.New("cannot query"))
 
 	d := &Client{
 		conn: db,
 	}
 
-	res, err := d.GetQuestions(context.Background())
+	res, err := d.GetQuestions(context.Background(), ListOptions{})
 	if !assert.NotNil(t, err) {
 		return
 	}
 
-	assert.Equal(t, 0, len(res))
+	assert.Nil(t, res)
 	assert.Equal(t, "cannot query", err.Error())
 }
 
@@ -90,24 +137,96 @@ func TestClient_GetQuestionsWithScanError(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT ticket_id, question from tickets").
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets ORDER BY coalesce\(questionshort, ''\) ASC, ticket_id ASC LIMIT 21`).
 		WillReturnRows(sqlmock.NewRows(
 			[]string{
-				"ticket_id", "question",
+				"ticket_id", "questionshort", "questionshort",
 			},
 		).
-			AddRow(nil, "")).
+			AddRow(nil, "", "")).
 		RowsWillBeClosed()
 
 	d := &Client{
 		conn: db,
 	}
 
-	res, err := d.GetQuestions(context.Background())
+	res, err := d.GetQuestions(context.Background(), ListOptions{})
 	if !assert.NotNil(t, err) {
 		return
 	}
 
-	assert.Equal(t, 0, len(res))
+	assert.Nil(t, res)
 	assert.Equal(t, "sql: Scan error on column index 0, name \"ticket_id\": converting NULL to string is unsupported", err.Error())
 }
+
+func TestClient_GetQuestionsNextCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets ORDER BY coalesce\(questionshort, ''\) DESC, ticket_id DESC LIMIT 3`).
+		WillReturnRows(sqlmock.NewRows(
+			[]string{
+				"ticket_id", "questionshort", "questionshort",
+			},
+		).
+			AddRow("3", "C", "C").
+			AddRow("2", "B", "B").
+			AddRow("1", "A", "A")).
+		RowsWillBeClosed()
+
+	after := encodeCursor("-question", "B", "2")
+	mock.ExpectQuery(`SELECT ticket_id, questionshort, \(coalesce\(questionshort, ''\)\)::text FROM tickets WHERE \(coalesce\(questionshort, ''\), ticket_id\) < \(CAST\(\$1 AS text\), \$2\) ORDER BY coalesce\(questionshort, ''\) DESC, ticket_id DESC LIMIT 3`).
+		WithArgs("B", "2").
+		WillReturnRows(sqlmock.NewRows(
+			[]string{
+				"ticket_id", "questionshort", "questionshort",
+			},
+		).
+			AddRow("1", "A", "A")).
+		RowsWillBeClosed()
+
+	d := &Client{
+		conn: db,
+	}
+
+	res, err := d.GetQuestions(context.Background(), ListOptions{Limit: 2, Sort: "-question"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 2, len(res.Items))
+	if assert.NotNil(t, res.NextCursor) {
+		assert.Equal(t, after, *res.NextCursor)
+	}
+
+	res, err = d.GetQuestions(context.Background(), ListOptions{Limit: 2, Sort: "-question", After: after})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, len(res.Items))
+	assert.Nil(t, res.NextCursor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_GetQuestionsWithInvalidOptions(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.GetQuestions(context.Background(), ListOptions{Sort: "answer"})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+	_, err = d.GetQuestions(context.Background(), ListOptions{After: encodeCursor("-question", "B", "2")})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+	_, err = d.GetQuestions(context.Background(), ListOptions{After: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+}
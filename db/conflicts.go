@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"strings"
+)
+
+// ErrConflictOfInterest is returned when a second reader has a declared conflict with a member of the project
+var ErrConflictOfInterest = errors.New("conflict of interest")
+
+// ErrConflictNotFound is returned when withdrawing a conflict that was never declared
+var ErrConflictNotFound = errors.New("conflict of interest not found")
+
+// DeclareConflict records that the staff member must not read for the user, declaring it again updates the reason
+func (db Client) DeclareConflict(ctx context.Context, staffID string, userID string, reason string) error {
+	var exists bool
+	if err := db.conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		log.Printf("cannot read user: %v", err)
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	query := `INSERT INTO conflicts_of_interest (staff_id, user_id, reason) VALUES ($1, $2, $3)
+ON CONFLICT (staff_id, user_id) DO UPDATE SET reason = excluded.reason`
+	_, err := db.conn.ExecContext(ctx, query, staffID, userID, reason)
+	if err != nil {
+		log.Printf("failed to declare conflict of interest: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (db Client) WithdrawConflict(ctx context.Context, staffID string, userID string) error {
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM conflicts_of_interest WHERE staff_id = $1 AND user_id = $2", staffID, userID)
+	if err != nil {
+		log.Printf("failed to withdraw conflict of interest: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrConflictNotFound
+	}
+	return nil
+}
+
+func (db Client) GetConflicts(ctx context.Context, staffID string) ([]model.Conflict, error) {
+	query := `SELECT c.user_id, u.name, c.reason, c.declared_at
+FROM conflicts_of_interest c INNER JOIN users u ON u.id = c.user_id
+WHERE c.staff_id = $1
+ORDER BY u.name, c.user_id`
+	rows, err := db.conn.QueryContext(ctx, query, staffID)
+	if err != nil {
+		log.Printf("cannot execute query to get conflicts of interest: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Conflict{}
+	for rows.Next() {
+		var conflict model.Conflict
+		if err = rows.Scan(&conflict.UserID, &conflict.Name, &conflict.Reason, &conflict.DeclaredAt); err != nil {
+			log.Printf("cannot read data while getting conflicts of interest: %v", err)
+			return nil, err
+		}
+		conflict.DeclaredAt = conflict.DeclaredAt.UTC()
+		result = append(result, conflict)
+	}
+	return result, nil
+}
+
+// readerConflicts explains every declared conflict between the reader and a member of the project, whichever
+// side declared it
+func readerConflicts(ctx context.Context, tx *sql.Tx, projectID string, readerID string) ([]string, error) {
+	query := `SELECT u.name, c.reason, c.staff_id = $2
+FROM conflicts_of_interest c
+    INNER JOIN project_members m ON m.project_id = $1 AND m.user_id = CASE WHEN c.staff_id = $2 THEN c.user_id ELSE c.staff_id END
+    INNER JOIN users u ON u.id = m.user_id
+WHERE c.staff_id = $2 OR c.user_id = $2
+ORDER BY u.name`
+	rows, err := tx.QueryContext(ctx, query, projectID, readerID)
+	if err != nil {
+		log.Printf("cannot execute query to get conflicts of interest: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []string
+	for rows.Next() {
+		var (
+			name, reason     string
+			declaredByReader bool
+		)
+		if err = rows.Scan(&name, &reason, &declaredByReader); err != nil {
+			log.Printf("cannot read data while getting conflicts of interest: %v", err)
+			return nil, err
+		}
+		if declaredByReader {
+			conflicts = append(conflicts, fmt.Sprintf("the reader declared a conflict with %s (%s)", name, reason))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("%s declared a conflict with the reader (%s)", name, reason))
+		}
+	}
+	return conflicts, nil
+}
+
+// refuseConflicts fails when the reader has a conflict with a member of the project
+func refuseConflicts(ctx context.Context, tx *sql.Tx, projectID string, readerID string) error {
+	conflicts, err := readerConflicts(ctx, tx, projectID, readerID)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return conflictError(conflicts)
+	}
+	return nil
+}
+
+func conflictError(conflicts []string) error {
+	return fmt.Errorf("%w: %s", ErrConflictOfInterest, strings.Join(conflicts, "; "))
+}
+
+// conflictPairs returns the users each staff member has a conflict with, a conflict counts for both sides
+func (db Client) conflictPairs(ctx context.Context) (map[string][]string, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT staff_id, user_id FROM conflicts_of_interest ORDER BY staff_id, user_id")
+	if err != nil {
+		log.Printf("cannot execute query to get conflicts of interest: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string][]string{}
+	var staffID, userID string
+	for rows.Next() {
+		if err = rows.Scan(&staffID, &userID); err != nil {
+			log.Printf("cannot read data while getting conflicts of interest: %v", err)
+			return nil, err
+		}
+		result[staffID] = append(result[staffID], userID)
+		result[userID] = append(result[userID], staffID)
+	}
+	return result, nil
+}
+
+// AssignSecondReader lets a coordinator make the supervisor second reader of a project. A conflict of interest
+// refuses the assignment unless an override reason is given, the override is recorded in the audit log.
+func (db Client) AssignSecondReader(ctx context.Context, projectID string, readerID string, coordinatorID string, overrideReason string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to assign second reader: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var projectName string
+	if err = tx.QueryRowContext(ctx, "SELECT project_name FROM projects WHERE project_id = $1", projectID).Scan(&projectName); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProjectNotFound
+		}
+		log.Printf("cannot read project: %v", err)
+		return err
+	}
+	var isSupervisor bool
+	if err = tx.QueryRowContext(ctx, "SELECT is_supervisor FROM users WHERE id = $1", readerID).Scan(&isSupervisor); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		log.Printf("cannot read user: %v", err)
+		return err
+	}
+	if !isSupervisor {
+		return ErrNotSupervisor
+	}
+
+	conflicts, err := readerConflicts(ctx, tx, projectID, readerID)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 && overrideReason == "" {
+		return conflictError(conflicts)
+	}
+
+	if err = insertMember(ctx, tx, projectID, readerID, RoleSecondReader); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		detail := fmt.Sprintf("%s made second reader despite: %s. Reason: %s", readerID, strings.Join(conflicts, "; "), overrideReason)
+		if err = audit(ctx, tx, coordinatorID, "second_reader_conflict_override", projectID, detail); err != nil {
+			return err
+		}
+	}
+	err = notify(ctx, tx, readerID, "project_member_added", fmt.Sprintf("You were added to the project \"%s\" as second reader", projectName))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_AddSecondReaderRefusesConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT p.project_id, p.supervisor_id FROM applications a INNER JOIN projects p`).
+		WithArgs("app-1", "accepted").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "supervisor_id"}).AddRow("project-1", "supervisor-1"))
+	mock.ExpectQuery(`FROM conflicts_of_interest c INNER JOIN project_members m ON m.project_id = \$1`).
+		WithArgs("project-1", "reader-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "reason", "declared_by_reader"}).
+			AddRow("Ada", "personal tutor", true).
+			AddRow("Grace", "co-author", false))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.AddSecondReader(context.Background(), "reader-1", "app-1")
+	assert.ErrorIs(t, err, ErrConflictOfInterest)
+	assert.EqualError(t, err, "conflict of interest: the reader declared a conflict with Ada (personal tutor); Grace declared a conflict with the reader (co-author)")
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_AssignSecondReaderOverride(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectAssignment := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT project_name FROM projects WHERE project_id = \$1`).
+			WithArgs("project-1").
+			WillReturnRows(sqlmock.NewRows([]string{"project_name"}).AddRow("A Go compiler"))
+		mock.ExpectQuery(`SELECT is_supervisor FROM users WHERE id = \$1`).
+			WithArgs("reader-1").
+			WillReturnRows(sqlmock.NewRows([]string{"is_supervisor"}).AddRow(true))
+		mock.ExpectQuery(`FROM conflicts_of_interest c`).
+			WithArgs("project-1", "reader-1").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "reason", "declared_by_reader"}).AddRow("Ada", "personal tutor", true))
+	}
+
+	d := &Client{
+		conn: db,
+	}
+
+	expectAssignment()
+	mock.ExpectRollback()
+	err = d.AssignSecondReader(context.Background(), "project-1", "reader-1", "coordinator", "")
+	assert.ErrorIs(t, err, ErrConflictOfInterest)
+
+	expectAssignment()
+	mock.ExpectExec(`INSERT INTO project_members \(project_id, user_id, role\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("project-1", "reader-1", "second_reader").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO audit_log \(id, actor_id, action, subject_id, detail\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs(sqlmock.AnyArg(), "coordinator", "second_reader_conflict_override", "project-1",
+			"reader-1 made second reader despite: the reader declared a conflict with Ada (personal tutor). Reason: only expert in the field").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "reader-1", "project_member_added", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	err = d.AssignSecondReader(context.Background(), "project-1", "reader-1", "coordinator", "only expert in the field")
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
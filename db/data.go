@@ -1,5 +1,11 @@
 package db
 
+import (
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"time"
+)
+
 type User struct {
 	Id   string //id
 	Name string
@@ -19,20 +25,137 @@ type Application struct {
 	SupervisorID string
 	Heading      string
 	Description  string
-	Accepted     bool
-	Declined     bool
+	Status       lifecycle.Status
+	Reason       string
+	Waitlist     bool
+	ProposalID   string
+	Attachments  []Attachment // stored together with the application
+	Teammates    []string     // students invited to a joint application
+	// ResponseDeadline of the round the application was submitted in, supervisors cannot act on it afterwards
+	ResponseDeadline *time.Time
+	TemplateID       string // milestone template of the project created on acceptance, the default template when empty
+	FollowTemplate   bool   // the project takes over later changes to the template
+	ProjectID        string // set when accepting the application created a project
+}
+
+type Round struct {
+	ID                string
+	Name              string
+	OpensAt           time.Time
+	ClosesAt          time.Time
+	PendingExpiryDays *int // nil falls back to the configured default
+	OfferExpiryDays   *int
+	StudentCap        *int       // nil for no limit
+	ResponseDeadline  *time.Time // nil when supervisors have no deadline
+}
+
+type Attachment struct {
+	ID            string
+	ApplicationID string
+	UploadedBy    string
+	FileName      string
+	ContentType   string
+	Size          int64
+	SHA256        string
+	StorageKey    string
+	CreatedAt     time.Time
+}
+
+type InterviewSlot struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+	Location string
+}
+
+type Contribution struct {
+	UserID string
+	Share  int // percent of the item
+	Note   string
+}
+
+type Dependency struct {
+	PredecessorID string
+	SuccessorID   string
+	Type          schedule.DependencyType
+	LagDays       int
+}
+
+type ProjectRole string
+
+const (
+	RolePrimarySupervisor ProjectRole = "primary_supervisor"
+	RoleCoSupervisor      ProjectRole = "co_supervisor"
+	RoleSecondReader      ProjectRole = "second_reader"
+	RoleExternalMentor    ProjectRole = "external_mentor"
+	RoleStudent           ProjectRole = "student"
+)
+
+type GanttStatus string
+
+const (
+	GanttOnTrack  GanttStatus = "on_track"
+	GanttAtRisk   GanttStatus = "at_risk"
+	GanttBlocked  GanttStatus = "blocked"
+	GanttComplete GanttStatus = "complete"
+)
+
+type ProposalStatus string
+
+const (
+	ProposalDraft     ProposalStatus = "draft"
+	ProposalPublished ProposalStatus = "published"
+	ProposalArchived  ProposalStatus = "archived"
+)
+
+type Proposal struct {
+	ID            string
+	SupervisorID  string
+	Title         string
+	Description   string
+	Prerequisites string
+	Tags          []string
+	Slots         int
 }
 
 type Gantt struct {
 	Id          string
 	ProjectID   string
 	GanttName   string
-	StartDate   string
-	EndDate     string
+	StartDate   time.Time
+	EndDate     time.Time
 	Description string
 	Links       string
-	Feedback    string
 	NewFeedBack string
+	Version     int // the version the client read, 0 when it sent none
+}
+
+// MilestoneTemplate is the list of milestones projects start with
+type MilestoneTemplate struct {
+	ID         string
+	Name       string
+	IsDefault  bool
+	Milestones []TemplateMilestone
+}
+
+// TemplateMilestone is placed in days after the start of the academic year of a project
+type TemplateMilestone struct {
+	ID             string // empty for a milestone added to the template
+	Name           string
+	Description    string
+	Links          string
+	StartDay       int
+	EndDay         int
+	DeadlineLocked bool
+}
+
+// GanttPatch holds the fields of an item to change, nil fields keep their value
+type GanttPatch struct {
+	GanttName      *string
+	StartDate      *time.Time
+	EndDate        *time.Time
+	Description    *string
+	Links          *string
+	DeadlineLocked *bool
 }
 
 type Question struct {
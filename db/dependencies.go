@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"log"
+	"time"
+)
+
+// ErrInvalidDependency is returned when an item would depend on itself or on an item of another project
+var ErrInvalidDependency = errors.New("items can only depend on other items of the same project")
+
+// ErrDependencyNotFound is returned when removing a dependency that does not exist
+var ErrDependencyNotFound = errors.New("dependency not found")
+
+type rowsQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ganttDependencies returns the dependencies between the items of a project
+func ganttDependencies(ctx context.Context, conn rowsQueryer, projectID string) ([]Dependency, error) {
+	query := `SELECT d.predecessor_id, d.successor_id, d.kind, d.lag_days
+FROM gantt_dependencies d INNER JOIN gantt_items g ON g.item_id = d.successor_id
+WHERE g.project_id = $1
+ORDER BY d.successor_id, d.predecessor_id`
+	rows, err := conn.QueryContext(ctx, query, projectID)
+	if err != nil {
+		log.Printf("cannot execute query to get dependencies: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Dependency{}
+	for rows.Next() {
+		var dependency Dependency
+		if err = rows.Scan(&dependency.PredecessorID, &dependency.SuccessorID, &dependency.Type, &dependency.LagDays); err != nil {
+			log.Printf("cannot read data while getting dependencies: %v", err)
+			return nil, err
+		}
+		result = append(result, dependency)
+	}
+	return result, nil
+}
+
+func (d Dependency) schedule() schedule.Dependency {
+	return schedule.Dependency{
+		PredecessorID: d.PredecessorID,
+		SuccessorID:   d.SuccessorID,
+		Type:          d.Type,
+		Lag:           time.Duration(d.LagDays) * 24 * time.Hour,
+	}
+}
+
+// AddGanttDependency makes an item wait for another item of the same project, adding it again changes the type
+// and lag. A dependency that closes a cycle is refused with schedule.ErrCycle.
+func (db Client) AddGanttDependency(ctx context.Context, dependency Dependency, userID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to add dependency: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	projectID, _, _, err := lockGanttItem(ctx, tx, dependency.SuccessorID, userID)
+	if err != nil {
+		return err
+	}
+	var predecessorProject string
+	err = tx.QueryRowContext(ctx, "SELECT project_id FROM gantt_items WHERE item_id = $1", dependency.PredecessorID).Scan(&predecessorProject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrGanttItemNotFound
+		}
+		log.Printf("cannot read gantt item: %v", err)
+		return err
+	}
+	if predecessorProject != projectID || dependency.PredecessorID == dependency.SuccessorID {
+		return ErrInvalidDependency
+	}
+	//concurrent additions to the same project could close a cycle together
+	if _, err = tx.ExecContext(ctx, "SELECT 1 FROM projects WHERE project_id = $1 FOR UPDATE", projectID); err != nil {
+		log.Printf("cannot lock project: %v", err)
+		return err
+	}
+
+	existing, err := ganttDependencies(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+	dependencies := []schedule.Dependency{dependency.schedule()}
+	for _, d := range existing {
+		if d.PredecessorID != dependency.PredecessorID || d.SuccessorID != dependency.SuccessorID {
+			dependencies = append(dependencies, d.schedule())
+		}
+	}
+	var tasks []schedule.Task //items without dependencies cannot be on a cycle
+	seen := map[string]bool{}
+	for _, d := range dependencies {
+		for _, id := range []string{d.PredecessorID, d.SuccessorID} {
+			if !seen[id] {
+				seen[id] = true
+				tasks = append(tasks, schedule.Task{ID: id})
+			}
+		}
+	}
+	if err = schedule.CheckCycle(tasks, dependencies); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO gantt_dependencies (predecessor_id, successor_id, kind, lag_days) VALUES ($1, $2, $3, $4)
+ON CONFLICT (predecessor_id, successor_id) DO UPDATE SET kind = excluded.kind, lag_days = excluded.lag_days`
+	_, err = tx.ExecContext(ctx, query, dependency.PredecessorID, dependency.SuccessorID, dependency.Type, dependency.LagDays)
+	if err != nil {
+		log.Printf("failed to add dependency: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db Client) RemoveGanttDependency(ctx context.Context, predecessorID string, successorID string, userID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to remove dependency: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, _, err = lockGanttItem(ctx, tx, successorID, userID); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM gantt_dependencies WHERE predecessor_id = $1 AND successor_id = $2", predecessorID, successorID)
+	if err != nil {
+		log.Printf("failed to remove dependency: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrDependencyNotFound
+	}
+	return tx.Commit()
+}
+
+// planGantt attaches the dependencies to the items and marks the critical path with the slack of every item
+func planGantt(items []*model.Gantt, dependencies []Dependency) {
+	tasks := make([]schedule.Task, len(items))
+	byID := make(map[string]*model.Gantt, len(items))
+	for i, item := range items {
+		tasks[i] = schedule.Task{ID: item.ID, Start: item.StartDate, End: item.EndDate}
+		byID[item.ID] = item
+	}
+	converted := make([]schedule.Dependency, len(dependencies))
+	for i, dependency := range dependencies {
+		converted[i] = dependency.schedule()
+		if item, ok := byID[dependency.SuccessorID]; ok {
+			item.Dependencies = append(item.Dependencies, model.Dependency{
+				PredecessorID: dependency.PredecessorID,
+				Type:          string(dependency.Type),
+				LagDays:       dependency.LagDays,
+			})
+		}
+	}
+
+	timings, err := schedule.Analyze(tasks, converted)
+	if err != nil { //cycles are refused when dependencies are added
+		log.Printf("cannot compute critical path: %v", err)
+		return
+	}
+	for id, timing := range timings {
+		byID[id].SlackDays = timing.Slack.Hours() / 24
+		byID[id].Critical = timing.Critical
+	}
+}
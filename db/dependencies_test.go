@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_GetGanttCriticalPath(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	day := func(n int) time.Time {
+		return time.Date(2024, 10, 7+n, 0, 0, 0, 0, time.UTC)
+	}
+	mock.ExpectQuery(`SELECT item_id, project_id, gantt_name, start_date, end_date, description, links, status, percent_complete, deadline_locked, version from gantt_items where project_id = \$1`).
+		WithArgs("project-1").
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "project_id", "gantt_name", "start_date", "end_date", "description", "links", "status", "percent_complete", "deadline_locked", "version"}).
+			AddRow("design", "project-1", "Design", day(0), day(5), "", "", "on_track", 0, false, 1).
+			AddRow("poster", "project-1", "Poster", day(5), day(7), "", "", "on_track", 0, false, 1).
+			AddRow("build", "project-1", "Implementation", day(5), day(15), "", "", "on_track", 0, false, 1))
+	mock.ExpectQuery(`FROM gantt_item_contributions c`).
+		WithArgs("project-1").
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "user_id", "name", "share", "note"}))
+	mock.ExpectQuery(`FROM gantt_dependencies d INNER JOIN gantt_items g ON g.item_id = d.successor_id WHERE g.project_id = \$1`).
+		WithArgs("project-1").
+		WillReturnRows(sqlmock.NewRows([]string{"predecessor_id", "successor_id", "kind", "lag_days"}).
+			AddRow("design", "build", "finish_to_start", 0).
+			AddRow("design", "poster", "finish_to_start", 1))
+
+	d := &Client{
+		conn: db,
+	}
+
+	rows, err := d.GetGantt(context.Background(), "project-1")
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.True(t, rows[0].Content[0].Critical)
+		assert.False(t, rows[1].Content[0].Critical)
+		assert.Equal(t, 7.0, rows[1].Content[0].SlackDays)
+		assert.Equal(t, []model.Dependency{{PredecessorID: "design", Type: "finish_to_start", LagDays: 1}}, rows[1].Content[0].Dependencies)
+		assert.True(t, rows[2].Content[0].Critical)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_AddGanttDependencyRefusesCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Design"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`SELECT project_id FROM gantt_items WHERE item_id = \$1`).
+		WithArgs("report").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow("project-1"))
+	mock.ExpectExec(`SELECT 1 FROM projects WHERE project_id = \$1 FOR UPDATE`).
+		WithArgs("project-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM gantt_dependencies d`).
+		WithArgs("project-1").
+		WillReturnRows(sqlmock.NewRows([]string{"predecessor_id", "successor_id", "kind", "lag_days"}).
+			AddRow("design", "build", "finish_to_start", 0).
+			AddRow("build", "report", "finish_to_start", 0))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.AddGanttDependency(context.Background(), Dependency{PredecessorID: "report", SuccessorID: "design", Type: schedule.FinishToStart}, "student-1")
+	assert.ErrorIs(t, err, schedule.ErrCycle)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
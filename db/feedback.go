@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"strings"
+)
+
+// ErrCommentNotFound is returned when the comment does not exist on the item or was deleted
+var ErrCommentNotFound = errors.New("comment not found")
+
+// ErrNotCommentAuthor is returned when someone other than the author edits or deletes a comment
+var ErrNotCommentAuthor = errors.New("only the author can change a comment")
+
+// ganttItemProject returns the project of the gantt item
+func ganttItemProject(ctx context.Context, conn queryer, itemID string) (string, error) {
+	var projectID string
+	err := conn.QueryRowContext(ctx, "SELECT project_id FROM gantt_items WHERE item_id = $1", itemID).Scan(&projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrGanttItemNotFound
+		}
+		log.Printf("cannot read gantt item: %v", err)
+		return "", err
+	}
+	return projectID, nil
+}
+
+// AddFeedbackComment adds a comment of a project member to the item, or a reply when parentID names a comment on
+// the same item. Every other member of the project is notified and has the comment left to read.
+func (db Client) AddFeedbackComment(ctx context.Context, itemID string, userID string, parentID string, body string, version int) (*model.FeedbackComment, int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to add feedback: %v", err)
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	projectID, ganttName, role, err := lockGanttItem(ctx, tx, itemID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var parent sql.NullString
+	if parentID != "" {
+		var exists bool
+		err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM feedback_comments WHERE id = $1 AND item_id = $2 AND deleted_at IS NULL)", parentID, itemID).Scan(&exists)
+		if err != nil {
+			log.Printf("cannot read feedback comment: %v", err)
+			return nil, 0, err
+		}
+		if !exists {
+			return nil, 0, ErrCommentNotFound
+		}
+		parent = sql.NullString{String: parentID, Valid: true}
+	}
+	version, err = bumpGanttVersion(ctx, tx, itemID, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	comment := model.FeedbackComment{
+		ID:       GenerateUUID(),
+		ItemID:   itemID,
+		ParentID: parentID,
+		AuthorID: userID,
+		Role:     string(role),
+		Body:     body,
+	}
+	query := "INSERT INTO feedback_comments (id, item_id, parent_id, author_id, role, body) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at"
+	err = tx.QueryRowContext(ctx, query, comment.ID, itemID, parent, userID, role, body).Scan(&comment.CreatedAt)
+	if err != nil {
+		log.Printf("failed to add feedback: %v", err)
+		return nil, 0, err
+	}
+	comment.CreatedAt = comment.CreatedAt.UTC()
+
+	err = notifyProjectMembers(ctx, tx, projectID, userID, "gantt_feedback", fmt.Sprintf("%s left feedback on \"%s\"", role.Label(), ganttName))
+	if err != nil {
+		return nil, 0, err
+	}
+	return &comment, version, tx.Commit()
+}
+
+// GetFeedbackComments returns the comments and replies on an item oldest first, deleted comments keep their place
+// without their body
+func (db Client) GetFeedbackComments(ctx context.Context, itemID string, userID string, opts ListOptions) (*model.Page[model.FeedbackComment], error) {
+	projectID, err := ganttItemProject(ctx, db.conn, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	q := listQuery{
+		columns: `c.id, c.item_id, coalesce(c.parent_id::text, ''), coalesce(c.author_id, ''), coalesce(u.name, ''), coalesce(c.role, ''),
+    CASE WHEN c.deleted_at IS NULL THEN c.body ELSE '' END, c.imported, c.deleted_at IS NOT NULL, c.created_at, c.edited_at`,
+		from:     "feedback_comments c LEFT JOIN users u ON u.id = c.author_id",
+		idColumn: "c.id",
+	}
+	q.where("c.item_id = " + q.arg(itemID))
+	q.dateRange("c.created_at", opts)
+	query, args, err := q.build(opts, feedbackSorts, "createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get feedback: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.FeedbackComment](opts, "createdAt")
+	for rows.Next() {
+		var (
+			comment  model.FeedbackComment
+			editedAt sql.NullTime
+			key      string
+		)
+		err = rows.Scan(&comment.ID, &comment.ItemID, &comment.ParentID, &comment.AuthorID, &comment.AuthorName, &comment.Role,
+			&comment.Body, &comment.Imported, &comment.Deleted, &comment.CreatedAt, &editedAt, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting feedback: %v", err)
+			return nil, err
+		}
+		comment.CreatedAt = comment.CreatedAt.UTC()
+		if editedAt.Valid {
+			at := editedAt.Time.UTC()
+			comment.EditedAt = &at
+		}
+		result.add(comment, comment.ID, key)
+	}
+	return result.page(), nil
+}
+
+// lockFeedbackComment checks that the comment is on the item, is not deleted and was written by the user
+func lockFeedbackComment(ctx context.Context, tx *sql.Tx, itemID string, commentID string, userID string) error {
+	var authorID sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT author_id FROM feedback_comments WHERE id = $1 AND item_id = $2 AND deleted_at IS NULL FOR UPDATE", commentID, itemID).
+		Scan(&authorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrCommentNotFound
+		}
+		log.Printf("cannot read feedback comment: %v", err)
+		return err
+	}
+	if authorID.String != userID {
+		return ErrNotCommentAuthor
+	}
+	return nil
+}
+
+// EditFeedbackComment replaces the body of a comment, only its author can edit it
+func (db Client) EditFeedbackComment(ctx context.Context, itemID string, commentID string, userID string, body string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to edit feedback: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFeedbackComment(ctx, tx, itemID, commentID, userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE feedback_comments SET body = $1, edited_at = now() WHERE id = $2", body, commentID)
+	if err != nil {
+		log.Printf("failed to edit feedback: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteFeedbackComment hides a comment of the author, its replies stay in the thread
+func (db Client) DeleteFeedbackComment(ctx context.Context, itemID string, commentID string, userID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to delete feedback: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFeedbackComment(ctx, tx, itemID, commentID, userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE feedback_comments SET deleted_at = now() WHERE id = $1", commentID)
+	if err != nil {
+		log.Printf("failed to delete feedback: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetFeedback returns the comments on an item in the text form feedback used to be stored in, for clients that
+// still read it
+func (db Client) GetFeedback(ctx context.Context, ganttID string) (string, error) {
+	if _, err := ganttItemProject(ctx, db.conn, ganttID); err != nil {
+		return "", err
+	}
+	rows, err := db.conn.QueryContext(ctx, "SELECT coalesce(role, ''), body FROM feedback_comments WHERE item_id = $1 AND deleted_at IS NULL ORDER BY created_at, id", ganttID)
+	if err != nil {
+		log.Printf("cannot execute query to get feedback: %v", err)
+		return "", err
+	}
+	defer rows.Close()
+
+	var (
+		feedback   strings.Builder
+		role, body string
+	)
+	for rows.Next() {
+		if err = rows.Scan(&role, &body); err != nil {
+			log.Printf("cannot read data while getting feedback: %v", err)
+			return "", err
+		}
+		if role != "" {
+			feedback.WriteString(ProjectRole(role).Label() + ": ")
+		}
+		feedback.WriteString(body + "\n\n")
+	}
+	return feedback.String(), nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+)
+
+// unreadComments joins the comments on the items g the user $1 has not read, the ones other members wrote after
+// the last read marker of the user on the item
+const unreadComments = `LEFT JOIN feedback_reads r ON r.item_id = g.item_id AND r.user_id = $1
+LEFT JOIN feedback_comments c ON c.item_id = g.item_id AND c.deleted_at IS NULL AND c.author_id IS DISTINCT FROM $1
+    AND (r.read_at IS NULL OR c.created_at > r.read_at)`
+
+// MarkFeedbackRead moves the read marker of the user on the item to now
+func (db Client) MarkFeedbackRead(ctx context.Context, itemID string, userID string) error {
+	projectID, err := ganttItemProject(ctx, db.conn, itemID)
+	if err != nil {
+		return err
+	}
+	if _, err = projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return err
+	}
+	_, err = db.conn.ExecContext(ctx, `INSERT INTO feedback_reads (item_id, user_id, read_at) VALUES ($1, $2, now())
+ON CONFLICT (item_id, user_id) DO UPDATE SET read_at = excluded.read_at`, itemID, userID)
+	if err != nil {
+		log.Printf("failed to mark feedback as read: %v", err)
+		return err
+	}
+	return nil
+}
+
+// MarkAllFeedbackRead moves the read markers of the user on every item of their projects to now, or only on the
+// items of one project when projectID is set
+func (db Client) MarkAllFeedbackRead(ctx context.Context, userID string, projectID string) error {
+	if projectID != "" {
+		if _, err := projectRole(ctx, db.conn, projectID, userID); err != nil {
+			return err
+		}
+	}
+	query := `INSERT INTO feedback_reads (item_id, user_id, read_at)
+SELECT g.item_id, m.user_id, now()
+FROM gantt_items g INNER JOIN project_members m ON m.project_id = g.project_id
+WHERE m.user_id = $1 AND ($2 = '' OR g.project_id::text = $2)
+ON CONFLICT (item_id, user_id) DO UPDATE SET read_at = excluded.read_at`
+	_, err := db.conn.ExecContext(ctx, query, userID, projectID)
+	if err != nil {
+		log.Printf("failed to mark all feedback as read: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetUnreadFeedback counts the comments the user has not read in each of their projects, projects without unread
+// comments are left out
+func (db Client) GetUnreadFeedback(ctx context.Context, userID string) (*model.UnreadFeedback, error) {
+	query := `SELECT p.project_id, p.project_name, count(c.id)
+FROM project_members m INNER JOIN projects p ON p.project_id = m.project_id INNER JOIN gantt_items g ON g.project_id = p.project_id
+` + unreadComments + `
+WHERE m.user_id = $1
+GROUP BY p.project_id, p.project_name
+HAVING count(c.id) > 0
+ORDER BY p.project_name, p.project_id`
+	rows, err := db.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("cannot execute query to get unread feedback: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &model.UnreadFeedback{Projects: []model.ProjectUnread{}}
+	for rows.Next() {
+		var project model.ProjectUnread
+		if err = rows.Scan(&project.ProjectID, &project.ProjectName, &project.Unread); err != nil {
+			log.Printf("cannot read data while getting unread feedback: %v", err)
+			return nil, err
+		}
+		result.Total += project.Unread
+		result.Projects = append(result.Projects, project)
+	}
+	return result, nil
+}
+
+// GetProjectUnreadFeedback counts the comments the user has not read on each item of the project, items without
+// unread comments are left out
+func (db Client) GetProjectUnreadFeedback(ctx context.Context, projectID string, userID string) (*model.UnreadFeedback, error) {
+	if _, err := projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return nil, err
+	}
+	query := `SELECT g.item_id, g.gantt_name, count(c.id)
+FROM gantt_items g
+` + unreadComments + `
+WHERE g.project_id = $2
+GROUP BY g.item_id, g.gantt_name, g.start_date
+HAVING count(c.id) > 0
+ORDER BY g.start_date, g.item_id`
+	rows, err := db.conn.QueryContext(ctx, query, userID, projectID)
+	if err != nil {
+		log.Printf("cannot execute query to get unread feedback: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &model.UnreadFeedback{Items: []model.ItemUnread{}}
+	for rows.Next() {
+		var item model.ItemUnread
+		if err = rows.Scan(&item.ItemID, &item.GanttName, &item.Unread); err != nil {
+			log.Printf("cannot read data while getting unread feedback: %v", err)
+			return nil, err
+		}
+		result.Total += item.Unread
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
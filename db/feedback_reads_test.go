@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_GetUnreadFeedback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`LEFT JOIN feedback_comments c ON c.item_id = g.item_id AND c.deleted_at IS NULL AND c.author_id IS DISTINCT FROM \$1 AND \(r.read_at IS NULL OR c.created_at > r.read_at\) WHERE m.user_id = \$1`).
+		WithArgs("reader-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "project_name", "count"}).
+			AddRow("project-1", "A Go compiler", 3).
+			AddRow("project-2", "Robot arm", 1))
+
+	d := &Client{
+		conn: db,
+	}
+
+	unread, err := d.GetUnreadFeedback(context.Background(), "reader-1")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.UnreadFeedback{
+		Total: 4,
+		Projects: []model.ProjectUnread{
+			{ProjectID: "project-1", ProjectName: "A Go compiler", Unread: 3},
+			{ProjectID: "project-2", ProjectName: "Robot arm", Unread: 1},
+		},
+	}, unread)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_MarkFeedbackReadNotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT project_id FROM gantt_items WHERE item_id = \$1`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow("project-1"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "outsider").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(nil))
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.MarkFeedbackRead(context.Background(), "item-1", "outsider")
+	assert.ErrorIs(t, err, ErrNotProjectMember)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_MarkAllFeedbackReadInProject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "supervisor-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("co_supervisor"))
+	mock.ExpectExec(`INSERT INTO feedback_reads \(item_id, user_id, read_at\) SELECT g.item_id, m.user_id, now\(\)`).
+		WithArgs("supervisor-1", "project-1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.MarkAllFeedbackRead(context.Background(), "supervisor-1", "project-1")
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_GetFeedbackComments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT project_id FROM gantt_items WHERE item_id = \$1`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow("project-1"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`FROM feedback_comments c LEFT JOIN users u ON u.id = c.author_id WHERE c.item_id = \$1 ORDER BY c.created_at ASC, c.id ASC LIMIT 21`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_id", "parent_id", "author_id", "name", "role", "body", "imported", "deleted", "created_at", "edited_at", "key"}).
+			AddRow("comment-1", "item-1", "", "", "", "primary_supervisor", "Start with the survey", true, false, createdAt, nil, "k1").
+			AddRow("comment-2", "item-1", "comment-1", "student-1", "Ada", "student", "", false, true, createdAt.Add(time.Hour), createdAt.Add(2*time.Hour), "k2"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	page, err := d.GetFeedbackComments(context.Background(), "item-1", "student-1", ListOptions{})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Items, 2) {
+		assert.True(t, page.Items[0].Imported)
+		assert.Equal(t, "comment-1", page.Items[1].ParentID)
+		assert.True(t, page.Items[1].Deleted)
+		assert.Equal(t, "", page.Items[1].Body)
+		assert.Equal(t, createdAt.Add(2*time.Hour), *page.Items[1].EditedAt)
+	}
+	assert.Nil(t, page.NextCursor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_AddFeedbackReplyToOtherItem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Design"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM feedback_comments WHERE id = \$1 AND item_id = \$2 AND deleted_at IS NULL\)`).
+		WithArgs("comment-9", "item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, _, err = d.AddFeedbackComment(context.Background(), "item-1", "student-1", "comment-9", "thanks", 0)
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_EditFeedbackCommentOfSomeoneElse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT author_id FROM feedback_comments WHERE id = \$1 AND item_id = \$2 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs("comment-1", "item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"author_id"}).AddRow("supervisor-1"))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.EditFeedbackComment(context.Background(), "item-1", "comment-1", "student-1", "rewritten")
+	assert.ErrorIs(t, err, ErrNotCommentAuthor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_GetFeedbackAsText(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT project_id FROM gantt_items WHERE item_id = \$1`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}).AddRow("project-1"))
+	mock.ExpectQuery(`SELECT coalesce\(role, ''\), body FROM feedback_comments WHERE item_id = \$1 AND deleted_at IS NULL ORDER BY created_at, id`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "body"}).
+			AddRow("", "notes from the first meeting").
+			AddRow("primary_supervisor", "looks good").
+			AddRow("student", "thanks"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	feedback, err := d.GetFeedback(context.Background(), "item-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "notes from the first meeting\n\nSupervisor: looks good\n\nStudent: thanks\n\n", feedback)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrDeadlineLocked is returned when a member other than a supervisor moves a locked end date or locks it
+var ErrDeadlineLocked = errors.New("only supervisors can change a locked deadline")
+
+// ErrInvalidGanttEdit is returned when the edited item would end before it starts
+var ErrInvalidGanttEdit = errors.New("gantt item must not end before it starts")
+
+// ganttFields are the editable fields of an item
+type ganttFields struct {
+	name           string
+	startDate      time.Time
+	endDate        time.Time
+	description    string
+	links          string
+	deadlineLocked bool
+}
+
+// EditGanttItem applies the patch to the item of a project the user is a member of. Once supervisors locked the
+// deadline only they can move the end date, and only they can lock or unlock it. Every changed field is recorded in
+// the audit log, a patch that changes nothing is not. A version other than 0 must be the current version of the item.
+func (db Client) EditGanttItem(ctx context.Context, itemID string, userID string, patch GanttPatch, version int) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to edit gantt item: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, _, role, err := lockGanttItem(ctx, tx, itemID, userID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		current        ganttFields
+		currentVersion int
+	)
+	err = tx.QueryRowContext(ctx, "SELECT gantt_name, start_date, end_date, description, links, deadline_locked, version FROM gantt_items WHERE item_id = $1", itemID).
+		Scan(&current.name, &current.startDate, &current.endDate, &current.description, &current.links, &current.deadlineLocked, &currentVersion)
+	if err != nil {
+		log.Printf("cannot read gantt item: %v", err)
+		return err
+	}
+	if version != 0 && version != currentVersion {
+		return ErrVersionMismatch
+	}
+	current.startDate = current.startDate.UTC()
+	current.endDate = current.endDate.UTC()
+
+	edited := current
+	var changes []string
+	if patch.GanttName != nil && *patch.GanttName != current.name {
+		edited.name = *patch.GanttName
+		changes = append(changes, fmt.Sprintf("ganttName: %q -> %q", current.name, edited.name))
+	}
+	if patch.StartDate != nil && !patch.StartDate.Equal(current.startDate) {
+		edited.startDate = patch.StartDate.UTC()
+		changes = append(changes, fmt.Sprintf("startDate: %s -> %s", current.startDate.Format(time.RFC3339), edited.startDate.Format(time.RFC3339)))
+	}
+	if patch.EndDate != nil && !patch.EndDate.Equal(current.endDate) {
+		if current.deadlineLocked && !role.Supervises() {
+			return ErrDeadlineLocked
+		}
+		edited.endDate = patch.EndDate.UTC()
+		changes = append(changes, fmt.Sprintf("endDate: %s -> %s", current.endDate.Format(time.RFC3339), edited.endDate.Format(time.RFC3339)))
+	}
+	if patch.Description != nil && *patch.Description != current.description {
+		edited.description = *patch.Description
+		changes = append(changes, fmt.Sprintf("description: %q -> %q", current.description, edited.description))
+	}
+	if patch.Links != nil && *patch.Links != current.links {
+		edited.links = *patch.Links
+		changes = append(changes, fmt.Sprintf("links: %q -> %q", current.links, edited.links))
+	}
+	if patch.DeadlineLocked != nil && *patch.DeadlineLocked != current.deadlineLocked {
+		if !role.Supervises() {
+			return ErrDeadlineLocked
+		}
+		edited.deadlineLocked = *patch.DeadlineLocked
+		changes = append(changes, fmt.Sprintf("deadlineLocked: %t -> %t", current.deadlineLocked, edited.deadlineLocked))
+	}
+	if edited.endDate.Before(edited.startDate) {
+		return ErrInvalidGanttEdit
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE gantt_items SET gantt_name = $1, start_date = $2, end_date = $3, description = $4, links = $5, deadline_locked = $6,
+    version = version + 1
+WHERE item_id = $7`, edited.name, edited.startDate, edited.endDate, edited.description, edited.links, edited.deadlineLocked, itemID)
+	if err != nil {
+		log.Printf("failed to edit gantt item: %v", err)
+		return err
+	}
+	for _, change := range changes {
+		if err = audit(ctx, tx, userID, "gantt_item_edited", itemID, change); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
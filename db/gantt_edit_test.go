@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func expectGanttFields(mock sqlmock.Sqlmock, role string, locked bool) {
+	start := time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Design"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+	mock.ExpectQuery(`SELECT gantt_name, start_date, end_date, description, links, deadline_locked, version FROM gantt_items WHERE item_id = \$1`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"gantt_name", "start_date", "end_date", "description", "links", "deadline_locked", "version"}).
+			AddRow("Design", start, start.AddDate(0, 0, 5), "UML diagrams", "", locked, 3))
+}
+
+func TestClient_EditGanttItem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 12)
+	expectGanttFields(mock, "primary_supervisor", true)
+	mock.ExpectExec(`UPDATE gantt_items SET gantt_name = \$1, start_date = \$2, end_date = \$3, description = \$4, links = \$5, deadline_locked = \$6, version = version \+ 1 WHERE item_id = \$7`).
+		WithArgs("Design", start, end, "", "", true, "item-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "user-1", "gantt_item_edited", "item-1", "endDate: 2024-10-12T00:00:00Z -> 2024-10-19T00:00:00Z").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "user-1", "gantt_item_edited", "item-1", `description: "UML diagrams" -> ""`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	name, description := "Design", ""
+	err = d.EditGanttItem(context.Background(), "item-1", "user-1", GanttPatch{GanttName: &name, EndDate: &end, Description: &description}, 3)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_EditGanttItemLockedDeadline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectGanttFields(mock, "student", true)
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	end := time.Date(2024, 10, 19, 0, 0, 0, 0, time.UTC)
+	err = d.EditGanttItem(context.Background(), "item-1", "user-1", GanttPatch{EndDate: &end}, 0)
+	assert.ErrorIs(t, err, ErrDeadlineLocked)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_EditGanttItemEndsBeforeStart(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectGanttFields(mock, "student", false)
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	start := time.Date(2024, 10, 20, 0, 0, 0, 0, time.UTC)
+	err = d.EditGanttItem(context.Background(), "item-1", "user-1", GanttPatch{StartDate: &start}, 0)
+	assert.ErrorIs(t, err, ErrInvalidGanttEdit)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_EditGanttItemStaleVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectGanttFields(mock, "student", false)
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	links := "https://example.org"
+	err = d.EditGanttItem(context.Background(), "item-1", "user-1", GanttPatch{Links: &links}, 2)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_DeleteGanttItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		found   bool
+		role    any
+		current int
+		err     error
+	}{
+		{name: "deleted", found: true, role: "student", current: 2},
+		{name: "missing", err: ErrGanttItemNotFound},
+		{name: "not a member", found: true, role: nil, err: ErrNotProjectMember},
+		{name: "changed since", found: true, role: "student", current: 3, err: ErrVersionMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			items := sqlmock.NewRows([]string{"project_id", "gantt_name"})
+			if tt.found {
+				items.AddRow("project-1", "Design")
+			}
+			mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+				WithArgs("item-1").
+				WillReturnRows(items)
+			if tt.found {
+				mock.ExpectQuery(`SELECT m.role FROM projects p`).
+					WithArgs("project-1", "user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(tt.role))
+			}
+			if tt.role != nil {
+				versions := sqlmock.NewRows([]string{"version"})
+				if tt.current == 2 {
+					versions.AddRow(3)
+				}
+				mock.ExpectQuery(`UPDATE gantt_items SET version = version \+ 1 WHERE item_id = \$1 AND \(\$2 = 0 OR version = \$2\) RETURNING version`).
+					WithArgs("item-1", 2).
+					WillReturnRows(versions)
+				if tt.current == 2 {
+					mock.ExpectExec(`DELETE FROM gantt_items WHERE item_id = \$1`).
+						WithArgs("item-1").
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM gantt_items WHERE item_id = \$1\)`).
+						WithArgs("item-1").
+						WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				}
+			}
+			if tt.err != nil {
+				mock.ExpectRollback()
+			}
+
+			d := &Client{
+				conn: db,
+			}
+
+			err = d.DeleteGanttItem(context.Background(), "item-1", "user-1", 2)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_CreateGanttItemRequiresMembership(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(nil))
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.CreateGanttItem(context.Background(), Gantt{ProjectID: "project-1", GanttName: "Design"}, "user-1")
+	assert.ErrorIs(t, err, ErrNotProjectMember)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/ganttfile"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+)
+
+// GetProjectGantt returns the name and the gantt chart of a project the user is a member of
+func (db Client) GetProjectGantt(ctx context.Context, projectID string, userID string) (string, []model.GanttChartRow, error) {
+	if _, err := projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return "", nil, err
+	}
+	return db.GetAnyProjectGantt(ctx, projectID)
+}
+
+// GetAnyProjectGantt returns the name and the gantt chart of a project without checking membership, for coordinators
+func (db Client) GetAnyProjectGantt(ctx context.Context, projectID string) (string, []model.GanttChartRow, error) {
+	var name string
+	err := db.conn.QueryRowContext(ctx, "SELECT project_name FROM projects WHERE project_id = $1", projectID).Scan(&name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil, ErrProjectNotFound
+		}
+		log.Printf("cannot read project name: %v", err)
+		return "", nil, err
+	}
+	rows, err := db.GetGantt(ctx, projectID)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, rows, nil
+}
+
+// ImportGantt creates the tasks of a plan file as items of a project the user is a member of, together with the
+// dependencies between them. Either every task is created or none is. Returns the ids of the items in the order of
+// the tasks, tasks are expected to have passed ganttfile.Read without row errors.
+func (db Client) ImportGantt(ctx context.Context, projectID string, userID string, tasks []ganttfile.Task) ([]string, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to import gantt items: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = projectRole(ctx, tx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(tasks))
+	byRef := make(map[string]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = GenerateUUID()
+		byRef[task.Ref] = ids[i]
+		status := GanttOnTrack
+		if task.PercentComplete == 100 {
+			status = GanttComplete
+		}
+		query := `INSERT INTO gantt_items (item_id, project_id, gantt_name, start_date, end_date, description, links, status, percent_complete)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		_, err = tx.ExecContext(ctx, query, ids[i], projectID, task.Name, task.Start, task.End, task.Description, task.Links, status, task.PercentComplete)
+		if err != nil {
+			log.Printf("failed to import gantt item: %v", err)
+			return nil, err
+		}
+	}
+	for i, task := range tasks {
+		for _, link := range task.Predecessors {
+			predecessorID, ok := byRef[link.Ref]
+			if !ok {
+				return nil, ErrInvalidDependency
+			}
+			_, err = tx.ExecContext(ctx, "INSERT INTO gantt_dependencies (predecessor_id, successor_id, kind, lag_days) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+				predecessorID, ids[i], link.Type, link.LagDays)
+			if err != nil {
+				log.Printf("failed to import dependency: %v", err)
+				return nil, err
+			}
+		}
+	}
+
+	if err = audit(ctx, tx, userID, "gantt_imported", projectID, fmt.Sprintf("%d items", len(tasks))); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
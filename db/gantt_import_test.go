@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/ganttfile"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_ImportGantt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+	tasks := []ganttfile.Task{
+		{Ref: "1", Name: "Design", Start: start, End: start.AddDate(0, 0, 5), PercentComplete: 100},
+		{Ref: "2", Name: "Build", Start: start.AddDate(0, 0, 5), End: start.AddDate(0, 0, 15),
+			Predecessors: []ganttfile.Link{{Ref: "1", Type: schedule.StartToStart, LagDays: 2}}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectExec(`INSERT INTO gantt_items`).
+		WithArgs(sqlmock.AnyArg(), "project-1", "Design", tasks[0].Start, tasks[0].End, "", "", GanttComplete, 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO gantt_items`).
+		WithArgs(sqlmock.AnyArg(), "project-1", "Build", tasks[1].Start, tasks[1].End, "", "", GanttOnTrack, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO gantt_dependencies`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), schedule.StartToStart, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "student-1", "gantt_imported", "project-1", "2 items").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	ids, err := d.ImportGantt(context.Background(), "project-1", "student-1", tasks)
+	assert.Nil(t, err)
+	assert.Len(t, ids, 2)
+	assert.NotEqual(t, ids[0], ids[1])
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_ImportGanttNotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-2").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(nil))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.ImportGantt(context.Background(), "project-1", "student-2", []ganttfile.Task{{Ref: "1", Name: "Design"}})
+	assert.ErrorIs(t, err, ErrNotProjectMember)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"slices"
+)
+
+// ErrInvalidGanttStatus is returned for a status that gantt items cannot have
+var ErrInvalidGanttStatus = fmt.Errorf("status must be one of %v", ganttStatuses)
+
+var ganttStatuses = []GanttStatus{GanttOnTrack, GanttAtRisk, GanttBlocked, GanttComplete}
+
+var ganttPalette = map[GanttStatus]string{
+	GanttOnTrack:  "#2A9D39",
+	GanttAtRisk:   "#F4A261",
+	GanttBlocked:  "#D62828",
+	GanttComplete: "#2C59C7",
+}
+
+// ParseGanttStatus converts a string into a GanttStatus
+func ParseGanttStatus(value string) (GanttStatus, error) {
+	status := GanttStatus(value)
+	if !slices.Contains(ganttStatuses, status) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidGanttStatus, value)
+	}
+	return status, nil
+}
+
+// GanttStatuses returns every status in the order they are listed in
+func GanttStatuses() []GanttStatus {
+	return slices.Clone(ganttStatuses)
+}
+
+// Colour is the colour the item is drawn in
+func (s GanttStatus) Colour() string {
+	return ganttPalette[s]
+}
+
+// Label is the human readable name of the status
+func (s GanttStatus) Label() string {
+	switch s {
+	case GanttAtRisk:
+		return "At risk"
+	case GanttBlocked:
+		return "Blocked"
+	case GanttComplete:
+		return "Complete"
+	default:
+		return "On track"
+	}
+}
+
+// setGanttState fills the status fields of an item and derives its colour
+func setGanttState(item *model.Gantt, status GanttStatus, percentComplete int) {
+	item.Status = string(status)
+	item.PercentComplete = percentComplete
+	item.Colour = status.Colour()
+}
+
+// SetGanttStatus lets a project member change the status and progress of an item, complete items are done
+func (db Client) SetGanttStatus(ctx context.Context, itemID string, userID string, status GanttStatus, percentComplete int, version int) (int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to set gantt status: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, _, _, err = lockGanttItem(ctx, tx, itemID, userID); err != nil {
+		return 0, err
+	}
+	version, err = bumpGanttVersion(ctx, tx, itemID, version)
+	if err != nil {
+		return 0, err
+	}
+	if status == GanttComplete {
+		percentComplete = 100
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE gantt_items SET status = $1, percent_complete = $2 WHERE item_id = $3", status, percentComplete, itemID)
+	if err != nil {
+		log.Printf("failed to set gantt status: %v", err)
+		return 0, err
+	}
+	return version, tx.Commit()
+}
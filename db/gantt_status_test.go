@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGanttStatus_Colour(t *testing.T) {
+	assert.Equal(t, "#2A9D39", GanttOnTrack.Colour())
+	assert.Equal(t, "#D62828", GanttBlocked.Colour())
+	assert.Equal(t, "#2C59C7", GanttComplete.Colour())
+
+	_, err := ParseGanttStatus("late")
+	assert.ErrorIs(t, err, ErrInvalidGanttStatus)
+}
+
+func TestClient_GetGanttItemDerivesColour(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT item_id, project_id, gantt_name, start_date, end_date, description, links, status, percent_complete, deadline_locked, version from gantt_items where item_id = \$1`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "project_id", "gantt_name", "start_date", "end_date", "description", "links", "status", "percent_complete", "deadline_locked", "version"}).
+			AddRow("item-1", "project-1", "Design", start, start.AddDate(0, 0, 5), "", "", "at_risk", 40, false, 4))
+
+	d := &Client{
+		conn: db,
+	}
+
+	items, err := d.GetGanttItem(context.Background(), "item-1")
+	assert.Nil(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "at_risk", items[0].Status)
+		assert.Equal(t, 40, items[0].PercentComplete)
+		assert.Equal(t, "#F4A261", items[0].Colour)
+		assert.Equal(t, 4, items[0].Version)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_CompleteGanttItemStaleVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Design"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`UPDATE gantt_items SET version = version \+ 1 WHERE item_id = \$1 AND \(\$2 = 0 OR version = \$2\) RETURNING version`).
+		WithArgs("item-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM gantt_items WHERE item_id = \$1\)`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.CompleteGanttItem(context.Background(), Gantt{Id: "item-1", Version: 2}, "user-1")
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_CompleteGanttItemDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Design"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`UPDATE gantt_items SET version = version \+ 1`).
+		WithArgs("item-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM gantt_items WHERE item_id = \$1\)`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.CompleteGanttItem(context.Background(), Gantt{Id: "item-1", Version: 2}, "user-1")
+	assert.ErrorIs(t, err, ErrGanttItemNotFound)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
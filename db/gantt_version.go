@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// ErrVersionMismatch is returned when the gantt item changed since the client read the version it sent
+var ErrVersionMismatch = errors.New("gantt item was changed by someone else, reload it and try again")
+
+// bumpGanttVersion moves the item to its next version and returns it. It fails when the client read another version
+// than the current one, a version of 0 skips the check for clients that do not send one.
+func bumpGanttVersion(ctx context.Context, tx *sql.Tx, itemID string, version int) (int, error) {
+	var next int
+	err := tx.QueryRowContext(ctx, "UPDATE gantt_items SET version = version + 1 WHERE item_id = $1 AND ($2 = 0 OR version = $2) RETURNING version",
+		itemID, version).Scan(&next)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ganttVersionError(ctx, tx, itemID)
+		}
+		log.Printf("cannot update gantt item version: %v", err)
+		return 0, err
+	}
+	return next, nil
+}
+
+// ganttVersionError tells a missing item from one that moved on to another version
+func ganttVersionError(ctx context.Context, tx *sql.Tx, itemID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM gantt_items WHERE item_id = $1)", itemID).Scan(&exists)
+	if err != nil {
+		log.Printf("cannot read gantt item: %v", err)
+		return err
+	}
+	if !exists {
+		return ErrGanttItemNotFound
+	}
+	return ErrVersionMismatch
+}
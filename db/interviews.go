@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log"
+	"time"
+)
+
+// ErrSlotNotFound is returned when the interview slot does not exist or is not visible to the user
+var ErrSlotNotFound = errors.New("interview slot not found")
+
+// ErrNotSlotOwner is returned when a supervisor changes an interview slot of somebody else
+var ErrNotSlotOwner = errors.New("user is not the owner of the interview slot")
+
+// ErrSlotOverlap is returned when a supervisor publishes slots overlapping one another
+var ErrSlotOverlap = errors.New("interview slot overlaps another slot")
+
+// ErrSlotTaken is returned when the interview slot is already booked
+var ErrSlotTaken = errors.New("interview slot is already booked")
+
+// ErrSlotPassed is returned when booking or cancelling an interview that has already started
+var ErrSlotPassed = errors.New("interview slot has already started")
+
+// ErrInterviewClash is returned when the student has another interview at the same time
+var ErrInterviewClash = errors.New("student has another interview at that time")
+
+// ErrNotShortlisted is returned when a slot is booked for an application which is not shortlisted
+var ErrNotShortlisted = errors.New("only shortlisted applications can book an interview")
+
+const interviewColumns = `s.id, s.supervisor_id, su.name, s.starts_at, s.ends_at, s.location, coalesce(s.application_id::text, ''),
+    coalesce(s.student_id, ''), coalesce(st.name, ''), coalesce(a.heading, ''), s.booked_at`
+
+const interviewFrom = `interview_slots s INNER JOIN users su ON su.id = s.supervisor_id
+    LEFT JOIN users st ON st.id = s.student_id LEFT JOIN applications a ON a.id = s.application_id`
+
+func scanInterviewSlot(row scanner, extra ...any) (*model.InterviewSlot, error) {
+	var (
+		slot     model.InterviewSlot
+		bookedAt sql.NullTime
+	)
+	err := row.Scan(append([]any{&slot.ID, &slot.SupervisorID, &slot.SupervisorName, &slot.StartsAt, &slot.EndsAt, &slot.Location,
+		&slot.ApplicationID, &slot.StudentID, &slot.StudentName, &slot.Heading, &bookedAt}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	slot.StartsAt, slot.EndsAt = slot.StartsAt.UTC(), slot.EndsAt.UTC()
+	if bookedAt.Valid {
+		at := bookedAt.Time.UTC()
+		slot.BookedAt = &at
+	}
+	return &slot, nil
+}
+
+// interviewError translates the double booking constraints of the interview slots table
+func interviewError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.ConstraintName {
+		case "interview_slots_supervisor_overlap":
+			return ErrSlotOverlap
+		case "interview_slots_student_overlap":
+			return ErrInterviewClash
+		}
+	}
+	return err
+}
+
+// CreateInterviewSlots publishes all slots or none of them
+func (db Client) CreateInterviewSlots(ctx context.Context, supervisorID string, slots []InterviewSlot) ([]string, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to create interview slots: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		id := GenerateUUID()
+		_, err = tx.ExecContext(ctx, "INSERT INTO interview_slots (id, supervisor_id, starts_at, ends_at, location) VALUES ($1, $2, $3, $4, $5)",
+			id, supervisorID, slot.StartsAt, slot.EndsAt, slot.Location)
+		if err != nil {
+			log.Printf("failed to create interview slot: %v", err)
+			return nil, interviewError(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+// lockInterviewSlot locks the slot and returns it together with the booked application, if any
+func lockInterviewSlot(ctx context.Context, tx *sql.Tx, slotID string) (*model.InterviewSlot, error) {
+	row := tx.QueryRowContext(ctx, "SELECT "+interviewColumns+" FROM "+interviewFrom+" WHERE s.id = $1 FOR UPDATE OF s", slotID)
+	slot, err := scanInterviewSlot(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSlotNotFound
+		}
+		log.Printf("cannot read interview slot: %v", err)
+		return nil, err
+	}
+	return slot, nil
+}
+
+// DeleteInterviewSlot removes a slot of the supervisor, a student who booked it is told that the interview is cancelled
+func (db Client) DeleteInterviewSlot(ctx context.Context, slotID string, supervisorID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to delete interview slot: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	slot, err := lockInterviewSlot(ctx, tx, slotID)
+	if err != nil {
+		return err
+	}
+	if slot.SupervisorID != supervisorID {
+		return ErrNotSlotOwner
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM interview_slots WHERE id = $1", slotID); err != nil {
+		log.Printf("failed to delete interview slot: %v", err)
+		return err
+	}
+	if slot.StudentID != "" && slot.StartsAt.After(time.Now()) {
+		err = notify(ctx, tx, slot.StudentID, "interview_cancelled",
+			fmt.Sprintf("Your interview for \"%s\" on %s was cancelled by the supervisor", slot.Heading, slot.StartsAt.Format(time.RFC3339)))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetInterviewSlots lists the slots of a supervisor. Students see the free upcoming slots of the supervisors
+// who shortlisted them and the slots they booked.
+func (db Client) GetInterviewSlots(ctx context.Context, userID string, opts ListOptions) (*model.Page[model.InterviewSlot], error) {
+	q := listQuery{
+		columns:  interviewColumns,
+		from:     interviewFrom,
+		idColumn: "s.id",
+	}
+	user := q.arg(userID)
+	q.where(fmt.Sprintf(`(s.supervisor_id = %[1]s OR s.student_id = %[1]s OR (s.application_id IS NULL AND s.starts_at > now()
+    AND EXISTS (SELECT 1 FROM applications sa WHERE sa.supervisor_id = s.supervisor_id AND sa.student_id = %[1]s AND sa.status = %[2]s)))`,
+		user, q.arg(lifecycle.Shortlisted)))
+	if opts.SupervisorID != "" {
+		q.where("s.supervisor_id = " + q.arg(opts.SupervisorID))
+	}
+	q.dateRange("s.starts_at", opts)
+
+	query, args, err := q.build(opts, interviewSorts, "startsAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get interview slots: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.InterviewSlot](opts, "startsAt")
+	var key string
+	for rows.Next() {
+		slot, err := scanInterviewSlot(rows, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting interview slots: %v", err)
+			return nil, err
+		}
+		result.add(*slot, slot.ID, key)
+	}
+	return result.page(), nil
+}
+
+// GetInterviewSlot returns a booked slot to its supervisor or student
+func (db Client) GetInterviewSlot(ctx context.Context, slotID string, userID string) (*model.InterviewSlot, error) {
+	row := db.conn.QueryRowContext(ctx, "SELECT "+interviewColumns+" FROM "+interviewFrom+" WHERE s.id = $1", slotID)
+	slot, err := scanInterviewSlot(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSlotNotFound
+		}
+		log.Printf("cannot read interview slot: %v", err)
+		return nil, err
+	}
+	if userID != slot.SupervisorID && userID != slot.StudentID {
+		return nil, ErrSlotNotFound
+	}
+	return slot, nil
+}
+
+// BookInterviewSlot books the slot for a shortlisted application. When the application already holds a slot
+// the interview is rescheduled, the old slot is released in the same transaction.
+func (db Client) BookInterviewSlot(ctx context.Context, slotID string, appID string, studentID string) (*model.InterviewSlot, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to book interview slot: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	application, err := lockApplication(ctx, tx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if application.StudentID != studentID {
+		return nil, ErrNotApplicationParticipant
+	}
+	if application.Status != lifecycle.Shortlisted {
+		return nil, ErrNotShortlisted
+	}
+
+	slot, err := lockInterviewSlot(ctx, tx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.SupervisorID != application.SupervisorID {
+		return nil, ErrSlotNotFound
+	}
+	if slot.ApplicationID == appID {
+		return slot, nil
+	}
+	if slot.ApplicationID != "" {
+		return nil, ErrSlotTaken
+	}
+	if !slot.StartsAt.After(time.Now()) {
+		return nil, ErrSlotPassed
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE interview_slots SET application_id = NULL, student_id = NULL, booked_at = NULL WHERE application_id = $1", appID)
+	if err != nil {
+		log.Printf("failed to release interview slot: %v", err)
+		return nil, err
+	}
+	rescheduled, _ := result.RowsAffected()
+
+	var bookedAt time.Time
+	err = tx.QueryRowContext(ctx, "UPDATE interview_slots SET application_id = $1, student_id = $2, booked_at = now() WHERE id = $3 RETURNING booked_at",
+		appID, studentID, slotID).Scan(&bookedAt)
+	if err != nil {
+		log.Printf("failed to book interview slot: %v", err)
+		return nil, interviewError(err)
+	}
+
+	kind, text := "interview_booked", "booked an interview"
+	if rescheduled > 0 {
+		kind, text = "interview_rescheduled", "moved the interview"
+	}
+	err = notify(ctx, tx, application.SupervisorID, kind,
+		fmt.Sprintf("The student %s for \"%s\" to %s", text, application.Heading, slot.StartsAt.Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+
+	bookedAt = bookedAt.UTC()
+	slot.ApplicationID, slot.StudentID, slot.Heading, slot.BookedAt = appID, studentID, application.Heading, &bookedAt
+	return slot, tx.Commit()
+}
+
+// CancelInterviewBooking frees a booked slot, either the student or the supervisor may cancel before the interview starts
+func (db Client) CancelInterviewBooking(ctx context.Context, slotID string, userID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to cancel interview: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	slot, err := lockInterviewSlot(ctx, tx, slotID)
+	if err != nil {
+		return err
+	}
+	if slot.StudentID == "" || (userID != slot.StudentID && userID != slot.SupervisorID) {
+		return ErrSlotNotFound
+	}
+	if !slot.StartsAt.After(time.Now()) {
+		return ErrSlotPassed
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE interview_slots SET application_id = NULL, student_id = NULL, booked_at = NULL WHERE id = $1", slotID)
+	if err != nil {
+		log.Printf("failed to cancel interview: %v", err)
+		return err
+	}
+
+	recipientID, by := slot.SupervisorID, "student"
+	if userID == slot.SupervisorID {
+		recipientID, by = slot.StudentID, "supervisor"
+	}
+	err = notify(ctx, tx, recipientID, "interview_cancelled",
+		fmt.Sprintf("The interview for \"%s\" on %s was cancelled by the %s", slot.Heading, slot.StartsAt.Format(time.RFC3339), by))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// releaseInterview frees the upcoming interview of an application that will not go ahead
+func releaseInterview(ctx context.Context, tx *sql.Tx, appID string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE interview_slots SET application_id = NULL, student_id = NULL, booked_at = NULL WHERE application_id = $1 AND starts_at > now()", appID)
+	if err != nil {
+		log.Printf("failed to release interview slot: %v", err)
+		return err
+	}
+	return nil
+}
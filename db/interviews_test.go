@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const lockSlotQuery = `SELECT s.id, .* FROM interview_slots s .* WHERE s.id = \$1 FOR UPDATE OF s`
+
+var slotColumns = []string{"id", "supervisor_id", "supervisor_name", "starts_at", "ends_at", "location", "application_id", "student_id", "student_name", "heading", "booked_at"}
+
+func expectLockApplication(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+		WithArgs("app-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+			AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", status, "", nil))
+}
+
+func TestClient_BookInterviewSlotRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name         string
+		status       string
+		supervisorID string
+		bookedBy     string
+		startsAt     time.Time
+		err          error
+	}{
+		{"not shortlisted", "under_review", "", "", future, ErrNotShortlisted},
+		{"slot of another supervisor", "shortlisted", "supervisor-2", "", future, ErrSlotNotFound},
+		{"slot taken", "shortlisted", "supervisor-1", "app-2", future, ErrSlotTaken},
+		{"slot passed", "shortlisted", "supervisor-1", "", past, ErrSlotPassed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			expectLockApplication(mock, test.status)
+			if test.supervisorID != "" {
+				studentID := ""
+				if test.bookedBy != "" {
+					studentID = "student-2"
+				}
+				mock.ExpectQuery(lockSlotQuery).
+					WithArgs("slot-1").
+					WillReturnRows(sqlmock.NewRows(slotColumns).
+						AddRow("slot-1", test.supervisorID, "Supervisor", test.startsAt, test.startsAt.Add(30*time.Minute), "Room 1", test.bookedBy, studentID, "", "", nil))
+			}
+			mock.ExpectRollback()
+
+			slot, err := d.BookInterviewSlot(context.Background(), "slot-1", "app-1", "student-1")
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, slot)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_RescheduleInterview(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	startsAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	bookedAt := time.Now().UTC().Truncate(time.Second)
+	mock.ExpectBegin()
+	expectLockApplication(mock, "shortlisted")
+	mock.ExpectQuery(lockSlotQuery).
+		WithArgs("slot-2").
+		WillReturnRows(sqlmock.NewRows(slotColumns).
+			AddRow("slot-2", "supervisor-1", "Supervisor", startsAt, startsAt.Add(30*time.Minute), "Room 1", "", "", "", "", nil))
+	mock.ExpectExec(`UPDATE interview_slots SET application_id = NULL, student_id = NULL, booked_at = NULL WHERE application_id = \$1`).
+		WithArgs("app-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE interview_slots SET application_id = \$1, student_id = \$2, booked_at = now\(\) WHERE id = \$3 RETURNING booked_at`).
+		WithArgs("app-1", "student-1", "slot-2").
+		WillReturnRows(sqlmock.NewRows([]string{"booked_at"}).AddRow(bookedAt))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "supervisor-1", "interview_rescheduled", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	slot, err := d.BookInterviewSlot(context.Background(), "slot-2", "app-1", "student-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "app-1", slot.ApplicationID)
+	assert.Equal(t, "Heading", slot.Heading)
+	assert.Equal(t, bookedAt, *slot.BookedAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestInterviewError(t *testing.T) {
+	assert.ErrorIs(t, interviewError(&pgconn.PgError{Code: "23P01", ConstraintName: "interview_slots_supervisor_overlap"}), ErrSlotOverlap)
+	assert.ErrorIs(t, interviewError(&pgconn.PgError{Code: "23P01", ConstraintName: "interview_slots_student_overlap"}), ErrInterviewClash)
+	assert.NotErrorIs(t, interviewError(&pgconn.PgError{Code: "23503"}), ErrSlotOverlap)
+}
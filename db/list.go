@@ -0,0 +1,242 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ErrInvalidListOptions is returned when a list request cannot be served with the given options,
+// for example an unknown sort field or a cursor produced for a different sort order
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ListOptions carries the pagination, filtering and sorting parameters shared by all list queries.
+// Filters that do not apply to a particular list are ignored.
+type ListOptions struct {
+	Limit        int
+	After        string
+	Sort         string // field name, prefixed with "-" for descending order
+	Accepted     *bool
+	Declined     *bool
+	Status       string
+	SupervisorID string
+	From         *time.Time
+	To           *time.Time
+	Search       string   // free text search, only used by proposals
+	Tags         []string // all tags must match, only used by proposals
+	Role         string   // role of the caller, only used by projects
+}
+
+type sortField struct {
+	column string // SQL expression used in ORDER BY, never NULL so every row has a key for the cursor
+	cast   string // postgres type the cursor value is converted back to
+}
+
+var (
+	applicationSorts = map[string]sortField{
+		"createdAt":   {column: "a.created_at", cast: "timestamptz"},
+		"heading":     {column: "coalesce(a.heading, '')", cast: "text"},
+		"studentName": {column: "coalesce(u.name, '')", cast: "text"},
+	}
+	projectSorts = map[string]sortField{
+		"createdAt": {column: "p.created_at", cast: "timestamptz"},
+		"name":      {column: "coalesce(p.project_name, '')", cast: "text"},
+	}
+	userSorts = map[string]sortField{
+		"name": {column: "coalesce(u.name, '')", cast: "text"},
+	}
+	notificationSorts = map[string]sortField{
+		"createdAt": {column: "created_at", cast: "timestamptz"},
+	}
+	proposalSorts = map[string]sortField{
+		"createdAt": {column: "p.created_at", cast: "timestamptz"},
+		"title":     {column: "coalesce(p.title, '')", cast: "text"},
+	}
+	messageSorts = map[string]sortField{
+		"createdAt": {column: "m.created_at", cast: "timestamptz"},
+	}
+	interviewSorts = map[string]sortField{
+		"startsAt": {column: "s.starts_at", cast: "timestamptz"},
+	}
+	auditSorts = map[string]sortField{
+		"createdAt": {column: "created_at", cast: "timestamptz"},
+	}
+	feedbackSorts = map[string]sortField{
+		"createdAt": {column: "c.created_at", cast: "timestamptz"},
+	}
+	questionSorts = map[string]sortField{
+		"question": {column: "coalesce(questionshort, '')", cast: "text"},
+	}
+)
+
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+// listQuery assembles a keyset paginated query. Arguments are numbered in the order they are added.
+type listQuery struct {
+	columns    string
+	from       string
+	idColumn   string
+	conditions []string
+	args       []any
+}
+
+func (q *listQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// applicationStatus applies the status filters, accepted and declined are shorthands for the matching status
+func (q *listQuery) applicationStatus(opts ListOptions) {
+	if opts.Status != "" {
+		q.where("a.status = " + q.arg(opts.Status))
+	}
+	shorthands := []struct {
+		status lifecycle.Status
+		filter *bool
+	}{
+		{lifecycle.Accepted, opts.Accepted},
+		{lifecycle.Declined, opts.Declined},
+	}
+	for _, shorthand := range shorthands {
+		if shorthand.filter == nil {
+			continue
+		}
+		if *shorthand.filter {
+			q.where("a.status = " + q.arg(shorthand.status))
+		} else {
+			q.where("a.status <> " + q.arg(shorthand.status))
+		}
+	}
+}
+
+func (q *listQuery) dateRange(column string, opts ListOptions) {
+	if opts.From != nil {
+		q.where(column + " >= " + q.arg(*opts.From))
+	}
+	if opts.To != nil {
+		q.where(column + " < " + q.arg(*opts.To))
+	}
+}
+
+// build returns the final SQL and its arguments. The sort key of every row is selected as the last column
+// so that the caller can produce the cursor for the next page.
+func (q *listQuery) build(opts ListOptions, sorts map[string]sortField, defaultSort string) (string, []any, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+	name, descending := strings.CutPrefix(sort, "-")
+	field, ok := sorts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unsupported sort field %q", ErrInvalidListOptions, name)
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if opts.After != "" {
+		c, err := decodeCursor(opts.After)
+		if err != nil {
+			return "", nil, err
+		}
+		if c.Sort != sort {
+			return "", nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidListOptions, c.Sort)
+		}
+		q.where(fmt.Sprintf("(%s, %s) %s (CAST(%s AS %s), %s)", field.column, q.idColumn, comparison, q.arg(c.Key), field.cast, q.arg(c.ID)))
+	}
+
+	query := fmt.Sprintf("SELECT %s, (%s)::text FROM %s", q.columns, field.column, q.from)
+	if len(q.conditions) > 0 {
+		query += " WHERE " + strings.Join(q.conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT %d", field.column, direction, q.idColumn, direction, listLimit(opts)+1)
+
+	return query, q.args, nil
+}
+
+func listLimit(opts ListOptions) int {
+	if opts.Limit <= 0 {
+		return DefaultListLimit
+	}
+	if opts.Limit > MaxListLimit {
+		return MaxListLimit
+	}
+	return opts.Limit
+}
+
+func encodeCursor(sort, key, id string) string {
+	data, _ := json.Marshal(cursor{Sort: sort, Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+	}
+	return &c, nil
+}
+
+// pageBuilder collects scanned rows together with their sort keys and trims the extra row fetched to
+// detect whether another page exists.
+type pageBuilder[T any] struct {
+	sort  string
+	limit int
+	items []T
+	ids   []string
+	keys  []string
+}
+
+func newPageBuilder[T any](opts ListOptions, defaultSort string) *pageBuilder[T] {
+	sort := opts.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+	return &pageBuilder[T]{
+		sort:  sort,
+		limit: listLimit(opts),
+		items: []T{},
+	}
+}
+
+func (p *pageBuilder[T]) add(item T, id, key string) {
+	p.items = append(p.items, item)
+	p.ids = append(p.ids, id)
+	p.keys = append(p.keys, key)
+}
+
+func (p *pageBuilder[T]) page() *model.Page[T] {
+	result := &model.Page[T]{
+		Items: p.items,
+	}
+	if len(p.items) > p.limit {
+		result.Items = p.items[:p.limit]
+		next := encodeCursor(p.sort, p.keys[p.limit-1], p.ids[p.limit-1])
+		result.NextCursor = &next
+	}
+	return result
+}
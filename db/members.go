@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ErrProjectNotFound is returned when the project does not exist
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrNotProjectMember is returned when the user takes no part in the project
+var ErrNotProjectMember = errors.New("user is not a member of the project")
+
+// ErrNotPrimarySupervisor is returned when somebody other than the primary supervisor manages the members
+var ErrNotPrimarySupervisor = errors.New("only the primary supervisor can change the members of the project")
+
+// ErrInvalidRole is returned when a member cannot be given or removed from the role
+var ErrInvalidRole = errors.New("invalid project role")
+
+// ErrAlreadyMember is returned when the user already takes part in the project or the role is taken
+var ErrAlreadyMember = errors.New("user is already a member of the project or the role is taken")
+
+// ErrUserNotFound is returned when the user to add does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// assignableRoles are the roles the primary supervisor hands out, students join through their application
+var assignableRoles = []ProjectRole{RoleCoSupervisor, RoleSecondReader, RoleExternalMentor}
+
+var projectRoles = append([]ProjectRole{RolePrimarySupervisor, RoleStudent}, assignableRoles...)
+
+// Staff reports whether the role belongs to the supervising side of the project
+func (r ProjectRole) Staff() bool {
+	return r != RoleStudent
+}
+
+// Supervises reports whether the role agrees the plan of the project with the students
+func (r ProjectRole) Supervises() bool {
+	return r == RolePrimarySupervisor || r == RoleCoSupervisor
+}
+
+// ParseProjectRole converts a string into a ProjectRole
+func ParseProjectRole(value string) (ProjectRole, error) {
+	role := ProjectRole(value)
+	if !slices.Contains(projectRoles, role) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRole, value)
+	}
+	return role, nil
+}
+
+// projectRole returns the role of the user in the project
+func projectRole(ctx context.Context, conn queryer, projectID string, userID string) (ProjectRole, error) {
+	row := conn.QueryRowContext(ctx, `SELECT m.role FROM projects p
+    LEFT JOIN project_members m ON m.project_id = p.project_id AND m.user_id = $2
+WHERE p.project_id = $1`, projectID, userID)
+	var role sql.NullString
+	err := row.Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrProjectNotFound
+		}
+		log.Printf("cannot read project membership: %v", err)
+		return "", err
+	}
+	if !role.Valid {
+		return "", ErrNotProjectMember
+	}
+	return ProjectRole(role.String), nil
+}
+
+// insertMember adds the user to the project, a second member in the same role is refused for primary supervisors
+// and second readers
+func insertMember(ctx context.Context, conn execer, projectID string, userID string, role ProjectRole) error {
+	_, err := conn.ExecContext(ctx, "INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)", projectID, userID, role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { //unique_violation
+			return ErrAlreadyMember
+		}
+		log.Printf("failed to add project member: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetProjectRole returns the role of the user in the project
+func (db Client) GetProjectRole(ctx context.Context, projectID string, userID string) (ProjectRole, error) {
+	return projectRole(ctx, db.conn, projectID, userID)
+}
+
+// GetProjectMembers lists the members of a project to any of its members
+func (db Client) GetProjectMembers(ctx context.Context, projectID string, userID string) ([]model.ProjectMember, error) {
+	if _, err := projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return nil, err
+	}
+	members, err := db.projectMembers(ctx, []string{projectID})
+	if err != nil {
+		return nil, err
+	}
+	return members[projectID], nil
+}
+
+// projectMembers returns the members of each project, primary supervisor first
+func (db Client) projectMembers(ctx context.Context, projectIDs []string) (map[string][]model.ProjectMember, error) {
+	query := `SELECT m.project_id, m.user_id, u.name, m.role FROM project_members m INNER JOIN users u ON u.id = m.user_id
+WHERE m.project_id = ANY($1)
+ORDER BY m.project_id, array_position(ARRAY['primary_supervisor', 'co_supervisor', 'second_reader', 'external_mentor', 'student'], m.role), u.name`
+	rows, err := db.conn.QueryContext(ctx, query, projectIDs)
+	if err != nil {
+		log.Printf("cannot execute query to get project members: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string][]model.ProjectMember{}
+	for rows.Next() {
+		var (
+			projectID string
+			member    model.ProjectMember
+		)
+		err = rows.Scan(&projectID, &member.UserID, &member.Name, &member.Role)
+		if err != nil {
+			log.Printf("cannot read data while getting project members: %v", err)
+			return nil, err
+		}
+		result[projectID] = append(result[projectID], member)
+	}
+	return result, nil
+}
+
+// AddProjectMember lets the primary supervisor add a co-supervisor, second reader or external mentor,
+// the new member is notified
+func (db Client) AddProjectMember(ctx context.Context, projectID string, actorID string, userID string, role ProjectRole) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to add project member: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	actorRole, err := projectRole(ctx, tx, projectID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorRole != RolePrimarySupervisor {
+		return ErrNotPrimarySupervisor
+	}
+	if !slices.Contains(assignableRoles, role) {
+		return fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+	var isSupervisor bool
+	if err = tx.QueryRowContext(ctx, "SELECT is_supervisor FROM users WHERE id = $1", userID).Scan(&isSupervisor); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		log.Printf("cannot read user: %v", err)
+		return err
+	}
+	if !isSupervisor && role != RoleExternalMentor { //mentors from industry have a plain account
+		return ErrNotSupervisor
+	}
+	if role == RoleSecondReader {
+		if err = refuseConflicts(ctx, tx, projectID, userID); err != nil {
+			return err
+		}
+	}
+	if err = insertMember(ctx, tx, projectID, userID, role); err != nil {
+		return err
+	}
+	if err = notify(ctx, tx, userID, "project_member_added", "You were added to a project as "+strings.ToLower(role.Label())); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveProjectMember lets the primary supervisor remove anybody but the primary supervisor and the students
+func (db Client) RemoveProjectMember(ctx context.Context, projectID string, actorID string, userID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to remove project member: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	actorRole, err := projectRole(ctx, tx, projectID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorRole != RolePrimarySupervisor {
+		return ErrNotPrimarySupervisor
+	}
+	role, err := projectRole(ctx, tx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(assignableRoles, role) {
+		return fmt.Errorf("%w: the %s cannot be removed", ErrInvalidRole, strings.ToLower(role.Label()))
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM project_members WHERE project_id = $1 AND user_id = $2", projectID, userID); err != nil {
+		log.Printf("failed to remove project member: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// notifyProjectMembers fans a notification out to every member of the project except the author
+func notifyProjectMembers(ctx context.Context, tx *sql.Tx, projectID string, authorID string, kind string, message string) error {
+	rows, err := tx.QueryContext(ctx, "SELECT user_id FROM project_members WHERE project_id = $1 AND user_id <> $2", projectID, authorID)
+	if err != nil {
+		log.Printf("cannot execute query to get project members: %v", err)
+		return err
+	}
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			rows.Close()
+			log.Printf("cannot read data while getting project members: %v", err)
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		if err = notify(ctx, tx, userID, kind, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Label is the human readable name of the role
+func (r ProjectRole) Label() string {
+	switch r {
+	case RolePrimarySupervisor:
+		return "Supervisor"
+	case RoleCoSupervisor:
+		return "Co-supervisor"
+	case RoleSecondReader:
+		return "Second reader"
+	case RoleExternalMentor:
+		return "Mentor"
+	default:
+		return "Student"
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_GetProjects(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM projects p INNER JOIN project_members m ON m.project_id = p.project_id AND m.user_id = \$1 WHERE m.role = \$2 ORDER BY p.created_at ASC, p.project_id ASC LIMIT 21`).
+		WithArgs("reader-1", "second_reader").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "project_name", "student_id", "supervisor_id", "proposal_id", "role", "key"}).
+			AddRow("project-1", "Ada", "student-1", "supervisor-1", "", "second_reader", createdAt.Format(time.RFC3339)))
+	mock.ExpectQuery(`SELECT m.project_id, m.user_id, u.name, m.role FROM project_members m INNER JOIN users u ON u.id = m.user_id WHERE m.project_id = ANY\(\$1\)`).
+		WithArgs([]string{"project-1"}).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "user_id", "name", "role"}).
+			AddRow("project-1", "supervisor-1", "Grace", "primary_supervisor").
+			AddRow("project-1", "reader-1", "Edsger", "second_reader").
+			AddRow("project-1", "student-1", "Ada", "student"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	page, err := d.GetProjects(context.Background(), "reader-1", ListOptions{Role: string(RoleSecondReader)})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ProjectData{{
+		ID:           "project-1",
+		Name:         "Ada",
+		StudentID:    "student-1",
+		SupervisorID: "supervisor-1",
+		Role:         "second_reader",
+		Members: []model.ProjectMember{
+			{UserID: "supervisor-1", Name: "Grace", Role: "primary_supervisor"},
+			{UserID: "reader-1", Name: "Edsger", Role: "second_reader"},
+			{UserID: "student-1", Name: "Ada", Role: "student"},
+		},
+	}}, page.Items)
+	assert.Nil(t, page.NextCursor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_AddProjectMemberRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	tests := []struct {
+		name         string
+		actorRole    any
+		role         ProjectRole
+		isSupervisor bool
+		err          error
+	}{
+		{"outsider", nil, RoleCoSupervisor, true, ErrNotProjectMember},
+		{"co-supervisor cannot add members", "co_supervisor", RoleSecondReader, true, ErrNotPrimarySupervisor},
+		{"students join through applications", "primary_supervisor", RoleStudent, false, ErrInvalidRole},
+		{"co-supervisor must be staff", "primary_supervisor", RoleCoSupervisor, false, ErrNotSupervisor},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT m.role FROM projects p LEFT JOIN project_members m ON m.project_id = p.project_id AND m.user_id = \$2 WHERE p.project_id = \$1`).
+				WithArgs("project-1", "actor-1").
+				WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(test.actorRole))
+			if test.err == ErrNotSupervisor {
+				mock.ExpectQuery(`SELECT is_supervisor FROM users WHERE id = \$1`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"is_supervisor"}).AddRow(test.isSupervisor))
+			}
+			mock.ExpectRollback()
+
+			err := d.AddProjectMember(context.Background(), "project-1", "actor-1", "user-1", test.role)
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_UpdateFeedbackNotifiesMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Literature review"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "mentor-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("external_mentor"))
+	mock.ExpectQuery(`UPDATE gantt_items SET version = version \+ 1 WHERE item_id = \$1 AND \(\$2 = 0 OR version = \$2\) RETURNING version`).
+		WithArgs("item-1", 6).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO feedback_comments \(id, item_id, parent_id, author_id, role, body\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING created_at`).
+		WithArgs(sqlmock.AnyArg(), "item-1", nil, "mentor-1", "external_mentor", "looks good").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC)))
+	mock.ExpectQuery(`SELECT user_id FROM project_members WHERE project_id = \$1 AND user_id <> \$2`).
+		WithArgs("project-1", "mentor-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("supervisor-1").AddRow("student-1"))
+	for _, userID := range []string{"supervisor-1", "student-1"} {
+		mock.ExpectExec(`INSERT INTO notifications`).
+			WithArgs(sqlmock.AnyArg(), userID, "gantt_feedback", `Mentor left feedback on "Literature review"`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	version, err := d.UpdateFeedback(context.Background(), Gantt{Id: "item-1", NewFeedBack: "looks good", Version: 6}, "mentor-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, version)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
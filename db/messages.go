@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+)
+
+// ErrThreadClosed is returned when a message is sent on an application that already has an outcome
+var ErrThreadClosed = errors.New("the application is closed, messages can no longer be sent")
+
+func (db Client) SendApplicationMessage(ctx context.Context, appID string, authorID string, body string) (*model.Message, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to send message: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	application, err := lockApplication(ctx, tx, appID)
+	if err != nil {
+		return nil, err
+	}
+	recipientID := application.SupervisorID
+	switch authorID {
+	case application.StudentID:
+	case application.SupervisorID:
+		recipientID = application.StudentID
+	default:
+		return nil, ErrNotApplicationParticipant
+	}
+	if application.Status.Terminal() {
+		return nil, ErrThreadClosed
+	}
+
+	message := model.Message{
+		ID:            GenerateUUID(),
+		ApplicationID: appID,
+		AuthorID:      authorID,
+		Body:          body,
+	}
+	query := "INSERT INTO application_messages (id, application_id, author_id, body) VALUES ($1, $2, $3, $4) RETURNING created_at"
+	err = tx.QueryRowContext(ctx, query, message.ID, appID, authorID, body).Scan(&message.CreatedAt)
+	if err != nil {
+		log.Printf("failed to send message: %v", err)
+		return nil, err
+	}
+	message.CreatedAt = message.CreatedAt.UTC()
+
+	err = notify(ctx, tx, recipientID, "application_message", fmt.Sprintf("New message about \"%s\"", application.Heading))
+	if err != nil {
+		return nil, err
+	}
+	return &message, tx.Commit()
+}
+
+func (db Client) GetApplicationMessages(ctx context.Context, appID string, userID string, opts ListOptions) (*model.Page[model.Message], error) {
+	if _, err := applicationParticipant(ctx, db.conn, appID, userID); err != nil {
+		return nil, err
+	}
+	q := messageQuery(userID)
+	q.where("m.application_id = " + q.arg(appID))
+	return db.getMessages(ctx, q, opts)
+}
+
+// GetProjectMessages returns the conversation that led to the project, it is kept as part of the project history
+func (db Client) GetProjectMessages(ctx context.Context, projectID string, userID string, opts ListOptions) (*model.Page[model.Message], error) {
+	if _, err := projectRole(ctx, db.conn, projectID, userID); err != nil {
+		return nil, err
+	}
+	q := messageQuery(userID)
+	q.where("m.project_id = " + q.arg(projectID))
+	return db.getMessages(ctx, q, opts)
+}
+
+func messageQuery(viewerID string) *listQuery {
+	q := &listQuery{
+		from:     "application_messages m INNER JOIN users u ON m.author_id = u.id",
+		idColumn: "m.id",
+	}
+	q.columns = "m.id, m.application_id, m.author_id, coalesce(u.name, ''), m.body, m.created_at, m.read_at, (m.author_id <> " + q.arg(viewerID) + " AND m.read_at IS NULL)"
+	return q
+}
+
+func (db Client) getMessages(ctx context.Context, q *listQuery, opts ListOptions) (*model.Page[model.Message], error) {
+	q.dateRange("m.created_at", opts)
+	query, args, err := q.build(opts, messageSorts, "createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get messages: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.Message](opts, "createdAt")
+	for rows.Next() {
+		var (
+			message model.Message
+			readAt  sql.NullTime
+			key     string
+		)
+		err = rows.Scan(&message.ID, &message.ApplicationID, &message.AuthorID, &message.AuthorName, &message.Body, &message.CreatedAt, &readAt, &message.Unread, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting messages: %v", err)
+			return nil, err
+		}
+		message.CreatedAt = message.CreatedAt.UTC()
+		if readAt.Valid {
+			at := readAt.Time.UTC()
+			message.ReadAt = &at
+		}
+		result.add(message, message.ID, key)
+	}
+	return result.page(), nil
+}
+
+// ReadApplicationMessages marks every message the user received on the application as read
+func (db Client) ReadApplicationMessages(ctx context.Context, appID string, userID string) error {
+	if _, err := applicationParticipant(ctx, db.conn, appID, userID); err != nil {
+		return err
+	}
+	_, err := db.conn.ExecContext(ctx, "UPDATE application_messages SET read_at = now() WHERE application_id = $1 AND author_id <> $2 AND read_at IS NULL", appID, userID)
+	if err != nil {
+		log.Printf("failed to mark messages as read: %v", err)
+		return err
+	}
+	return nil
+}
+
+// moveMessagesToProject attaches the thread of an accepted application to the project created from it
+func moveMessagesToProject(ctx context.Context, tx *sql.Tx, appID string, projectID string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE application_messages SET project_id = $1 WHERE application_id = $2", projectID, appID)
+	if err != nil {
+		log.Printf("failed to move messages to project: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_SendApplicationMessageRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	tests := []struct {
+		name     string
+		authorID string
+		status   string
+		err      error
+	}{
+		{"outsider", "student-2", "submitted", ErrNotApplicationParticipant},
+		{"application has an outcome", "student-1", "withdrawn", ErrThreadClosed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+				WithArgs("app-1").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+					AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", test.status, "", nil))
+			mock.ExpectRollback()
+
+			message, err := d.SendApplicationMessage(context.Background(), "app-1", test.authorID, "hello")
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, message)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_SendApplicationMessage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+		WithArgs("app-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+			AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", "submitted", "", nil))
+	mock.ExpectQuery(`INSERT INTO application_messages \(id, application_id, author_id, body\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING created_at`).
+		WithArgs(sqlmock.AnyArg(), "app-1", "supervisor-1", "**hi**").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(created))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "student-1", "application_message", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	message, err := d.SendApplicationMessage(context.Background(), "app-1", "supervisor-1", "**hi**")
+	assert.NoError(t, err)
+	assert.Equal(t, "supervisor-1", message.AuthorID)
+	assert.Equal(t, created, message.CreatedAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
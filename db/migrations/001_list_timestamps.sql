@@ -0,0 +1,6 @@
+-- creation timestamps used for date range filtering and the default sort order of list endpoints
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS applications_supervisor_created_idx ON applications (supervisor_id, created_at, id);
+CREATE INDEX IF NOT EXISTS projects_supervisor_created_idx ON projects (supervisor_id, created_at, project_id);
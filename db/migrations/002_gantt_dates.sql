@@ -0,0 +1,60 @@
+-- gantt item dates were stored as free text, convert them to timestamptz so they sort chronologically. the text is
+-- kept in gantt_item_legacy_dates so nothing is lost when a date had to be filled in from the other date of the item
+CREATE TABLE gantt_item_legacy_dates (
+    item_id    uuid PRIMARY KEY REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    start_date text,
+    end_date   text
+);
+
+INSERT INTO gantt_item_legacy_dates (item_id, start_date, end_date)
+SELECT item_id, start_date, end_date FROM gantt_items;
+
+-- the clients stored month/year ("01/24" or "01/2024") or ISO dates and timestamps. a month/year end date means the
+-- end of that month. anything else stops the migration instead of being replaced with a made up date.
+CREATE FUNCTION pg_temp.legacy_timestamptz(value text, is_end boolean) RETURNS timestamptz AS $$
+DECLARE
+    month date;
+BEGIN
+    value := trim(value);
+    IF value IS NULL OR value = '' THEN
+        RETURN NULL;
+    END IF;
+    IF value ~ '^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}(:?\d{2})?)?)?$' THEN
+        RETURN value::timestamptz;
+    END IF;
+    IF value !~ '^(0?[1-9]|1[0-2])/(\d{2}|\d{4})$' THEN
+        RAISE EXCEPTION 'cannot convert gantt item date "%", fix it before migrating', value;
+    END IF;
+    IF length(split_part(value, '/', 2)) = 2 THEN
+        month := to_date(value, 'MM/YY');
+    ELSE
+        month := to_date(value, 'MM/YYYY');
+    END IF;
+    IF is_end THEN
+        month := month + interval '1 month' - interval '1 day';
+    END IF;
+    RETURN month::timestamp AT TIME ZONE 'UTC';
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+ALTER TABLE gantt_items
+    ALTER COLUMN start_date TYPE timestamptz USING pg_temp.legacy_timestamptz(start_date, false),
+    ALTER COLUMN end_date TYPE timestamptz USING pg_temp.legacy_timestamptz(end_date, true);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM gantt_items WHERE start_date IS NULL AND end_date IS NULL) THEN
+        RAISE EXCEPTION 'gantt items without any date cannot be converted, fix them before migrating';
+    END IF;
+END;
+$$;
+
+-- an item missing one of its dates takes the other one, the original text stays in gantt_item_legacy_dates
+UPDATE gantt_items SET start_date = end_date WHERE start_date IS NULL;
+UPDATE gantt_items SET end_date = start_date WHERE end_date IS NULL;
+UPDATE gantt_items SET start_date = end_date, end_date = start_date WHERE end_date < start_date;
+
+ALTER TABLE gantt_items
+    ALTER COLUMN start_date SET NOT NULL,
+    ALTER COLUMN end_date SET NOT NULL,
+    ADD CONSTRAINT gantt_items_dates_check CHECK (end_date >= start_date);
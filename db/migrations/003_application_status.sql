@@ -0,0 +1,32 @@
+-- replace the accepted and declined flags with an explicit lifecycle status and record every transition
+ALTER TABLE applications
+    ADD COLUMN status text NOT NULL DEFAULT 'submitted'
+        CHECK (status IN ('draft', 'submitted', 'under_review', 'shortlisted', 'offered', 'accepted', 'declined', 'withdrawn', 'expired')),
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+
+UPDATE applications SET status = CASE
+    WHEN accepted THEN 'accepted'
+    WHEN declined THEN 'declined'
+    ELSE 'submitted'
+END;
+
+CREATE TABLE application_transitions (
+    id             uuid PRIMARY KEY,
+    application_id uuid        NOT NULL REFERENCES applications (id) ON DELETE CASCADE,
+    from_status    text,
+    to_status      text        NOT NULL,
+    actor_id       text REFERENCES users (id),
+    actor_role     text        NOT NULL CHECK (actor_role IN ('student', 'supervisor', 'system')),
+    reason         text        NOT NULL DEFAULT '',
+    created_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX application_transitions_application_idx ON application_transitions (application_id, created_at);
+
+INSERT INTO application_transitions (id, application_id, from_status, to_status, actor_role, reason, created_at)
+SELECT gen_random_uuid(), id, NULL, status, 'system', 'migrated from accepted/declined flags', created_at
+FROM applications;
+
+ALTER TABLE applications DROP COLUMN accepted, DROP COLUMN declined;
+
+CREATE INDEX applications_status_idx ON applications (status);
@@ -0,0 +1,34 @@
+-- preference based allocation of students to supervisors
+ALTER TABLE users ADD COLUMN capacity integer NOT NULL DEFAULT 5 CHECK (capacity >= 0);
+
+CREATE TABLE student_preferences (
+    student_id    text    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    supervisor_id text    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    rank          integer NOT NULL CHECK (rank > 0),
+    PRIMARY KEY (student_id, rank),
+    UNIQUE (student_id, supervisor_id)
+);
+
+CREATE TABLE supervisor_rankings (
+    supervisor_id text    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    student_id    text    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    rank          integer NOT NULL CHECK (rank > 0),
+    PRIMARY KEY (supervisor_id, rank),
+    UNIQUE (supervisor_id, student_id)
+);
+
+CREATE TABLE allocation_runs (
+    id           uuid PRIMARY KEY,
+    created_by   text        NOT NULL, -- coordinators are not necessarily in users
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    committed_by text,
+    committed_at timestamptz
+);
+
+CREATE TABLE allocation_assignments (
+    run_id        uuid NOT NULL REFERENCES allocation_runs (id) ON DELETE CASCADE,
+    student_id    text NOT NULL REFERENCES users (id),
+    supervisor_id text REFERENCES users (id), -- null when the student could not be placed
+    student_rank  integer,
+    PRIMARY KEY (run_id, student_id)
+);
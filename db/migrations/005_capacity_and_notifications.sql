@@ -0,0 +1,31 @@
+-- per academic year supervisor capacity, users.capacity remains the default for years without an entry
+CREATE TABLE supervisor_capacities (
+    supervisor_id text    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    academic_year text    NOT NULL CHECK (academic_year ~ '^\d{4}/\d{2}$'),
+    capacity      integer NOT NULL CHECK (capacity >= 0),
+    PRIMARY KEY (supervisor_id, academic_year)
+);
+
+ALTER TABLE projects ADD COLUMN academic_year text;
+UPDATE projects SET academic_year = CASE
+    WHEN extract(month FROM created_at) >= 9
+        THEN to_char(created_at, 'YYYY') || '/' || to_char(created_at + interval '1 year', 'YY')
+    ELSE to_char(created_at - interval '1 year', 'YYYY') || '/' || to_char(created_at, 'YY')
+END;
+ALTER TABLE projects ALTER COLUMN academic_year SET NOT NULL;
+CREATE INDEX projects_supervisor_year_idx ON projects (supervisor_id, academic_year);
+
+ALTER TABLE applications DROP CONSTRAINT applications_status_check;
+ALTER TABLE applications ADD CONSTRAINT applications_status_check
+    CHECK (status IN ('draft', 'submitted', 'waitlisted', 'under_review', 'shortlisted', 'offered', 'accepted', 'declined', 'withdrawn', 'expired'));
+
+CREATE TABLE notifications (
+    id         uuid PRIMARY KEY,
+    user_id    text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       text        NOT NULL,
+    message    text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    read_at    timestamptz
+);
+
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at, id);
@@ -0,0 +1,27 @@
+-- catalogue of project ideas published by supervisors
+CREATE TABLE project_proposals (
+    id            uuid PRIMARY KEY,
+    supervisor_id text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title         text        NOT NULL,
+    description   text        NOT NULL,
+    prerequisites text        NOT NULL DEFAULT '',
+    tags          text[]      NOT NULL DEFAULT '{}',
+    slots         integer     NOT NULL CHECK (slots > 0),
+    status        text        NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published', 'archived')),
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    updated_at    timestamptz NOT NULL DEFAULT now(),
+    search        tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'B') ||
+        setweight(to_tsvector('english', prerequisites), 'C')
+    ) STORED
+);
+
+CREATE INDEX project_proposals_status_created_idx ON project_proposals (status, created_at, id);
+CREATE INDEX project_proposals_search_idx ON project_proposals USING gin (search);
+CREATE INDEX project_proposals_tags_idx ON project_proposals USING gin (tags);
+
+ALTER TABLE applications ADD COLUMN proposal_id uuid REFERENCES project_proposals (id);
+ALTER TABLE projects ADD COLUMN proposal_id uuid REFERENCES project_proposals (id);
+CREATE INDEX applications_proposal_idx ON applications (proposal_id) WHERE proposal_id IS NOT NULL;
+CREATE INDEX projects_proposal_idx ON projects (proposal_id) WHERE proposal_id IS NOT NULL;
@@ -0,0 +1,14 @@
+-- files uploaded with applications, the content lives in the blob store under storage_key
+CREATE TABLE application_attachments (
+    id             uuid PRIMARY KEY,
+    application_id uuid        NOT NULL REFERENCES applications (id) ON DELETE CASCADE,
+    uploaded_by    text        NOT NULL REFERENCES users (id),
+    file_name      text        NOT NULL,
+    content_type   text        NOT NULL,
+    size_bytes     bigint      NOT NULL CHECK (size_bytes >= 0),
+    sha256         text        NOT NULL CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+    storage_key    text        NOT NULL UNIQUE,
+    created_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX application_attachments_application_idx ON application_attachments (application_id, created_at, id);
@@ -0,0 +1,18 @@
+-- application rounds with their own expiry windows, null windows use the server defaults
+CREATE TABLE application_rounds (
+    id                  uuid PRIMARY KEY,
+    name                text        NOT NULL,
+    opens_at            timestamptz NOT NULL,
+    closes_at           timestamptz NOT NULL,
+    pending_expiry_days integer CHECK (pending_expiry_days > 0),
+    offer_expiry_days   integer CHECK (offer_expiry_days > 0),
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    CHECK (closes_at > opens_at),
+    EXCLUDE USING gist (tstzrange(opens_at, closes_at) WITH &&)
+);
+
+ALTER TABLE applications ADD COLUMN round_id uuid REFERENCES application_rounds (id);
+
+-- the sweeper looks for pending applications by their last activity
+CREATE INDEX applications_pending_updated_idx ON applications (updated_at, id)
+    WHERE status IN ('submitted', 'waitlisted', 'under_review', 'shortlisted', 'offered');
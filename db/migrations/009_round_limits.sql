@@ -0,0 +1,5 @@
+-- per round limits: applications per student and a deadline for supervisors to respond
+ALTER TABLE application_rounds ADD COLUMN student_cap integer CHECK (student_cap > 0);
+ALTER TABLE application_rounds ADD COLUMN response_deadline timestamptz CHECK (response_deadline > opens_at);
+
+CREATE INDEX applications_student_round_idx ON applications (student_id, round_id);
@@ -0,0 +1,13 @@
+-- message thread between student and supervisor, kept with the project once the application is accepted
+CREATE TABLE application_messages (
+    id             uuid PRIMARY KEY,
+    application_id uuid        NOT NULL REFERENCES applications (id) ON DELETE CASCADE,
+    project_id     uuid REFERENCES projects (project_id) ON DELETE SET NULL,
+    author_id      text        NOT NULL REFERENCES users (id),
+    body           text        NOT NULL CHECK (length(body) > 0),
+    created_at     timestamptz NOT NULL DEFAULT now(),
+    read_at        timestamptz
+);
+
+CREATE INDEX application_messages_application_idx ON application_messages (application_id, created_at, id);
+CREATE INDEX application_messages_project_idx ON application_messages (project_id, created_at, id) WHERE project_id IS NOT NULL;
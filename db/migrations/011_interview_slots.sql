@@ -0,0 +1,22 @@
+-- interview slots published by supervisors and booked by their shortlisted applicants
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE interview_slots (
+    id             uuid PRIMARY KEY,
+    supervisor_id  text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    starts_at      timestamptz NOT NULL,
+    ends_at        timestamptz NOT NULL,
+    location       text        NOT NULL DEFAULT '',
+    application_id uuid UNIQUE REFERENCES applications (id), -- one interview per application
+    student_id     text REFERENCES users (id),
+    booked_at      timestamptz,
+    created_at     timestamptz NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at),
+    CHECK ((application_id IS NULL) = (student_id IS NULL)),
+    -- neither the supervisor nor the student can be in two interviews at once
+    CONSTRAINT interview_slots_supervisor_overlap EXCLUDE USING gist (supervisor_id WITH =, tstzrange(starts_at, ends_at) WITH &&),
+    CONSTRAINT interview_slots_student_overlap EXCLUDE USING gist (student_id WITH =, tstzrange(starts_at, ends_at) WITH &&)
+        WHERE (student_id IS NOT NULL)
+);
+
+CREATE INDEX interview_slots_starts_idx ON interview_slots (starts_at, id);
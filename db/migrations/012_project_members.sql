@@ -0,0 +1,23 @@
+-- everybody taking part in a project with their role, replaces the single second reader column
+CREATE TABLE project_members (
+    project_id uuid        NOT NULL REFERENCES projects (project_id) ON DELETE CASCADE,
+    user_id    text        NOT NULL REFERENCES users (id),
+    role       text        NOT NULL CHECK (role IN ('primary_supervisor', 'co_supervisor', 'second_reader', 'external_mentor', 'student')),
+    added_at   timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+-- exactly one primary supervisor, it stays mirrored in projects.supervisor_id for the capacity count
+CREATE UNIQUE INDEX project_members_primary_idx ON project_members (project_id) WHERE role = 'primary_supervisor';
+CREATE UNIQUE INDEX project_members_second_reader_idx ON project_members (project_id) WHERE role = 'second_reader';
+CREATE INDEX project_members_user_idx ON project_members (user_id, role);
+
+INSERT INTO project_members (project_id, user_id, role)
+SELECT project_id, supervisor_id, 'primary_supervisor' FROM projects;
+INSERT INTO project_members (project_id, user_id, role)
+SELECT project_id, student_id, 'student' FROM projects;
+INSERT INTO project_members (project_id, user_id, role)
+SELECT project_id, second_reader_id, 'second_reader' FROM projects
+WHERE second_reader_id IS NOT NULL AND second_reader_id NOT IN (supervisor_id, student_id);
+
+ALTER TABLE projects DROP COLUMN second_reader_id;
@@ -0,0 +1,20 @@
+-- students invited to a joint application, confirmed teammates join the project with the applicant
+CREATE TABLE application_team (
+    application_id uuid        NOT NULL REFERENCES applications (id) ON DELETE CASCADE,
+    student_id     text        NOT NULL REFERENCES users (id),
+    status         text        NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'confirmed', 'declined')),
+    invited_at     timestamptz NOT NULL DEFAULT now(),
+    responded_at   timestamptz,
+    PRIMARY KEY (application_id, student_id)
+);
+
+CREATE INDEX application_team_student_idx ON application_team (student_id, status);
+
+-- the share of the work every student member did on a gantt item
+CREATE TABLE gantt_item_contributions (
+    item_id uuid    NOT NULL REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    user_id text    NOT NULL REFERENCES users (id),
+    share   integer NOT NULL CHECK (share BETWEEN 1 AND 100),
+    note    text    NOT NULL DEFAULT '',
+    PRIMARY KEY (item_id, user_id)
+);
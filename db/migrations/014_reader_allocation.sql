@@ -0,0 +1,19 @@
+-- topics a staff member can second read, matched against the tags of a project's proposal
+ALTER TABLE users ADD COLUMN expertise text[] NOT NULL DEFAULT '{}';
+
+-- proposed second reader assignments, they are reviewed before the readers join the projects
+CREATE TABLE reader_allocation_runs (
+    id           uuid PRIMARY KEY,
+    created_by   text        NOT NULL, -- coordinators are not necessarily in users
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    committed_by text,
+    committed_at timestamptz
+);
+
+CREATE TABLE reader_allocation_assignments (
+    run_id       uuid   NOT NULL REFERENCES reader_allocation_runs (id) ON DELETE CASCADE,
+    project_id   uuid   NOT NULL REFERENCES projects (project_id) ON DELETE CASCADE,
+    reader_id    text   REFERENCES users (id), -- null when nobody can read the project
+    matched_tags text[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (run_id, project_id)
+);
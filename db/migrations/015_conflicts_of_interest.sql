@@ -0,0 +1,23 @@
+-- students and supervisors a staff member must not second read for, co-authors or personal tutees for example
+CREATE TABLE conflicts_of_interest (
+    staff_id    text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_id     text        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason      text        NOT NULL,
+    declared_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (staff_id, user_id),
+    CHECK (staff_id <> user_id)
+);
+
+CREATE INDEX conflicts_of_interest_user_idx ON conflicts_of_interest (user_id);
+
+-- decisions coordinators took against the rules, kept for later review
+CREATE TABLE audit_log (
+    id         uuid PRIMARY KEY,
+    actor_id   text        NOT NULL, -- coordinators are not necessarily in users
+    action     text        NOT NULL,
+    subject_id text        NOT NULL,
+    detail     text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_created_idx ON audit_log (created_at, id);
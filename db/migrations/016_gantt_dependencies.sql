@@ -0,0 +1,11 @@
+-- an item waits for another item of the same project, the lag in days may be negative to let them overlap
+CREATE TABLE gantt_dependencies (
+    predecessor_id uuid    NOT NULL REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    successor_id   uuid    NOT NULL REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    kind           text    NOT NULL DEFAULT 'finish_to_start' CHECK (kind IN ('finish_to_start', 'start_to_start', 'finish_to_finish')),
+    lag_days       integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (predecessor_id, successor_id),
+    CHECK (predecessor_id <> successor_id)
+);
+
+CREATE INDEX gantt_dependencies_successor_idx ON gantt_dependencies (successor_id);
@@ -0,0 +1,9 @@
+-- the state of a gantt item was encoded in its colour, the colour is now derived from the status on the server
+ALTER TABLE gantt_items
+    ADD COLUMN status text NOT NULL DEFAULT 'on_track' CHECK (status IN ('on_track', 'at_risk', 'blocked', 'complete')),
+    ADD COLUMN percent_complete integer NOT NULL DEFAULT 0 CHECK (percent_complete BETWEEN 0 AND 100);
+
+UPDATE gantt_items SET status = 'complete', percent_complete = 100 WHERE upper(colour) = '#2C59C7';
+-- yellow only flagged unread feedback, which feedback_update_tracker records on its own. an alert overwrote the
+-- blue of completed items, so those cannot be told apart and stay on track
+ALTER TABLE gantt_items DROP COLUMN colour;
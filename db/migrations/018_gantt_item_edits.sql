@@ -0,0 +1,2 @@
+-- supervisors lock the end date of an item once it is agreed, edits are recorded in the audit log
+ALTER TABLE gantt_items ADD COLUMN deadline_locked boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- every change to a gantt item moves it to the next version, clients send the version they read as If-Match
+ALTER TABLE gantt_items ADD COLUMN version integer NOT NULL DEFAULT 1;
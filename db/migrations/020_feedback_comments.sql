@@ -0,0 +1,52 @@
+-- feedback on a gantt item becomes a thread of comments with their author, replaces the appended feedback text
+CREATE TABLE feedback_comments (
+    id         uuid PRIMARY KEY,
+    item_id    uuid        NOT NULL REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    parent_id  uuid REFERENCES feedback_comments (id),
+    author_id  text REFERENCES users (id), -- unknown for imported feedback the author cannot be told for
+    role       text CHECK (role IN ('primary_supervisor', 'co_supervisor', 'second_reader', 'external_mentor', 'student')),
+    body       text        NOT NULL CHECK (length(body) > 0),
+    imported   boolean     NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    edited_at  timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE INDEX feedback_comments_item_idx ON feedback_comments (item_id, created_at, id);
+
+-- the text was a list of "Label: message" blocks separated by a blank line. a block without a known label belongs
+-- to the message before it, blocks before the first label keep no role. the author is only filled in when a single
+-- member of the project has the role. the order is kept but the time of the old feedback is not known.
+WITH blocks AS (
+    SELECT g.item_id, g.project_id, b.ord, b.block,
+           substring(b.block FROM '^(Supervisor|Co-supervisor|Second reader|Mentor|Student): ') AS label
+    FROM gantt_items g, regexp_split_to_table(g.feedback, E'\n\n') WITH ORDINALITY AS b(block, ord)
+    WHERE trim(b.block) <> ''
+), grouped AS (
+    SELECT *, count(label) OVER (PARTITION BY item_id ORDER BY ord) AS message
+    FROM blocks
+), messages AS (
+    SELECT item_id, project_id, min(ord) AS ord, (array_agg(label ORDER BY ord))[1] AS label,
+           string_agg(block, E'\n\n' ORDER BY ord) AS body
+    FROM grouped
+    GROUP BY item_id, project_id, message
+), comments AS (
+    SELECT item_id, project_id, ord,
+           CASE label
+               WHEN 'Supervisor' THEN 'primary_supervisor'
+               WHEN 'Co-supervisor' THEN 'co_supervisor'
+               WHEN 'Second reader' THEN 'second_reader'
+               WHEN 'Mentor' THEN 'external_mentor'
+               WHEN 'Student' THEN 'student'
+           END AS role,
+           trim(substring(body FROM length(coalesce(label || ': ', '')) + 1)) AS body
+    FROM messages
+)
+INSERT INTO feedback_comments (id, item_id, author_id, role, body, imported, created_at)
+SELECT gen_random_uuid(), c.item_id,
+       (SELECT min(m.user_id) FROM project_members m WHERE m.project_id = c.project_id AND m.role = c.role HAVING count(*) = 1),
+       c.role, c.body, true, now() - interval '1 second' + c.ord * interval '1 microsecond'
+FROM comments c
+WHERE c.body <> '';
+
+ALTER TABLE gantt_items DROP COLUMN feedback;
@@ -0,0 +1,23 @@
+-- every member of a project keeps their own last read marker per gantt item, replaces the tracker that could only
+-- tell the student side from the staff side
+CREATE TABLE feedback_reads (
+    item_id uuid        NOT NULL REFERENCES gantt_items (item_id) ON DELETE CASCADE,
+    user_id text        NOT NULL REFERENCES users (id),
+    read_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (item_id, user_id)
+);
+
+CREATE INDEX feedback_reads_user_idx ON feedback_reads (user_id);
+
+-- the side the alert was raised for (1 students, 2 staff) has the latest comment left to read, everybody else has
+-- read the whole thread
+INSERT INTO feedback_reads (item_id, user_id, read_at)
+SELECT g.item_id, m.user_id,
+       CASE
+           WHEN (g.feedback_update_tracker = 1 AND m.role = 'student') OR (g.feedback_update_tracker = 2 AND m.role <> 'student')
+               THEN coalesce((SELECT max(c.created_at) FROM feedback_comments c WHERE c.item_id = g.item_id), now()) - interval '1 microsecond'
+           ELSE now()
+       END
+FROM gantt_items g INNER JOIN project_members m ON m.project_id = g.project_id;
+
+ALTER TABLE gantt_items DROP COLUMN feedback_update_tracker;
@@ -0,0 +1,7 @@
+-- secret feed urls for calendar applications, only a hash of the token is kept. a new token replaces the old one
+CREATE TABLE calendar_feeds (
+    user_id      text PRIMARY KEY REFERENCES users (id),
+    token_hash   text        NOT NULL UNIQUE,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    last_used_at timestamptz
+);
@@ -0,0 +1,35 @@
+-- coordinators keep the milestones every project starts with, placed in days after the start of the academic year
+CREATE TABLE milestone_templates (
+    id         uuid PRIMARY KEY,
+    name       text        NOT NULL UNIQUE,
+    is_default boolean     NOT NULL DEFAULT false, -- seeded into projects created without choosing a template
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX milestone_templates_default_idx ON milestone_templates (is_default) WHERE is_default;
+
+CREATE TABLE template_milestones (
+    id              uuid PRIMARY KEY,
+    template_id     uuid    NOT NULL REFERENCES milestone_templates (id) ON DELETE CASCADE,
+    position        integer NOT NULL,
+    name            text    NOT NULL,
+    description     text    NOT NULL DEFAULT '',
+    links           text    NOT NULL DEFAULT '',
+    start_day       integer NOT NULL,
+    end_day         integer NOT NULL,
+    deadline_locked boolean NOT NULL DEFAULT true,
+    CHECK (end_day >= start_day)
+);
+
+CREATE INDEX template_milestones_template_idx ON template_milestones (template_id, position);
+
+-- projects that follow their template take over later changes to it, items keep their place when a milestone is
+-- removed from the template
+ALTER TABLE projects
+    ADD COLUMN template_id     uuid REFERENCES milestone_templates (id) ON DELETE SET NULL,
+    ADD COLUMN follow_template boolean NOT NULL DEFAULT false;
+
+ALTER TABLE gantt_items ADD COLUMN template_milestone_id uuid REFERENCES template_milestones (id) ON DELETE SET NULL;
+
+CREATE INDEX gantt_items_template_milestone_idx ON gantt_items (template_milestone_id) WHERE template_milestone_id IS NOT NULL;
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// ErrNotificationNotFound is returned when the notification does not exist or belongs to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// execer is implemented by both the connection pool and transactions
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// notify stores an in-app notification for the user
+func notify(ctx context.Context, conn execer, userID string, kind string, message string) error {
+	_, err := conn.ExecContext(ctx, "INSERT INTO notifications (id, user_id, kind, message) VALUES ($1, $2, $3, $4)", GenerateUUID(), userID, kind, message)
+	if err != nil {
+		log.Printf("failed to create notification: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (db Client) GetNotifications(ctx context.Context, userID string, opts ListOptions) (*model.Page[model.Notification], error) {
+	q := listQuery{
+		columns:  "id, kind, message, created_at, read_at",
+		from:     "notifications",
+		idColumn: "id",
+	}
+	q.where("user_id = " + q.arg(userID))
+	q.dateRange("created_at", opts)
+
+	query, args, err := q.build(opts, notificationSorts, "-createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get notifications: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.Notification](opts, "-createdAt")
+	var (
+		id        string
+		kind      string
+		message   string
+		createdAt time.Time
+		readAt    sql.NullTime
+		key       string
+	)
+	for rows.Next() {
+		err = rows.Scan(&id, &kind, &message, &createdAt, &readAt, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting notifications: %v", err)
+			return nil, err
+		}
+
+		notification := model.Notification{
+			ID:        id,
+			Kind:      kind,
+			Message:   message,
+			CreatedAt: createdAt.UTC(),
+		}
+		if readAt.Valid {
+			at := readAt.Time.UTC()
+			notification.ReadAt = &at
+		}
+		result.add(notification, id, key)
+	}
+	return result.page(), nil
+}
+
+func (db Client) ReadNotification(ctx context.Context, userID string, notificationID string) error {
+	result, err := db.conn.ExecContext(ctx, "UPDATE notifications SET read_at = coalesce(read_at, now()) WHERE id = $1 AND user_id = $2", notificationID, userID)
+	if err != nil {
+		log.Printf("failed to mark notification as read: %v", err)
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// ErrProposalNotFound is returned when the proposal does not exist or is not visible to the user
+var ErrProposalNotFound = errors.New("proposal not found")
+
+// ErrNotProposalOwner is returned when a supervisor changes a proposal of another supervisor
+var ErrNotProposalOwner = errors.New("user is not the owner of the proposal")
+
+// ErrProposalNotPublished is returned when students apply to a proposal that is not open for applications
+var ErrProposalNotPublished = errors.New("proposal is not published")
+
+// ErrProposalFull is returned when every slot of the proposal is taken
+var ErrProposalFull = errors.New("proposal has no remaining slots")
+
+// ErrInvalidProposalStatus is returned for changes the current status of the proposal does not allow
+var ErrInvalidProposalStatus = errors.New("invalid proposal status change")
+
+// proposalColumns selects a proposal p together with the name of its supervisor u. Projects created from the
+// proposal and open offers on it use up slots.
+var proposalColumns = fmt.Sprintf(`p.id, p.supervisor_id, u.name, p.title, p.description, p.prerequisites, to_json(p.tags)::text, p.slots,
+    p.slots
+    - (SELECT count(*) FROM projects pr WHERE pr.proposal_id = p.id)
+    - (SELECT count(*) FROM applications a WHERE a.proposal_id = p.id AND a.status = '%s'),
+    p.status, p.created_at`, lifecycle.Offered)
+
+const proposalFrom = "project_proposals p INNER JOIN users u ON p.supervisor_id = u.id"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProposal(row scanner, extra ...any) (*model.Proposal, error) {
+	var (
+		proposal  model.Proposal
+		tags      string
+		createdAt time.Time
+	)
+	err := row.Scan(append([]any{&proposal.ID, &proposal.SupervisorID, &proposal.SupervisorName, &proposal.Title, &proposal.Description,
+		&proposal.Prerequisites, &tags, &proposal.Slots, &proposal.Remaining, &proposal.Status, &createdAt}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(tags), &proposal.Tags); err != nil {
+		return nil, err
+	}
+	proposal.CreatedAt = createdAt.UTC()
+	return &proposal, nil
+}
+
+func (db Client) CreateProposal(ctx context.Context, proposal Proposal) (string, error) {
+	id := GenerateUUID()
+	query := "INSERT INTO project_proposals (id, supervisor_id, title, description, prerequisites, tags, slots, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	_, err := db.conn.ExecContext(ctx, query, id, proposal.SupervisorID, proposal.Title, proposal.Description, proposal.Prerequisites,
+		proposal.Tags, proposal.Slots, ProposalDraft)
+	if err != nil {
+		log.Printf("failed to create proposal: %v", err)
+		return "", err
+	}
+	return id, nil
+}
+
+// lockProposal returns the owner and status of the proposal, failing when supervisorID is not the owner
+func lockProposal(ctx context.Context, tx *sql.Tx, proposalID string, supervisorID string) (ProposalStatus, error) {
+	var (
+		owner  string
+		status ProposalStatus
+	)
+	err := tx.QueryRowContext(ctx, "SELECT supervisor_id, status FROM project_proposals WHERE id = $1 FOR UPDATE", proposalID).Scan(&owner, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrProposalNotFound
+		}
+		log.Printf("cannot read proposal: %v", err)
+		return "", err
+	}
+	if owner != supervisorID {
+		return "", ErrNotProposalOwner
+	}
+	return status, nil
+}
+
+// UpdateProposal replaces the content of a draft or published proposal, archived proposals are read only
+func (db Client) UpdateProposal(ctx context.Context, proposal Proposal) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update proposal: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockProposal(ctx, tx, proposal.ID, proposal.SupervisorID)
+	if err != nil {
+		return err
+	}
+	if status == ProposalArchived {
+		return fmt.Errorf("%w: archived proposals cannot be edited", ErrInvalidProposalStatus)
+	}
+
+	query := "UPDATE project_proposals SET title = $1, description = $2, prerequisites = $3, tags = $4, slots = $5, updated_at = now() WHERE id = $6"
+	_, err = tx.ExecContext(ctx, query, proposal.Title, proposal.Description, proposal.Prerequisites, proposal.Tags, proposal.Slots, proposal.ID)
+	if err != nil {
+		log.Printf("failed to update proposal: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetProposalStatus publishes or archives a proposal. Archived proposals can be published again, nothing
+// goes back to draft.
+func (db Client) SetProposalStatus(ctx context.Context, proposalID string, supervisorID string, to ProposalStatus) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update proposal: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockProposal(ctx, tx, proposalID, supervisorID)
+	if err != nil {
+		return err
+	}
+	if to == ProposalDraft || to == status {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidProposalStatus, status, to)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE project_proposals SET status = $1, updated_at = now() WHERE id = $2", to, proposalID)
+	if err != nil {
+		log.Printf("failed to update proposal status: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteProposal removes a draft, proposals that have been published are archived instead so that
+// applications and projects keep their reference
+func (db Client) DeleteProposal(ctx context.Context, proposalID string, supervisorID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to delete proposal: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockProposal(ctx, tx, proposalID, supervisorID)
+	if err != nil {
+		return err
+	}
+	if status != ProposalDraft {
+		return fmt.Errorf("%w: only drafts can be deleted, archive the proposal instead", ErrInvalidProposalStatus)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM project_proposals WHERE id = $1", proposalID)
+	if err != nil {
+		log.Printf("failed to delete proposal: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetProposal returns a published proposal, or a proposal in any status to its owner
+func (db Client) GetProposal(ctx context.Context, proposalID string, userID string) (*model.Proposal, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE p.id = $1 AND (p.status = $2 OR p.supervisor_id = $3)", proposalColumns, proposalFrom)
+
+	proposal, err := scanProposal(db.conn.QueryRowContext(ctx, query, proposalID, ProposalPublished, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProposalNotFound
+		}
+		log.Printf("cannot read proposal: %v", err)
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// GetProposals searches the published catalogue. With mine set the proposals of userID are listed in every status instead.
+func (db Client) GetProposals(ctx context.Context, userID string, mine bool, opts ListOptions) (*model.Page[model.Proposal], error) {
+	q := listQuery{
+		columns:  proposalColumns,
+		from:     proposalFrom,
+		idColumn: "p.id",
+	}
+	if mine {
+		q.where("p.supervisor_id = " + q.arg(userID))
+	} else {
+		q.where("p.status = " + q.arg(ProposalPublished))
+		if opts.SupervisorID != "" {
+			q.where("p.supervisor_id = " + q.arg(opts.SupervisorID))
+		}
+	}
+	if opts.Search != "" {
+		q.where("p.search @@ websearch_to_tsquery('english', " + q.arg(opts.Search) + ")")
+	}
+	if len(opts.Tags) > 0 {
+		q.where("p.tags @> CAST(" + q.arg(opts.Tags) + " AS text[])")
+	}
+	q.dateRange("p.created_at", opts)
+
+	query, args, err := q.build(opts, proposalSorts, "-createdAt")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("cannot execute query to get proposals: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := newPageBuilder[model.Proposal](opts, "-createdAt")
+	var key string
+	for rows.Next() {
+		proposal, err := scanProposal(rows, &key)
+		if err != nil {
+			log.Printf("cannot read data while getting proposals: %v", err)
+			return nil, err
+		}
+		result.add(*proposal, proposal.ID, key)
+	}
+	return result.page(), nil
+}
+
+// openProposal locks a published proposal for an application and returns its supervisor and title
+func openProposal(ctx context.Context, tx *sql.Tx, proposalID string) (string, string, error) {
+	var supervisorID, title string
+	var status ProposalStatus
+	err := tx.QueryRowContext(ctx, "SELECT supervisor_id, title, status FROM project_proposals WHERE id = $1 FOR SHARE", proposalID).
+		Scan(&supervisorID, &title, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", ErrProposalNotFound
+		}
+		log.Printf("cannot read proposal: %v", err)
+		return "", "", err
+	}
+	if status != ProposalPublished {
+		return "", "", ErrProposalNotPublished
+	}
+	if err = reserveProposalSlot(ctx, tx, proposalID); err != nil {
+		return "", "", err
+	}
+	return supervisorID, title, nil
+}
+
+// reserveProposalSlot fails when projects and open offers already fill every slot of the proposal
+func reserveProposalSlot(ctx context.Context, tx *sql.Tx, proposalID string) error {
+	query := fmt.Sprintf(`SELECT p.slots
+    - (SELECT count(*) FROM projects pr WHERE pr.proposal_id = p.id)
+    - (SELECT count(*) FROM applications a WHERE a.proposal_id = p.id AND a.status = '%s')
+FROM project_proposals p WHERE p.id = $1 FOR UPDATE OF p`, lifecycle.Offered)
+
+	var remaining int
+	err := tx.QueryRowContext(ctx, query, proposalID).Scan(&remaining)
+	if err != nil {
+		log.Printf("cannot read proposal slots: %v", err)
+		return err
+	}
+	if remaining <= 0 {
+		return ErrProposalFull
+	}
+	return nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
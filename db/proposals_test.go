@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// arrayConverter lets slices through to the mock the way the pgx driver accepts them for array parameters
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v any) (driver.Value, error) {
+	if tags, ok := v.([]string); ok {
+		return tags, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestClient_GetProposals(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM project_proposals p INNER JOIN users u ON p.supervisor_id = u.id WHERE p.status = \$1 AND p.search @@ websearch_to_tsquery\('english', \$2\) AND p.tags @> CAST\(\$3 AS text\[\]\) ORDER BY p.created_at DESC, p.id DESC LIMIT 21`).
+		WithArgs(ProposalPublished, "compilers", []string{"go"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "supervisor_id", "name", "title", "description", "prerequisites", "tags", "slots", "remaining", "status", "created_at", "key"}).
+			AddRow("proposal-1", "supervisor-1", "Ada", "A Go compiler", "Write one", "Go", `["go","compilers"]`, 2, 1, "published", createdAt, "2024-10-01"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	page, err := d.GetProposals(context.Background(), "student-1", false, ListOptions{Search: "compilers", Tags: []string{"go"}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, []string{"go", "compilers"}, page.Items[0].Tags)
+		assert.Equal(t, 1, page.Items[0].Remaining)
+		assert.Equal(t, "Ada", page.Items[0].SupervisorName)
+	}
+	assert.Nil(t, page.NextCursor)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_SetProposalStatusRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	tests := []struct {
+		name   string
+		owner  string
+		status string
+		to     ProposalStatus
+		err    error
+	}{
+		{"other supervisor", "supervisor-2", "draft", ProposalPublished, ErrNotProposalOwner},
+		{"already published", "supervisor-1", "published", ProposalPublished, ErrInvalidProposalStatus},
+		{"back to draft", "supervisor-1", "archived", ProposalDraft, ErrInvalidProposalStatus},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT supervisor_id, status FROM project_proposals WHERE id = \$1 FOR UPDATE`).
+				WithArgs("proposal-1").
+				WillReturnRows(sqlmock.NewRows([]string{"supervisor_id", "status"}).AddRow(test.owner, test.status))
+			mock.ExpectRollback()
+
+			err := d.SetProposalStatus(context.Background(), "proposal-1", "supervisor-1", test.to)
+			assert.ErrorIs(t, err, test.err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/allocation"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"time"
+)
+
+// ErrReaderRunNotFound is returned when the reader allocation run does not exist
+var ErrReaderRunNotFound = errors.New("reader allocation run not found")
+
+// ErrReaderRunCommitted is returned when committing a reader allocation run a second time
+var ErrReaderRunCommitted = errors.New("reader allocation run already committed")
+
+// ErrReaderAlreadyAssigned is returned when committing a reader for a project that got a second reader in the
+// meantime or that the reader joined in another role
+var ErrReaderAlreadyAssigned = errors.New("project already has a second reader or the reader is a member")
+
+// SetExpertise replaces the topics the supervisor can second read
+func (db Client) SetExpertise(ctx context.Context, supervisorID string, tags []string) error {
+	result, err := db.conn.ExecContext(ctx, "UPDATE users SET expertise = $1 WHERE id = $2 AND is_supervisor = true", tags, supervisorID)
+	if err != nil {
+		log.Printf("failed to update expertise: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotSupervisor
+	}
+	return nil
+}
+
+func (db Client) GetExpertise(ctx context.Context, supervisorID string) ([]string, error) {
+	var tags string
+	err := db.conn.QueryRowContext(ctx, "SELECT to_json(expertise)::text FROM users WHERE id = $1 AND is_supervisor = true", supervisorID).Scan(&tags)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotSupervisor
+		}
+		log.Printf("cannot read expertise: %v", err)
+		return nil, err
+	}
+	result := []string{}
+	if err = json.Unmarshal([]byte(tags), &result); err != nil {
+		log.Printf("cannot decode expertise: %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetReaderAllocationInput loads the projects of the academic year without a second reader with their members
+// and topics, and every supervisor with their expertise and the projects they supervise or read this year
+func (db Client) GetReaderAllocationInput(ctx context.Context) ([]allocation.ReadingProject, []allocation.Reader, error) {
+	year := academic.Current()
+	query := `SELECT p.project_id, to_json(coalesce(pp.tags, '{}'))::text
+FROM projects p LEFT JOIN project_proposals pp ON pp.id = p.proposal_id
+WHERE p.academic_year = $1
+  AND NOT EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.project_id AND m.role = $2)
+ORDER BY p.project_id`
+	rows, err := db.conn.QueryContext(ctx, query, year, RoleSecondReader)
+	if err != nil {
+		log.Printf("cannot execute query to get projects without a second reader: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	projects := []allocation.ReadingProject{}
+	index := map[string]int{}
+	var projectIDs []string
+	for rows.Next() {
+		var (
+			project allocation.ReadingProject
+			tags    string
+		)
+		if err = rows.Scan(&project.ID, &tags); err != nil {
+			log.Printf("cannot read data while getting projects without a second reader: %v", err)
+			return nil, nil, err
+		}
+		if err = json.Unmarshal([]byte(tags), &project.Tags); err != nil {
+			log.Printf("cannot decode project tags: %v", err)
+			return nil, nil, err
+		}
+		index[project.ID] = len(projects)
+		projects = append(projects, project)
+		projectIDs = append(projectIDs, project.ID)
+	}
+	rows.Close()
+
+	if len(projects) > 0 {
+		memberRows, err := db.conn.QueryContext(ctx, "SELECT project_id, user_id FROM project_members WHERE project_id = ANY($1) ORDER BY project_id, user_id", projectIDs)
+		if err != nil {
+			log.Printf("cannot execute query to get project members: %v", err)
+			return nil, nil, err
+		}
+		defer memberRows.Close()
+
+		var projectID, userID string
+		for memberRows.Next() {
+			if err = memberRows.Scan(&projectID, &userID); err != nil {
+				log.Printf("cannot read data while getting project members: %v", err)
+				return nil, nil, err
+			}
+			if i, ok := index[projectID]; ok {
+				projects[i].Members = append(projects[i].Members, userID)
+			}
+		}
+	}
+
+	query = `SELECT u.id, to_json(u.expertise)::text,
+    (SELECT count(*) FROM project_members m INNER JOIN projects p ON p.project_id = m.project_id
+     WHERE m.user_id = u.id AND m.role <> $2 AND p.academic_year = $1)
+FROM users u
+WHERE u.is_supervisor = true
+ORDER BY u.id`
+	readerRows, err := db.conn.QueryContext(ctx, query, year, RoleStudent)
+	if err != nil {
+		log.Printf("cannot execute query to get reader load: %v", err)
+		return nil, nil, err
+	}
+	defer readerRows.Close()
+
+	readers := []allocation.Reader{}
+	for readerRows.Next() {
+		var (
+			reader    allocation.Reader
+			expertise string
+		)
+		if err = readerRows.Scan(&reader.ID, &expertise, &reader.Load); err != nil {
+			log.Printf("cannot read data while getting reader load: %v", err)
+			return nil, nil, err
+		}
+		if err = json.Unmarshal([]byte(expertise), &reader.Expertise); err != nil {
+			log.Printf("cannot decode expertise: %v", err)
+			return nil, nil, err
+		}
+		readers = append(readers, reader)
+	}
+	readerRows.Close()
+
+	conflicts, err := db.conflictPairs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range readers {
+		readers[i].Conflicts = conflicts[readers[i].ID]
+	}
+	return projects, readers, nil
+}
+
+func (db Client) SaveReaderAllocationRun(ctx context.Context, createdBy string, result allocation.ReaderResult) (string, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to save reader allocation run: %v", err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	runID := GenerateUUID()
+	_, err = tx.ExecContext(ctx, "INSERT INTO reader_allocation_runs (id, created_by) VALUES ($1, $2)", runID, createdBy)
+	if err != nil {
+		log.Printf("failed to create reader allocation run: %v", err)
+		return "", err
+	}
+
+	insert := "INSERT INTO reader_allocation_assignments (run_id, project_id, reader_id, matched_tags) VALUES ($1, $2, $3, $4)"
+	for _, assignment := range result.Assignments {
+		_, err = tx.ExecContext(ctx, insert, runID, assignment.ProjectID, assignment.ReaderID, assignment.Matched)
+		if err != nil {
+			log.Printf("failed to save reader assignment: %v", err)
+			return "", err
+		}
+	}
+	for _, projectID := range result.Unassigned {
+		_, err = tx.ExecContext(ctx, insert, runID, projectID, nil, []string{})
+		if err != nil {
+			log.Printf("failed to save project without reader: %v", err)
+			return "", err
+		}
+	}
+	return runID, tx.Commit()
+}
+
+// GetReaderAllocationRun returns a proposed or committed reader allocation with the names of the projects and
+// readers for review
+func (db Client) GetReaderAllocationRun(ctx context.Context, runID string) (*model.ReaderAllocationRun, error) {
+	run := &model.ReaderAllocationRun{
+		ID:          runID,
+		Assignments: []model.ReaderAssignment{},
+		Unassigned:  []model.ProjectData{},
+	}
+
+	var (
+		createdAt   time.Time
+		committedAt sql.NullTime
+		committedBy sql.NullString
+	)
+	row := db.conn.QueryRowContext(ctx, "SELECT created_by, created_at, committed_by, committed_at FROM reader_allocation_runs WHERE id = $1", runID)
+	err := row.Scan(&run.CreatedBy, &createdAt, &committedBy, &committedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReaderRunNotFound
+		}
+		log.Printf("cannot read reader allocation run: %v", err)
+		return nil, err
+	}
+	createdAt = createdAt.UTC()
+	run.CreatedAt = &createdAt
+	if committedAt.Valid {
+		at := committedAt.Time.UTC()
+		run.CommittedAt = &at
+		run.CommittedBy = committedBy.String
+	}
+
+	query := `SELECT a.project_id, p.project_name, p.supervisor_id, coalesce(a.reader_id, ''), coalesce(u.name, ''), to_json(a.matched_tags)::text
+FROM reader_allocation_assignments a INNER JOIN projects p ON p.project_id = a.project_id LEFT JOIN users u ON u.id = a.reader_id
+WHERE a.run_id = $1
+ORDER BY p.project_name, a.project_id`
+	rows, err := db.conn.QueryContext(ctx, query, runID)
+	if err != nil {
+		log.Printf("cannot execute query to get reader assignments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			assignment model.ReaderAssignment
+			matched    string
+		)
+		err = rows.Scan(&assignment.ProjectID, &assignment.ProjectName, &assignment.SupervisorID, &assignment.ReaderID, &assignment.ReaderName, &matched)
+		if err != nil {
+			log.Printf("cannot read data while getting reader assignments: %v", err)
+			return nil, err
+		}
+		if assignment.ReaderID == "" {
+			run.Unassigned = append(run.Unassigned, model.ProjectData{
+				ID:           assignment.ProjectID,
+				Name:         assignment.ProjectName,
+				SupervisorID: assignment.SupervisorID,
+			})
+			continue
+		}
+		if err = json.Unmarshal([]byte(matched), &assignment.MatchedTags); err != nil {
+			log.Printf("cannot decode matched tags: %v", err)
+			return nil, err
+		}
+		run.Assignments = append(run.Assignments, assignment)
+	}
+	return run, nil
+}
+
+// CommitReaderAllocationRun adds the proposed readers to their projects. Either every reader is added or none is.
+func (db Client) CommitReaderAllocationRun(ctx context.Context, runID string, committedBy string) error {
+	run, err := db.GetReaderAllocationRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to commit reader allocation run: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var committedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT committed_at FROM reader_allocation_runs WHERE id = $1 FOR UPDATE", runID).Scan(&committedAt)
+	if err != nil {
+		log.Printf("cannot lock reader allocation run: %v", err)
+		return err
+	}
+	if committedAt.Valid {
+		return ErrReaderRunCommitted
+	}
+
+	for _, assignment := range run.Assignments {
+		// conflicts declared after the run was proposed still count
+		if err = refuseConflicts(ctx, tx, assignment.ProjectID, assignment.ReaderID); err != nil {
+			return fmt.Errorf("%s: %w", assignment.ProjectName, err)
+		}
+		err = insertMember(ctx, tx, assignment.ProjectID, assignment.ReaderID, RoleSecondReader)
+		if errors.Is(err, ErrAlreadyMember) {
+			return fmt.Errorf("%w: %s", ErrReaderAlreadyAssigned, assignment.ProjectName)
+		}
+		if err != nil {
+			return err
+		}
+		err = notify(ctx, tx, assignment.ReaderID, "project_member_added",
+			fmt.Sprintf("You were added to the project \"%s\" as second reader", assignment.ProjectName))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE reader_allocation_runs SET committed_by = $1, committed_at = $2 WHERE id = $3", committedBy, time.Now(), runID)
+	if err != nil {
+		log.Printf("failed to mark reader allocation run as committed: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/allocation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_GetReaderAllocationInput(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	year := academic.Current()
+	mock.ExpectQuery(`FROM projects p LEFT JOIN project_proposals pp ON pp.id = p.proposal_id WHERE p.academic_year = \$1 AND NOT EXISTS`).
+		WithArgs(year, "second_reader").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "tags"}).
+			AddRow("project-1", `["go"]`).
+			AddRow("project-2", `[]`))
+	mock.ExpectQuery(`SELECT project_id, user_id FROM project_members WHERE project_id = ANY\(\$1\)`).
+		WithArgs([]string{"project-1", "project-2"}).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "user_id"}).
+			AddRow("project-1", "student-1").
+			AddRow("project-1", "supervisor-1").
+			AddRow("project-2", "student-2").
+			AddRow("project-2", "supervisor-2"))
+	mock.ExpectQuery(`FROM users u WHERE u.is_supervisor = true ORDER BY u.id`).
+		WithArgs(year, "student").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expertise", "load"}).
+			AddRow("supervisor-1", `["go","compilers"]`, 3).
+			AddRow("supervisor-2", `[]`, 1))
+	mock.ExpectQuery(`SELECT staff_id, user_id FROM conflicts_of_interest`).
+		WillReturnRows(sqlmock.NewRows([]string{"staff_id", "user_id"}).
+			AddRow("supervisor-1", "student-2"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	projects, readers, err := d.GetReaderAllocationInput(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []allocation.ReadingProject{
+		{ID: "project-1", Members: []string{"student-1", "supervisor-1"}, Tags: []string{"go"}},
+		{ID: "project-2", Members: []string{"student-2", "supervisor-2"}, Tags: []string{}},
+	}, projects)
+	assert.Equal(t, []allocation.Reader{
+		{ID: "supervisor-1", Load: 3, Expertise: []string{"go", "compilers"}, Conflicts: []string{"student-2"}},
+		{ID: "supervisor-2", Load: 1, Expertise: []string{}},
+	}, readers)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_CommitReaderAllocationRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT created_by, created_at, committed_by, committed_at FROM reader_allocation_runs WHERE id = \$1`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by", "created_at", "committed_by", "committed_at"}).
+			AddRow("coordinator", createdAt, nil, nil))
+	mock.ExpectQuery(`FROM reader_allocation_assignments a INNER JOIN projects p ON p.project_id = a.project_id LEFT JOIN users u ON u.id = a.reader_id WHERE a.run_id = \$1`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "project_name", "supervisor_id", "reader_id", "name", "matched_tags"}).
+			AddRow("project-1", "A Go compiler", "supervisor-1", "supervisor-2", "Edsger", `["go"]`).
+			AddRow("project-2", "Nobody reads this", "supervisor-3", "", "", `[]`))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT committed_at FROM reader_allocation_runs WHERE id = \$1 FOR UPDATE`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"committed_at"}).AddRow(nil))
+	mock.ExpectQuery(`FROM conflicts_of_interest c`).
+		WithArgs("project-1", "supervisor-2").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "reason", "declared_by_reader"}))
+	mock.ExpectExec(`INSERT INTO project_members \(project_id, user_id, role\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("project-1", "supervisor-2", "second_reader").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "supervisor-2", "project_member_added", `You were added to the project "A Go compiler" as second reader`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE reader_allocation_runs SET committed_by = \$1, committed_at = \$2 WHERE id = \$3`).
+		WithArgs("coordinator", sqlmock.AnyArg(), "run-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.CommitReaderAllocationRun(context.Background(), "run-1", "coordinator")
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_CommitReaderAllocationRunTwice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM reader_allocation_runs WHERE id = \$1`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"created_by", "created_at", "committed_by", "committed_at"}).
+			AddRow("coordinator", createdAt, "coordinator", createdAt))
+	mock.ExpectQuery(`FROM reader_allocation_assignments a`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "project_name", "supervisor_id", "reader_id", "name", "matched_tags"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT committed_at FROM reader_allocation_runs WHERE id = \$1 FOR UPDATE`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"committed_at"}).AddRow(createdAt))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.CommitReaderAllocationRun(context.Background(), "run-1", "coordinator")
+	assert.ErrorIs(t, err, ErrReaderRunCommitted)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,304 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log"
+	"time"
+)
+
+// ErrRoundNotFound is returned when the application round does not exist
+var ErrRoundNotFound = errors.New("application round not found")
+
+// ErrRoundOverlap is returned when an application round would overlap another round
+var ErrRoundOverlap = errors.New("application round overlaps another round")
+
+// ErrRoundClosed is returned when applications are submitted while no application round is open
+var ErrRoundClosed = errors.New("applications are closed")
+
+// ErrApplicationCapReached is returned when the student already submitted as many applications as the round allows
+var ErrApplicationCapReached = errors.New("application limit for the round reached")
+
+// ErrResponseDeadlinePassed is returned when a supervisor acts on an application after the response deadline of its round
+var ErrResponseDeadlinePassed = errors.New("response deadline of the round has passed")
+
+const roundColumns = "id, name, opens_at, closes_at, pending_expiry_days, offer_expiry_days, student_cap, response_deadline"
+
+func scanRound(row scanner) (*model.Round, error) {
+	var (
+		round             model.Round
+		pendingExpiryDays sql.NullInt32
+		offerExpiryDays   sql.NullInt32
+		studentCap        sql.NullInt32
+		responseDeadline  sql.NullTime
+	)
+	err := row.Scan(&round.ID, &round.Name, &round.OpensAt, &round.ClosesAt, &pendingExpiryDays, &offerExpiryDays, &studentCap, &responseDeadline)
+	if err != nil {
+		return nil, err
+	}
+	round.OpensAt, round.ClosesAt = round.OpensAt.UTC(), round.ClosesAt.UTC()
+	round.PendingExpiryDays = intPointer(pendingExpiryDays)
+	round.OfferExpiryDays = intPointer(offerExpiryDays)
+	round.StudentCap = intPointer(studentCap)
+	if responseDeadline.Valid {
+		deadline := responseDeadline.Time.UTC()
+		round.ResponseDeadline = &deadline
+	}
+	return &round, nil
+}
+
+func intPointer(value sql.NullInt32) *int {
+	if !value.Valid {
+		return nil
+	}
+	result := int(value.Int32)
+	return &result
+}
+
+// roundError translates constraint violations of the rounds table
+func roundError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" { //exclusion_violation
+		return ErrRoundOverlap
+	}
+	return err
+}
+
+func nullInt(value *int) sql.NullInt32 {
+	if value == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(*value), Valid: true}
+}
+
+func nullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
+
+func (db Client) CreateRound(ctx context.Context, round Round) (string, error) {
+	id := GenerateUUID()
+	query := "INSERT INTO application_rounds (" + roundColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	_, err := db.conn.ExecContext(ctx, query, id, round.Name, round.OpensAt, round.ClosesAt, nullInt(round.PendingExpiryDays), nullInt(round.OfferExpiryDays),
+		nullInt(round.StudentCap), nullTime(round.ResponseDeadline))
+	if err != nil {
+		log.Printf("failed to create application round: %v", err)
+		return "", roundError(err)
+	}
+	return id, nil
+}
+
+func (db Client) UpdateRound(ctx context.Context, round Round) error {
+	query := `UPDATE application_rounds SET name = $1, opens_at = $2, closes_at = $3, pending_expiry_days = $4, offer_expiry_days = $5,
+    student_cap = $6, response_deadline = $7 WHERE id = $8`
+
+	result, err := db.conn.ExecContext(ctx, query, round.Name, round.OpensAt, round.ClosesAt, nullInt(round.PendingExpiryDays), nullInt(round.OfferExpiryDays),
+		nullInt(round.StudentCap), nullTime(round.ResponseDeadline), round.ID)
+	if err != nil {
+		log.Printf("failed to update application round: %v", err)
+		return roundError(err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrRoundNotFound
+	}
+	return nil
+}
+
+func (db Client) GetRounds(ctx context.Context) ([]model.Round, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT "+roundColumns+" FROM application_rounds ORDER BY opens_at, id")
+	if err != nil {
+		log.Printf("cannot execute query to get application rounds: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Round{}
+	for rows.Next() {
+		round, err := scanRound(rows)
+		if err != nil {
+			log.Printf("cannot read data while getting application rounds: %v", err)
+			return nil, err
+		}
+		result = append(result, *round)
+	}
+	return result, nil
+}
+
+// ExpireStaleApplications expires up to limit pending applications without activity for longer than the expiry
+// window of their round, rounds without a window use the given defaults. Offers have their own, usually shorter,
+// window. Applications the supervisor has not answered by the response deadline of the round expire as well.
+// Both the student and the supervisor are notified. Returns how many applications were expired.
+func (db Client) ExpireStaleApplications(ctx context.Context, pendingExpiryDays int, offerExpiryDays int, limit int) (int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to expire applications: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`SELECT a.id, CASE WHEN a.status = '%[1]s' THEN coalesce(r.offer_expiry_days, $1) ELSE coalesce(r.pending_expiry_days, $2) END AS days,
+    coalesce(a.status <> '%[1]s' AND r.response_deadline < now(), false) AS overdue
+FROM applications a LEFT JOIN application_rounds r ON r.id = a.round_id
+WHERE a.status IN ('%[2]s', '%[3]s', '%[4]s', '%[5]s', '%[1]s')
+  AND (a.updated_at + make_interval(days => CASE WHEN a.status = '%[1]s' THEN coalesce(r.offer_expiry_days, $1) ELSE coalesce(r.pending_expiry_days, $2) END) < now()
+    OR (a.status <> '%[1]s' AND r.response_deadline < now()))
+ORDER BY a.updated_at, a.id
+LIMIT $3
+FOR UPDATE OF a SKIP LOCKED`, lifecycle.Offered, lifecycle.Submitted, lifecycle.Waitlisted, lifecycle.UnderReview, lifecycle.Shortlisted)
+
+	rows, err := tx.QueryContext(ctx, query, offerExpiryDays, pendingExpiryDays, limit)
+	if err != nil {
+		log.Printf("cannot execute query to get stale applications: %v", err)
+		return 0, err
+	}
+	type stale struct {
+		id      string
+		days    int
+		overdue bool
+	}
+	var applications []stale
+	for rows.Next() {
+		var application stale
+		if err = rows.Scan(&application.id, &application.days, &application.overdue); err != nil {
+			rows.Close()
+			log.Printf("cannot read stale application: %v", err)
+			return 0, err
+		}
+		applications = append(applications, application)
+	}
+	rows.Close()
+
+	for _, stale := range applications {
+		reason := fmt.Sprintf("no activity for %d days", stale.days)
+		if stale.overdue {
+			reason = "no response by the deadline of the round"
+		}
+		application, err := transitionApplication(ctx, tx, stale.id, lifecycle.Expired, "", reason)
+		if err != nil {
+			return 0, err
+		}
+		message := fmt.Sprintf("The application \"%s\" expired, %s", application.Heading, reason)
+		for _, userID := range []string{application.StudentID, application.SupervisorID} {
+			if err = notify(ctx, tx, userID, "application_expired", message); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(applications), tx.Commit()
+}
+
+// currentRound returns the open round and the next one to open, either may be nil
+func currentRound(ctx context.Context, conn queryer) (*model.Round, *model.Round, error) {
+	var rounds [2]*model.Round
+	queries := []string{
+		"SELECT " + roundColumns + " FROM application_rounds WHERE opens_at <= now() AND closes_at > now()",
+		"SELECT " + roundColumns + " FROM application_rounds WHERE opens_at > now() ORDER BY opens_at LIMIT 1",
+	}
+	for i, query := range queries {
+		round, err := scanRound(conn.QueryRowContext(ctx, query))
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("cannot read application round: %v", err)
+			return nil, nil, err
+		}
+		rounds[i] = round
+	}
+	return rounds[0], rounds[1], nil
+}
+
+// countRoundApplications counts the applications a student submitted in a round, drafts and withdrawn ones do not count
+func countRoundApplications(ctx context.Context, conn queryer, studentID string, roundID string) (int, error) {
+	var count int
+	err := conn.QueryRowContext(ctx, "SELECT count(*) FROM applications WHERE student_id = $1 AND round_id = $2 AND status NOT IN ($3, $4)",
+		studentID, roundID, lifecycle.Draft, lifecycle.Withdrawn).Scan(&count)
+	if err != nil {
+		log.Printf("cannot count applications of the round: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// enterRound checks that an application of the student can be submitted now and returns the round it belongs to.
+// As long as no round has been set up applications are accepted at any time and belong to no round.
+func enterRound(ctx context.Context, tx *sql.Tx, studentID string) (sql.NullString, error) {
+	round, next, err := currentRound(ctx, tx)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if round == nil {
+		var configured bool
+		if err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM application_rounds)").Scan(&configured); err != nil {
+			log.Printf("cannot read application rounds: %v", err)
+			return sql.NullString{}, err
+		}
+		if !configured {
+			return sql.NullString{}, nil
+		}
+		if next != nil {
+			return sql.NullString{}, fmt.Errorf("%w: %s opens at %s", ErrRoundClosed, next.Name, next.OpensAt.Format(time.RFC3339))
+		}
+		return sql.NullString{}, fmt.Errorf("%w: no further round is planned", ErrRoundClosed)
+	}
+
+	if round.StudentCap != nil {
+		//serialises submissions of the same student so that the cap holds
+		if _, err = tx.ExecContext(ctx, "SELECT 1 FROM users WHERE id = $1 FOR UPDATE", studentID); err != nil {
+			log.Printf("cannot lock student: %v", err)
+			return sql.NullString{}, err
+		}
+		count, err := countRoundApplications(ctx, tx, studentID, round.ID)
+		if err != nil {
+			return sql.NullString{}, err
+		}
+		if count >= *round.StudentCap {
+			return sql.NullString{}, fmt.Errorf("%w: at most %d applications can be submitted in %s", ErrApplicationCapReached, *round.StudentCap, round.Name)
+		}
+	}
+	return sql.NullString{String: round.ID, Valid: true}, nil
+}
+
+// GetRoundStatus tells whether applications are open, students also see how many applications they have left
+func (db Client) GetRoundStatus(ctx context.Context, userID string) (*model.RoundStatus, error) {
+	round, next, err := currentRound(ctx, db.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.RoundStatus{
+		Open:      round != nil,
+		Round:     round,
+		NextRound: next,
+	}
+	if round == nil {
+		var configured bool
+		if err = db.conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM application_rounds)").Scan(&configured); err != nil {
+			log.Printf("cannot read application rounds: %v", err)
+			return nil, err
+		}
+		status.Open = !configured
+		return status, nil
+	}
+
+	isSupervisor, err := db.getAccountStatus(ctx, userID)
+	if err != nil || isSupervisor { //coordinators are not necessarily in users
+		return status, nil
+	}
+	used, err := countRoundApplications(ctx, db.conn, userID, round.ID)
+	if err != nil {
+		return nil, err
+	}
+	status.ApplicationsUsed = &used
+	if round.StudentCap != nil {
+		remaining := max(*round.StudentCap-used, 0)
+		status.ApplicationsRemaining = &remaining
+	}
+	return status, nil
+}
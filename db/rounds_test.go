@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_ExpireStaleApplications(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM applications a LEFT JOIN application_rounds r ON r.id = a.round_id .* FOR UPDATE OF a SKIP LOCKED`).
+		WithArgs(7, 30, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "days", "overdue"}).AddRow("app-1", 30, false))
+	mock.ExpectQuery(`SELECT id, student_id, supervisor_id, heading, description, status, coalesce\(proposal_id::text, ''\), \(SELECT r.response_deadline FROM application_rounds r WHERE r.id = applications.round_id\) FROM applications WHERE id = \$1 FOR UPDATE`).
+		WithArgs("app-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "supervisor_id", "heading", "description", "status", "proposal_id", "response_deadline"}).
+			AddRow("app-1", "student-1", "supervisor-1", "Heading", "Description", "under_review", "", nil))
+	mock.ExpectExec(`UPDATE applications SET status = \$1, updated_at = now\(\) WHERE id = \$2`).
+		WithArgs(lifecycle.Expired, "app-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO application_transitions`).
+		WithArgs(sqlmock.AnyArg(), "app-1", sqlmock.AnyArg(), lifecycle.Expired, sqlmock.AnyArg(), lifecycle.System, "no activity for 30 days").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE interview_slots SET application_id = NULL, student_id = NULL, booked_at = NULL WHERE application_id = \$1 AND starts_at > now\(\)`).
+		WithArgs("app-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, userID := range []string{"student-1", "supervisor-1"} {
+		mock.ExpectExec(`INSERT INTO notifications`).
+			WithArgs(sqlmock.AnyArg(), userID, "application_expired", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	expired, err := d.ExpireStaleApplications(context.Background(), 30, 7, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_CreateApplicationOutsideRound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "name", "opens_at", "closes_at", "pending_expiry_days", "offer_expiry_days", "student_cap", "response_deadline"}
+	opensAt := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT coalesce\(c.capacity, u.capacity\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"capacity", "remaining"}).AddRow(5, 2))
+	mock.ExpectQuery(`FROM application_rounds WHERE opens_at <= now\(\) AND closes_at > now\(\)`).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(`FROM application_rounds WHERE opens_at > now\(\) ORDER BY opens_at LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("round-2", "Round 2", opensAt, opensAt.AddDate(0, 0, 14), nil, nil, 3, nil))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM application_rounds\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.CreateApplication(context.Background(), Application{SupervisorID: "supervisor-1", Heading: "Heading"}, "student-1")
+	assert.ErrorIs(t, err, ErrRoundClosed)
+	assert.ErrorContains(t, err, "Round 2 opens at 2024-11-01T09:00:00Z")
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
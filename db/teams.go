@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"log"
+	"slices"
+)
+
+// MaxTeamSize is the largest team a joint application can put together, the applicant included
+const MaxTeamSize = 4
+
+// ErrTeamTooLarge is returned when a joint application invites more students than a team can have
+var ErrTeamTooLarge = fmt.Errorf("a team has at most %d students", MaxTeamSize)
+
+// ErrInvalidTeammate is returned when an invited user is not a student who can still join a team
+var ErrInvalidTeammate = errors.New("invalid teammate")
+
+// ErrInviteNotFound is returned when the student has no open invitation to the application
+var ErrInviteNotFound = errors.New("team invitation not found")
+
+// ErrTeamIncomplete is returned when an application is offered or accepted while invitations are unanswered
+var ErrTeamIncomplete = errors.New("not every invited teammate has answered yet")
+
+// ErrInvalidContribution is returned when contributions are given for users who are not students of the project
+// or add up to more than the whole item
+var ErrInvalidContribution = errors.New("invalid contribution")
+
+// insertTeam invites the teammates of a joint application and notifies them
+func insertTeam(ctx context.Context, tx *sql.Tx, appID string, leadID string, heading string, teammateIDs []string) error {
+	if len(teammateIDs)+1 > MaxTeamSize {
+		return ErrTeamTooLarge
+	}
+	for i, studentID := range teammateIDs {
+		if studentID == leadID || slices.Contains(teammateIDs[:i], studentID) {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidTeammate, studentID)
+		}
+		var isSupervisor, hasProject bool
+		err := tx.QueryRowContext(ctx, "SELECT is_supervisor, has_project FROM users WHERE id = $1", studentID).Scan(&isSupervisor, &hasProject)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s does not exist", ErrInvalidTeammate, studentID)
+			}
+			log.Printf("cannot read teammate: %v", err)
+			return err
+		}
+		if isSupervisor || hasProject {
+			return fmt.Errorf("%w: %s is not a student without a project", ErrInvalidTeammate, studentID)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO application_team (application_id, student_id) VALUES ($1, $2)", appID, studentID)
+		if err != nil {
+			log.Printf("failed to invite teammate: %v", err)
+			return err
+		}
+		err = notify(ctx, tx, studentID, "team_invite", fmt.Sprintf("You were invited to apply for \"%s\" as a team", heading))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openTeamInvites counts the invitations of the application nobody answered yet
+func openTeamInvites(ctx context.Context, tx *sql.Tx, appID string) (int, error) {
+	var open int
+	err := tx.QueryRowContext(ctx, "SELECT count(*) FROM application_team WHERE application_id = $1 AND status = 'invited'", appID).Scan(&open)
+	if err != nil {
+		log.Printf("cannot count team invitations: %v", err)
+		return 0, err
+	}
+	return open, nil
+}
+
+// confirmedTeammates returns the students who confirmed they are part of the application's team
+func confirmedTeammates(ctx context.Context, tx *sql.Tx, appID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT student_id FROM application_team WHERE application_id = $1 AND status = 'confirmed' ORDER BY student_id", appID)
+	if err != nil {
+		log.Printf("cannot execute query to get teammates: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var studentIDs []string
+	for rows.Next() {
+		var studentID string
+		if err = rows.Scan(&studentID); err != nil {
+			log.Printf("cannot read teammate: %v", err)
+			return nil, err
+		}
+		studentIDs = append(studentIDs, studentID)
+	}
+	return studentIDs, nil
+}
+
+// RespondTeamInvite confirms or declines an invitation to a joint application, the applicant is notified
+func (db Client) RespondTeamInvite(ctx context.Context, appID string, studentID string, confirm bool) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to answer team invitation: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	application, err := lockApplication(ctx, tx, appID)
+	if err != nil {
+		return err
+	}
+	if application.Status.Terminal() {
+		return ErrInviteNotFound
+	}
+
+	status, answer := "declined", "declined"
+	if confirm {
+		status, answer = "confirmed", "joined"
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE application_team SET status = $1, responded_at = now() WHERE application_id = $2 AND student_id = $3 AND status = 'invited'",
+		status, appID, studentID)
+	if err != nil {
+		log.Printf("failed to answer team invitation: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInviteNotFound
+	}
+
+	err = notify(ctx, tx, application.StudentID, "team_invite_answered", fmt.Sprintf("A student %s your team for \"%s\"", answer, application.Heading))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTeamInvites lists the joint applications the student was invited to
+func (db Client) GetTeamInvites(ctx context.Context, studentID string) ([]model.TeamInvite, error) {
+	query := `SELECT a.id, a.heading, a.student_id, u.name, a.status, t.status, t.invited_at
+FROM application_team t INNER JOIN applications a ON a.id = t.application_id INNER JOIN users u ON u.id = a.student_id
+WHERE t.student_id = $1
+ORDER BY t.invited_at DESC`
+	rows, err := db.conn.QueryContext(ctx, query, studentID)
+	if err != nil {
+		log.Printf("cannot execute query to get team invitations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.TeamInvite{}
+	for rows.Next() {
+		var invite model.TeamInvite
+		err = rows.Scan(&invite.ApplicationID, &invite.Heading, &invite.ApplicantID, &invite.ApplicantName, &invite.ApplicationStatus, &invite.Status, &invite.InvitedAt)
+		if err != nil {
+			log.Printf("cannot read data while getting team invitations: %v", err)
+			return nil, err
+		}
+		invite.InvitedAt = invite.InvitedAt.UTC()
+		result = append(result, invite)
+	}
+	return result, nil
+}
+
+// GetApplicationTeam lists the invited teammates of an application to its student, supervisor and teammates
+func (db Client) GetApplicationTeam(ctx context.Context, appID string, userID string) ([]model.TeamMember, error) {
+	query := `SELECT t.student_id, u.name, t.status FROM application_team t INNER JOIN users u ON u.id = t.student_id
+WHERE t.application_id = $1 ORDER BY t.invited_at, u.name`
+	rows, err := db.conn.QueryContext(ctx, query, appID)
+	if err != nil {
+		log.Printf("cannot execute query to get application team: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.TeamMember{}
+	teammate := false
+	for rows.Next() {
+		var member model.TeamMember
+		if err = rows.Scan(&member.StudentID, &member.Name, &member.Status); err != nil {
+			log.Printf("cannot read data while getting application team: %v", err)
+			return nil, err
+		}
+		teammate = teammate || member.StudentID == userID
+		result = append(result, member)
+	}
+	if !teammate {
+		if _, err = applicationParticipant(ctx, db.conn, appID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetMyProjects returns every project the user takes part in, newest first
+func (db Client) GetMyProjects(ctx context.Context, userID string) ([]model.ProjectData, error) {
+	query := `SELECT p.project_id, p.project_name, p.student_id, p.supervisor_id, coalesce(p.proposal_id::text, ''), m.role
+FROM project_members m INNER JOIN projects p ON p.project_id = m.project_id
+WHERE m.user_id = $1
+ORDER BY p.created_at DESC, p.project_id`
+	rows, err := db.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		log.Printf("cannot execute query to get projects: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.ProjectData{}
+	var projectIDs []string
+	for rows.Next() {
+		var project model.ProjectData
+		err = rows.Scan(&project.ID, &project.Name, &project.StudentID, &project.SupervisorID, &project.ProposalID, &project.Role)
+		if err != nil {
+			log.Printf("cannot read data while getting projects: %v", err)
+			return nil, err
+		}
+		result = append(result, project)
+		projectIDs = append(projectIDs, project.ID)
+	}
+	rows.Close()
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	members, err := db.projectMembers(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].Members = members[result[i].ID]
+	}
+	return result, nil
+}
+
+// SetGanttContributions replaces the contributions recorded on a gantt item, every contributor must be a
+// student of the project and the shares add up to at most 100
+func (db Client) SetGanttContributions(ctx context.Context, itemID string, userID string, contributions []Contribution, version int) (int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to set contributions: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	projectID, _, _, err := lockGanttItem(ctx, tx, itemID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, contribution := range contributions {
+		role, err := projectRole(ctx, tx, projectID, contribution.UserID)
+		if errors.Is(err, ErrNotProjectMember) || (err == nil && role != RoleStudent) {
+			return 0, fmt.Errorf("%w: %s is not a student of the project", ErrInvalidContribution, contribution.UserID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += contribution.Share
+	}
+	if total > 100 {
+		return 0, fmt.Errorf("%w: the shares add up to %d%%", ErrInvalidContribution, total)
+	}
+	version, err = bumpGanttVersion(ctx, tx, itemID, version)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM gantt_item_contributions WHERE item_id = $1", itemID); err != nil {
+		log.Printf("failed to clear contributions: %v", err)
+		return 0, err
+	}
+	for _, contribution := range contributions {
+		_, err = tx.ExecContext(ctx, "INSERT INTO gantt_item_contributions (item_id, user_id, share, note) VALUES ($1, $2, $3, $4)",
+			itemID, contribution.UserID, contribution.Share, contribution.Note)
+		if err != nil {
+			log.Printf("failed to add contribution: %v", err)
+			return 0, err
+		}
+	}
+	return version, tx.Commit()
+}
+
+// ganttContributions returns the contributions to the items of a project by item
+func (db Client) ganttContributions(ctx context.Context, projectID string) (map[string][]model.Contribution, error) {
+	query := `SELECT c.item_id, c.user_id, u.name, c.share, c.note
+FROM gantt_item_contributions c INNER JOIN gantt_items g ON g.item_id = c.item_id INNER JOIN users u ON u.id = c.user_id
+WHERE g.project_id = $1 ORDER BY c.share DESC, u.name`
+	rows, err := db.conn.QueryContext(ctx, query, projectID)
+	if err != nil {
+		log.Printf("cannot execute query to get contributions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string][]model.Contribution{}
+	for rows.Next() {
+		var (
+			itemID       string
+			contribution model.Contribution
+		)
+		if err = rows.Scan(&itemID, &contribution.UserID, &contribution.Name, &contribution.Share, &contribution.Note); err != nil {
+			log.Printf("cannot read data while getting contributions: %v", err)
+			return nil, err
+		}
+		result[itemID] = append(result[itemID], contribution)
+	}
+	return result, nil
+}
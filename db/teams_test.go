@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_OfferWithOpenTeamInvites(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	mock.ExpectBegin()
+	expectLockApplication(mock, "shortlisted")
+	mock.ExpectQuery(`SELECT count\(\*\) FROM application_team WHERE application_id = \$1 AND status = 'invited'`).
+		WithArgs("app-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err = d.TransitionApplication(context.Background(), "app-1", lifecycle.Offered, "supervisor-1", "")
+	assert.ErrorIs(t, err, ErrTeamIncomplete)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_RespondTeamInvite(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	mock.ExpectBegin()
+	expectLockApplication(mock, "submitted")
+	mock.ExpectExec(`UPDATE application_team SET status = \$1, responded_at = now\(\) WHERE application_id = \$2 AND student_id = \$3 AND status = 'invited'`).
+		WithArgs("confirmed", "app-1", "student-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "student-1", "team_invite_answered", `A student joined your team for "Heading"`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = d.RespondTeamInvite(context.Background(), "app-1", "student-2", true)
+	assert.Nil(t, err)
+
+	mock.ExpectBegin()
+	expectLockApplication(mock, "submitted")
+	mock.ExpectExec(`UPDATE application_team SET status = \$1`).
+		WithArgs("declined", "app-1", "student-3").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = d.RespondTeamInvite(context.Background(), "app-1", "student-3", false)
+	assert.ErrorIs(t, err, ErrInviteNotFound)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_SetGanttContributionsRejectsStaff(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	d := &Client{
+		conn: db,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT project_id, gantt_name FROM gantt_items WHERE item_id = \$1 FOR UPDATE`).
+		WithArgs("item-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "gantt_name"}).AddRow("project-1", "Prototype"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "student-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("student"))
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "supervisor-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("primary_supervisor"))
+	mock.ExpectRollback()
+
+	_, err = d.SetGanttContributions(context.Background(), "item-1", "student-1", []Contribution{
+		{UserID: "student-1", Share: 60},
+		{UserID: "supervisor-1", Share: 40},
+	}, 0)
+	assert.ErrorIs(t, err, ErrInvalidContribution)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
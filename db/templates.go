@@ -0,0 +1,478 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log"
+	"slices"
+	"time"
+)
+
+// ErrTemplateNotFound is returned when the milestone template does not exist or the project has no template
+var ErrTemplateNotFound = errors.New("milestone template not found")
+
+// ErrTemplateNameTaken is returned when another milestone template has the same name
+var ErrTemplateNameTaken = errors.New("a milestone template with this name already exists")
+
+// ErrTemplateMilestoneNotFound is returned when an update keeps a milestone that is not part of the template
+var ErrTemplateMilestoneNotFound = errors.New("milestone is not part of the template")
+
+// ErrTemplateFollowDenied is returned when a member other than the primary supervisor changes whether the project
+// follows its template
+var ErrTemplateFollowDenied = errors.New("only the primary supervisor can choose whether the project follows its template")
+
+// templateStart and templateEnd place a milestone the given number of days after $2, the start of the academic
+// year. Days are counted in UTC like academic.Start, in the session time zone they would shift by an hour across
+// daylight saving changes.
+const (
+	templateStart = "($2::timestamptz AT TIME ZONE 'UTC' + m.start_day * interval '1 day') AT TIME ZONE 'UTC'"
+	templateEnd   = "($2::timestamptz AT TIME ZONE 'UTC' + m.end_day * interval '1 day') AT TIME ZONE 'UTC'"
+)
+
+// templateItemsQuery creates the gantt items of template milestones in a project. $1 is the project, $2 the start of
+// its academic year, $3 the status of new items and $4 picks the milestones.
+const templateItemsQuery = `INSERT INTO gantt_items (item_id, project_id, gantt_name, start_date, end_date, description, links, deadline_locked, status, template_milestone_id)
+SELECT gen_random_uuid(), $1, m.name, ` + templateStart + `, ` + templateEnd + `,
+    m.description, m.links, m.deadline_locked, $3, m.id
+FROM template_milestones m
+WHERE `
+
+// templateError translates constraint violations of the templates table
+func templateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { //unique_violation
+		return ErrTemplateNameTaken
+	}
+	return err
+}
+
+// claimDefault makes the template the only default template
+func claimDefault(ctx context.Context, tx *sql.Tx, templateID string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE milestone_templates SET is_default = false WHERE is_default AND id <> $1", templateID)
+	if err != nil {
+		log.Printf("failed to clear the default milestone template: %v", err)
+	}
+	return err
+}
+
+func insertTemplateMilestone(ctx context.Context, tx *sql.Tx, templateID string, position int, milestone TemplateMilestone) (string, error) {
+	id := GenerateUUID()
+	query := `INSERT INTO template_milestones (id, template_id, position, name, description, links, start_day, end_day, deadline_locked)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := tx.ExecContext(ctx, query, id, templateID, position, milestone.Name, milestone.Description, milestone.Links, milestone.StartDay, milestone.EndDay,
+		milestone.DeadlineLocked)
+	if err != nil {
+		log.Printf("failed to add template milestone: %v", err)
+		return "", err
+	}
+	return id, nil
+}
+
+func (db Client) CreateMilestoneTemplate(ctx context.Context, template MilestoneTemplate, coordinatorID string) (string, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to create milestone template: %v", err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	id := GenerateUUID()
+	if template.IsDefault {
+		if err = claimDefault(ctx, tx, id); err != nil {
+			return "", err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO milestone_templates (id, name, is_default) VALUES ($1, $2, $3)", id, template.Name, template.IsDefault)
+	if err != nil {
+		log.Printf("failed to create milestone template: %v", err)
+		return "", templateError(err)
+	}
+	for i, milestone := range template.Milestones {
+		if _, err = insertTemplateMilestone(ctx, tx, id, i, milestone); err != nil {
+			return "", err
+		}
+	}
+	if err = audit(ctx, tx, coordinatorID, "milestone_template_created", id, template.Name); err != nil {
+		return "", err
+	}
+	return id, tx.Commit()
+}
+
+func (db Client) GetMilestoneTemplates(ctx context.Context) ([]model.MilestoneTemplate, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT id, name, is_default FROM milestone_templates ORDER BY name, id")
+	if err != nil {
+		log.Printf("cannot execute query to get milestone templates: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.MilestoneTemplate{}
+	positions := map[string]int{}
+	for rows.Next() {
+		template := model.MilestoneTemplate{Milestones: []model.TemplateMilestone{}}
+		if err = rows.Scan(&template.ID, &template.Name, &template.IsDefault); err != nil {
+			log.Printf("cannot read data while getting milestone templates: %v", err)
+			return nil, err
+		}
+		positions[template.ID] = len(result)
+		result = append(result, template)
+	}
+	rows.Close()
+
+	rows, err = db.conn.QueryContext(ctx, `SELECT template_id, id, name, description, links, start_day, end_day, deadline_locked
+FROM template_milestones ORDER BY template_id, position`)
+	if err != nil {
+		log.Printf("cannot execute query to get template milestones: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			templateID string
+			milestone  model.TemplateMilestone
+		)
+		err = rows.Scan(&templateID, &milestone.ID, &milestone.Name, &milestone.Description, &milestone.Links, &milestone.StartDay, &milestone.EndDay,
+			&milestone.DeadlineLocked)
+		if err != nil {
+			log.Printf("cannot read data while getting template milestones: %v", err)
+			return nil, err
+		}
+		if i, ok := positions[templateID]; ok {
+			result[i].Milestones = append(result[i].Milestones, milestone)
+		}
+	}
+	return result, nil
+}
+
+// UpdateMilestoneTemplate replaces the name and the milestones of a template. Milestones that keep their id are
+// changed in place, the others are added and milestones left out are removed from the template. Projects that
+// follow the template take the changes over, their items of changed milestones are overwritten and added
+// milestones become new items. Items that were edited in the project and items of removed milestones are kept.
+func (db Client) UpdateMilestoneTemplate(ctx context.Context, template MilestoneTemplate, coordinatorID string) (*model.TemplateUpdate, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to update milestone template: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT m.id, coalesce(m.name, ''), coalesce(m.description, ''), coalesce(m.links, ''), coalesce(m.start_day, 0), coalesce(m.end_day, 0),
+    coalesce(m.deadline_locked, false)
+FROM milestone_templates t LEFT JOIN template_milestones m ON m.template_id = t.id
+WHERE t.id = $1 FOR UPDATE OF t`, template.ID)
+	if err != nil {
+		log.Printf("cannot execute query to get milestone template: %v", err)
+		return nil, err
+	}
+	found := false
+	current := map[string]TemplateMilestone{}
+	for rows.Next() {
+		found = true
+		var (
+			id        sql.NullString
+			milestone TemplateMilestone
+		)
+		err = rows.Scan(&id, &milestone.Name, &milestone.Description, &milestone.Links, &milestone.StartDay, &milestone.EndDay, &milestone.DeadlineLocked)
+		if err != nil {
+			rows.Close()
+			log.Printf("cannot read data while getting milestone template: %v", err)
+			return nil, err
+		}
+		if id.Valid {
+			milestone.ID = id.String
+			current[id.String] = milestone
+		}
+	}
+	rows.Close()
+	if !found {
+		return nil, ErrTemplateNotFound
+	}
+
+	if template.IsDefault {
+		if err = claimDefault(ctx, tx, template.ID); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE milestone_templates SET name = $1, is_default = $2, updated_at = now() WHERE id = $3", template.Name, template.IsDefault, template.ID)
+	if err != nil {
+		log.Printf("failed to update milestone template: %v", err)
+		return nil, templateError(err)
+	}
+
+	kept := []string{} //sent as an empty array when every milestone is removed, NULL would keep them all
+	var added []string
+	previous := map[string]TemplateMilestone{} //content of the changed milestones before the update
+	for i, milestone := range template.Milestones {
+		if milestone.ID == "" {
+			id, err := insertTemplateMilestone(ctx, tx, template.ID, i, milestone)
+			if err != nil {
+				return nil, err
+			}
+			kept, added = append(kept, id), append(added, id)
+			continue
+		}
+		before, ok := current[milestone.ID]
+		if !ok || slices.Contains(kept, milestone.ID) {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateMilestoneNotFound, milestone.ID)
+		}
+		kept = append(kept, milestone.ID)
+		query := `UPDATE template_milestones SET position = $1, name = $2, description = $3, links = $4, start_day = $5, end_day = $6, deadline_locked = $7
+WHERE id = $8`
+		_, err = tx.ExecContext(ctx, query, i, milestone.Name, milestone.Description, milestone.Links, milestone.StartDay, milestone.EndDay, milestone.DeadlineLocked,
+			milestone.ID)
+		if err != nil {
+			log.Printf("failed to update template milestone: %v", err)
+			return nil, err
+		}
+		if before != milestone {
+			previous[milestone.ID] = before
+		}
+	}
+	removed := len(current) - (len(kept) - len(added))
+	if removed > 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM template_milestones WHERE template_id = $1 AND NOT (id::text = ANY($2))", template.ID, kept)
+		if err != nil {
+			log.Printf("failed to remove template milestones: %v", err)
+			return nil, err
+		}
+	}
+
+	update := &model.TemplateUpdate{Skipped: []model.SkippedTemplateItem{}}
+	if len(previous) > 0 || len(added) > 0 {
+		if update, err = propagateTemplate(ctx, tx, template, previous, added, coordinatorID); err != nil {
+			return nil, err
+		}
+	}
+	detail := fmt.Sprintf("%d milestones changed, %d added, %d removed, %d projects updated, %d edited items kept", len(previous), len(added), removed,
+		update.ProjectsUpdated, len(update.Skipped))
+	if err = audit(ctx, tx, coordinatorID, "milestone_template_updated", template.ID, detail); err != nil {
+		return nil, err
+	}
+	return update, tx.Commit()
+}
+
+// propagateTemplate overwrites the items of changed milestones and adds the new milestones in every project that
+// follows the template, members of the projects that changed are notified. An item only takes the change over while
+// it still has the previous content of its milestone, items edited in the project are kept and reported.
+func propagateTemplate(ctx context.Context, tx *sql.Tx, template MilestoneTemplate, previous map[string]TemplateMilestone, added []string,
+	coordinatorID string) (*model.TemplateUpdate, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT project_id, academic_year FROM projects WHERE template_id = $1 AND follow_template ORDER BY project_id FOR UPDATE", template.ID)
+	if err != nil {
+		log.Printf("cannot execute query to get projects following the template: %v", err)
+		return nil, err
+	}
+	type following struct {
+		id   string
+		year string
+	}
+	var projects []following
+	for rows.Next() {
+		var project following
+		if err = rows.Scan(&project.id, &project.year); err != nil {
+			rows.Close()
+			log.Printf("cannot read data while getting projects following the template: %v", err)
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	rows.Close()
+
+	changed := make([]string, 0, len(previous))
+	for id := range previous {
+		changed = append(changed, id)
+	}
+	slices.Sort(changed)
+
+	update := &model.TemplateUpdate{Skipped: []model.SkippedTemplateItem{}}
+	for _, project := range projects {
+		start, err := academic.Start(project.year)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			affected  int64
+			unchanged []string
+			skipped   int
+		)
+		if len(changed) > 0 {
+			unchanged, skipped, err = uneditedTemplateItems(ctx, tx, project.id, start, previous, changed, update)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(unchanged) > 0 {
+			query := `UPDATE gantt_items g SET gantt_name = m.name, description = m.description, links = m.links,
+    start_date = ` + templateStart + `, end_date = ` + templateEnd + `,
+    deadline_locked = m.deadline_locked, version = g.version + 1
+FROM template_milestones m
+WHERE m.id = g.template_milestone_id AND g.project_id = $1 AND g.item_id::text = ANY($3)`
+			result, err := tx.ExecContext(ctx, query, project.id, start, unchanged)
+			if err != nil {
+				log.Printf("failed to update template items: %v", err)
+				return nil, err
+			}
+			rowsAffected, _ := result.RowsAffected()
+			affected += rowsAffected
+		}
+		if len(added) > 0 {
+			result, err := tx.ExecContext(ctx, templateItemsQuery+"m.id::text = ANY($4)", project.id, start, GanttOnTrack, added)
+			if err != nil {
+				log.Printf("failed to add template items: %v", err)
+				return nil, err
+			}
+			rowsAffected, _ := result.RowsAffected()
+			affected += rowsAffected
+		}
+		if affected == 0 && skipped == 0 {
+			continue
+		}
+		message := fmt.Sprintf("The milestones of \"%s\" changed, your gantt chart was updated", template.Name)
+		if affected == 0 {
+			message = fmt.Sprintf("The milestones of \"%s\" changed, your gantt chart was not updated", template.Name)
+		} else {
+			update.ProjectsUpdated++
+		}
+		if skipped > 0 {
+			message += fmt.Sprintf(", %d edited items were kept", skipped)
+		}
+		if err = notifyProjectMembers(ctx, tx, project.id, coordinatorID, "milestone_template_updated", message); err != nil {
+			return nil, err
+		}
+	}
+	return update, nil
+}
+
+// uneditedTemplateItems returns the items of the changed milestones in the project that still have the previous
+// content of their milestone. Edited items are added to the skipped items of the update.
+func uneditedTemplateItems(ctx context.Context, tx *sql.Tx, projectID string, start time.Time, previous map[string]TemplateMilestone, changed []string,
+	update *model.TemplateUpdate) ([]string, int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT item_id, template_milestone_id, gantt_name, description, links, start_date, end_date, deadline_locked
+FROM gantt_items WHERE project_id = $1 AND template_milestone_id::text = ANY($2) ORDER BY item_id FOR UPDATE`, projectID, changed)
+	if err != nil {
+		log.Printf("cannot execute query to get template items: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		unchanged []string
+		skipped   int
+	)
+	for rows.Next() {
+		var (
+			itemID, milestoneID string
+			item                ganttFields
+		)
+		err = rows.Scan(&itemID, &milestoneID, &item.name, &item.description, &item.links, &item.startDate, &item.endDate, &item.deadlineLocked)
+		if err != nil {
+			log.Printf("cannot read data while getting template items: %v", err)
+			return nil, 0, err
+		}
+		before := previous[milestoneID]
+		if item.name == before.Name && item.description == before.Description && item.links == before.Links &&
+			item.startDate.Equal(start.AddDate(0, 0, before.StartDay)) && item.endDate.Equal(start.AddDate(0, 0, before.EndDay)) &&
+			item.deadlineLocked == before.DeadlineLocked {
+			unchanged = append(unchanged, itemID)
+			continue
+		}
+		skipped++
+		update.Skipped = append(update.Skipped, model.SkippedTemplateItem{ProjectID: projectID, ItemID: itemID, GanttName: item.name})
+	}
+	return unchanged, skipped, rows.Err()
+}
+
+// DeleteMilestoneTemplate removes a template, the items it seeded stay in the projects
+func (db Client) DeleteMilestoneTemplate(ctx context.Context, templateID string, coordinatorID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("cannot start transaction to delete milestone template: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM milestone_templates WHERE id = $1", templateID)
+	if err != nil {
+		log.Printf("failed to delete milestone template: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	if err = audit(ctx, tx, coordinatorID, "milestone_template_deleted", templateID, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// seedTemplate gives a new project the milestones of a template, the default template when templateID is empty.
+// Items seeded from another template before are replaced. Without a default template nothing is seeded.
+func seedTemplate(ctx context.Context, tx *sql.Tx, projectID string, templateID string, follow bool) error {
+	var year string
+	if err := tx.QueryRowContext(ctx, "SELECT academic_year FROM projects WHERE project_id = $1", projectID).Scan(&year); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProjectNotFound
+		}
+		log.Printf("cannot read project: %v", err)
+		return err
+	}
+	query := "SELECT id FROM milestone_templates WHERE id = $1"
+	args := []any{templateID}
+	if templateID == "" {
+		query, args = "SELECT id FROM milestone_templates WHERE is_default", nil
+	}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&templateID); err != nil {
+		if err == sql.ErrNoRows {
+			if len(args) == 0 {
+				return nil
+			}
+			return ErrTemplateNotFound
+		}
+		log.Printf("cannot read milestone template: %v", err)
+		return err
+	}
+	start, err := academic.Start(year)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM gantt_items WHERE project_id = $1 AND template_milestone_id IS NOT NULL", projectID); err != nil {
+		log.Printf("failed to remove template items: %v", err)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE projects SET template_id = $1, follow_template = $2 WHERE project_id = $3", templateID, follow, projectID)
+	if err != nil {
+		log.Printf("failed to set the template of the project: %v", err)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, templateItemsQuery+"m.template_id = $4", projectID, start, GanttOnTrack, templateID); err != nil {
+		log.Printf("failed to seed template items: %v", err)
+		return err
+	}
+	return nil
+}
+
+// SetTemplateFollow lets the primary supervisor choose whether the project takes over later changes to its template
+func (db Client) SetTemplateFollow(ctx context.Context, projectID string, userID string, follow bool) error {
+	role, err := projectRole(ctx, db.conn, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != RolePrimarySupervisor {
+		return ErrTemplateFollowDenied
+	}
+	result, err := db.conn.ExecContext(ctx, "UPDATE projects SET follow_template = $1 WHERE project_id = $2 AND template_id IS NOT NULL", follow, projectID)
+	if err != nil {
+		log.Printf("failed to set whether the project follows its template: %v", err)
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClient_UpdateMilestoneTemplatePropagates(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM milestone_templates t LEFT JOIN template_milestones m ON m.template_id = t.id WHERE t.id = \$1 FOR UPDATE OF t`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "links", "start_day", "end_day", "deadline_locked"}).
+			AddRow("proposal", "Proposal", "", "", 21, 35, true).
+			AddRow("poster", "Poster", "", "", 200, 210, true))
+	mock.ExpectExec(`UPDATE milestone_templates SET name = \$1, is_default = \$2, updated_at = now\(\) WHERE id = \$3`).
+		WithArgs("Honours", false, "template-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE template_milestones SET position = \$1`).
+		WithArgs(0, "Proposal", "", "", 21, 42, true, "proposal").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO template_milestones`).
+		WithArgs(sqlmock.AnyArg(), "template-1", 1, "Final report", "", "", 220, 240, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM template_milestones WHERE template_id = \$1 AND NOT \(id::text = ANY\(\$2\)\)`).
+		WithArgs("template-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT project_id, academic_year FROM projects WHERE template_id = \$1 AND follow_template`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "academic_year"}).AddRow("project-1", "2024/25"))
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM gantt_items WHERE project_id = \$1 AND template_milestone_id::text = ANY\(\$2\)`).
+		WithArgs("project-1", []string{"proposal"}).
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "template_milestone_id", "gantt_name", "description", "links", "start_date", "end_date", "deadline_locked"}).
+			AddRow("item-1", "proposal", "Proposal", "", "", start.AddDate(0, 0, 21), start.AddDate(0, 0, 35), true).
+			AddRow("item-2", "proposal", "Proposal (with ethics form)", "", "", start.AddDate(0, 0, 21), start.AddDate(0, 0, 35), true))
+	mock.ExpectExec(`AND g.item_id::text = ANY\(\$3\)`).
+		WithArgs("project-1", start, []string{"item-1"}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO gantt_items .* WHERE m.id::text = ANY\(\$4\)`).
+		WithArgs("project-1", start, GanttOnTrack, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM project_members WHERE project_id = \$1 AND user_id <> \$2`).
+		WithArgs("project-1", "coordinator-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("student-1"))
+	mock.ExpectExec(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), "student-1", "milestone_template_updated", `The milestones of "Honours" changed, your gantt chart was updated, 1 edited items were kept`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "coordinator-1", "milestone_template_updated", "template-1", "1 milestones changed, 1 added, 1 removed, 1 projects updated, 1 edited items kept").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	update, err := d.UpdateMilestoneTemplate(context.Background(), MilestoneTemplate{
+		ID:   "template-1",
+		Name: "Honours",
+		Milestones: []TemplateMilestone{
+			{ID: "proposal", Name: "Proposal", StartDay: 21, EndDay: 42, DeadlineLocked: true},
+			{Name: "Final report", StartDay: 220, EndDay: 240, DeadlineLocked: true},
+		},
+	}, "coordinator-1")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.TemplateUpdate{
+		ProjectsUpdated: 1,
+		Skipped:         []model.SkippedTemplateItem{{ProjectID: "project-1", ItemID: "item-2", GanttName: "Proposal (with ethics form)"}},
+	}, update)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_UpdateMilestoneTemplateRejectsUnknownMilestone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM milestone_templates t LEFT JOIN template_milestones m`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "links", "start_day", "end_day", "deadline_locked"}).
+			AddRow(nil, "", "", "", 0, 0, false))
+	mock.ExpectExec(`UPDATE milestone_templates SET name`).
+		WithArgs("Honours", false, "template-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	d := &Client{
+		conn: db,
+	}
+
+	_, err = d.UpdateMilestoneTemplate(context.Background(), MilestoneTemplate{
+		ID:         "template-1",
+		Name:       "Honours",
+		Milestones: []TemplateMilestone{{ID: "poster", Name: "Poster", StartDay: 200, EndDay: 210}},
+	}, "coordinator-1")
+	assert.ErrorIs(t, err, ErrTemplateMilestoneNotFound)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_SetTemplateFollowRequiresPrimarySupervisor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT m.role FROM projects p`).
+		WithArgs("project-1", "reader-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("second_reader"))
+
+	d := &Client{
+		conn: db,
+	}
+
+	err = d.SetTemplateFollow(context.Background(), "project-1", "reader-1", true)
+	assert.ErrorIs(t, err, ErrTemplateFollowDenied)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_UpdateMilestoneTemplateRemovesAllMilestones(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM milestone_templates t LEFT JOIN template_milestones m`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "links", "start_day", "end_day", "deadline_locked"}).
+			AddRow("proposal", "Proposal", "", "", 21, 35, true).
+			AddRow("poster", "Poster", "", "", 200, 210, true))
+	mock.ExpectExec(`UPDATE milestone_templates SET name`).
+		WithArgs("Honours", false, "template-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM template_milestones WHERE template_id = \$1 AND NOT \(id::text = ANY\(\$2\)\)`).
+		WithArgs("template-1", []string{}).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "coordinator-1", "milestone_template_updated", "template-1", "0 milestones changed, 0 added, 2 removed, 0 projects updated, 0 edited items kept").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	update, err := d.UpdateMilestoneTemplate(context.Background(), MilestoneTemplate{ID: "template-1", Name: "Honours"}, "coordinator-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, update.ProjectsUpdated)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestClient_UpdateMilestoneTemplateAfterClockChange(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Skip("time zone data is not available")
+	}
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM milestone_templates t LEFT JOIN template_milestones m`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "links", "start_day", "end_day", "deadline_locked"}).
+			AddRow("review", "Interim review", "", "", 60, 65, true))
+	mock.ExpectExec(`UPDATE milestone_templates SET name`).
+		WithArgs("Honours", false, "template-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE template_milestones SET position = \$1`).
+		WithArgs(0, "Interim review", "", "", 60, 70, true, "review").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT project_id, academic_year FROM projects WHERE template_id = \$1 AND follow_template`).
+		WithArgs("template-1").
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "academic_year"}).AddRow("project-1", "2024/25"))
+	// the items are read back in the time zone of the server, after the clocks went back on 27 october
+	mock.ExpectQuery(`FROM gantt_items WHERE project_id = \$1 AND template_milestone_id::text = ANY\(\$2\)`).
+		WithArgs("project-1", []string{"review"}).
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "template_milestone_id", "gantt_name", "description", "links", "start_date", "end_date", "deadline_locked"}).
+			AddRow("item-1", "review", "Interim review", "", "", start.AddDate(0, 0, 60).In(dublin), start.AddDate(0, 0, 65).In(dublin), true))
+	mock.ExpectExec(`start_date = \(\$2::timestamptz AT TIME ZONE 'UTC' \+ m.start_day \* interval '1 day'\) AT TIME ZONE 'UTC'`).
+		WithArgs("project-1", start, []string{"item-1"}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM project_members`).
+		WithArgs("project-1", "coordinator-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(sqlmock.AnyArg(), "coordinator-1", "milestone_template_updated", "template-1", "1 milestones changed, 0 added, 0 removed, 1 projects updated, 0 edited items kept").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d := &Client{
+		conn: db,
+	}
+
+	update, err := d.UpdateMilestoneTemplate(context.Background(), MilestoneTemplate{
+		ID:         "template-1",
+		Name:       "Honours",
+		Milestones: []TemplateMilestone{{ID: "review", Name: "Interim review", StartDay: 60, EndDay: 70, DeadlineLocked: true}},
+	}, "coordinator-1")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.TemplateUpdate{ProjectsUpdated: 1, Skipped: []model.SkippedTemplateItem{}}, update)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,64 @@
+// Package expiry periodically expires applications that have been waiting for too long
+package expiry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Store is implemented by the database client
+type Store interface {
+	ExpireStaleApplications(ctx context.Context, pendingExpiryDays int, offerExpiryDays int, limit int) (int, error)
+}
+
+// Sweeper expires stale applications in batches, the expiry windows are used for rounds without their own
+type Sweeper struct {
+	store             Store
+	interval          time.Duration
+	pendingExpiryDays int
+	offerExpiryDays   int
+	batchSize         int
+}
+
+func New(store Store, interval time.Duration, pendingExpiryDays int, offerExpiryDays int) *Sweeper {
+	return &Sweeper{
+		store:             store,
+		interval:          interval,
+		pendingExpiryDays: pendingExpiryDays,
+		offerExpiryDays:   offerExpiryDays,
+		batchSize:         100,
+	}
+}
+
+// Run sweeps right away and then on every interval until the context is cancelled
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		expired, err := s.Sweep(ctx)
+		if err != nil {
+			slog.Error("cannot expire stale applications", "error", err)
+		} else if expired > 0 {
+			slog.Info("expired stale applications", "count", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep expires batches until no stale application is left and returns how many were expired
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		expired, err := s.store.ExpireStaleApplications(ctx, s.pendingExpiryDays, s.offerExpiryDays, s.batchSize)
+		total += expired
+		if err != nil || expired < s.batchSize {
+			return total, err
+		}
+	}
+}
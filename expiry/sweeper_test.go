@@ -0,0 +1,63 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	batches []int
+	err     error
+	calls   int
+	limit   int
+}
+
+func (f *fakeStore) ExpireStaleApplications(ctx context.Context, pendingExpiryDays int, offerExpiryDays int, limit int) (int, error) {
+	f.limit = limit
+	if f.calls == len(f.batches) {
+		return 0, f.err
+	}
+	f.calls++
+	return f.batches[f.calls-1], nil
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	store := &fakeStore{batches: []int{2, 2, 1}}
+	sweeper := New(store, time.Hour, 30, 7)
+	sweeper.batchSize = 2
+
+	expired, err := sweeper.Sweep(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 5, expired)
+	assert.Equal(t, 3, store.calls)
+	assert.Equal(t, 2, store.limit)
+}
+
+func TestSweeper_SweepStopsOnError(t *testing.T) {
+	store := &fakeStore{batches: []int{2}, err: errors.New("connection lost")}
+	sweeper := New(store, time.Hour, 30, 7)
+	sweeper.batchSize = 2
+
+	expired, err := sweeper.Sweep(context.Background())
+	assert.EqualError(t, err, "connection lost")
+	assert.Equal(t, 2, expired)
+}
+
+func TestSweeper_RunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		New(&fakeStore{}, time.Hour, 30, 7).Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not stop")
+	}
+}
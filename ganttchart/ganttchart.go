@@ -0,0 +1,312 @@
+// Package ganttchart draws the gantt chart of a project as SVG or PDF for progress reports. The drawing only depends
+// on the chart, the same chart always gives the same bytes.
+package ganttchart
+
+import (
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"time"
+)
+
+// Item is a bar of the chart, an item that ends when it starts is drawn as a milestone
+type Item struct {
+	ID              string
+	Name            string
+	Start           time.Time
+	End             time.Time
+	Colour          string // fill of the bar as #RRGGBB, such as the colour of its status
+	PercentComplete int
+	Dependencies    []Dependency
+}
+
+// Dependency is drawn as an arrow from the item the bar waits for
+type Dependency struct {
+	PredecessorID string
+	Type          schedule.DependencyType
+}
+
+// LegendEntry explains a bar colour
+type LegendEntry struct {
+	Label  string
+	Colour string
+}
+
+// Chart is drawn in UTC on a page as wide as A4 landscape, it grows downwards with the number of items
+type Chart struct {
+	Title  string
+	Today  time.Time // drawn as a dashed line when it is within the chart, zero for none
+	Items  []Item
+	Legend []LegendEntry
+}
+
+const (
+	pageWidth    = 842.0 // points, the SVG uses the same units
+	margin       = 24.0
+	labelWidth   = 170.0
+	headerHeight = 72.0 // title, subtitle and the time axis
+	rowHeight    = 20.0
+	barHeight    = 10.0
+	footerHeight = 52.0 // legend and bottom margin
+	titleSize    = 14.0
+	textSize     = 8.0
+
+	textColour       = "#222222"
+	mutedColour      = "#666666"
+	gridColour       = "#DDDDDD"
+	stripeColour     = "#F4F4F4"
+	progressColour   = "#1D3557"
+	dependencyColour = "#555555"
+	todayColour      = "#D62828"
+	defaultColour    = "#999999"
+)
+
+const day = 24 * time.Hour
+
+type point struct {
+	x, y float64
+}
+
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// canvas is what the chart is drawn on, y grows downwards
+type canvas interface {
+	rect(x, y, w, h float64, fill string)
+	line(from, to point, stroke string, width float64, dashed bool)
+	polyline(points []point, stroke string, width float64)
+	polygon(points []point, fill string)
+	text(at point, size float64, bold bool, colour string, anchor anchor, value string)
+}
+
+func (c Chart) size() (float64, float64) {
+	rows := max(len(c.Items), 1)
+	return pageWidth, headerHeight + float64(rows)*rowHeight + footerHeight
+}
+
+// timeRange covers every item in whole days and at least a week, with a day to spare for milestones at the end
+func (c Chart) timeRange() (time.Time, time.Time) {
+	var start, end time.Time
+	for i, item := range c.Items {
+		if i == 0 || item.Start.Before(start) {
+			start = item.Start
+		}
+		if i == 0 || item.End.After(end) {
+			end = item.End
+		}
+	}
+	if len(c.Items) == 0 {
+		start, end = c.Today, c.Today
+	}
+	start = start.UTC().Truncate(day)
+	end = end.UTC().Truncate(day).Add(day)
+	if end.Sub(start) < 7*day {
+		end = start.Add(7 * day)
+	}
+	return start, end
+}
+
+// ticks are the dates labelled on the time axis, days for short plans, Mondays for a few months, else months
+func ticks(start time.Time, end time.Time) ([]time.Time, string) {
+	span := end.Sub(start)
+	var (
+		result []time.Time
+		layout = "2 Jan"
+	)
+	switch {
+	case span <= 21*day:
+		for t := start; !t.After(end); t = t.Add(day) {
+			result = append(result, t)
+		}
+	case span <= 140*day:
+		t := start.Add(time.Duration((8-int(start.Weekday()))%7) * day)
+		for ; !t.After(end); t = t.Add(7 * day) {
+			result = append(result, t)
+		}
+	default:
+		layout = "Jan 2006"
+		t := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if t.Before(start) {
+			t = t.AddDate(0, 1, 0)
+		}
+		for ; !t.After(end); t = t.AddDate(0, 1, 0) {
+			result = append(result, t)
+		}
+	}
+	return result, layout
+}
+
+func (c Chart) draw(cv canvas) {
+	width, height := c.size()
+	left, right := margin+labelWidth, width-margin
+	bodyTop := headerHeight
+	bodyBottom := height - footerHeight
+	start, end := c.timeRange()
+	x := func(t time.Time) float64 {
+		return left + float64(t.Sub(start))/float64(end.Sub(start))*(right-left)
+	}
+
+	cv.text(point{margin, margin + titleSize}, titleSize, true, textColour, anchorStart, c.Title)
+	subtitle := fmt.Sprintf("%d items", len(c.Items))
+	if !c.Today.IsZero() {
+		subtitle = "Plan as of " + c.Today.UTC().Format("2 Jan 2006") + ", " + subtitle
+	}
+	cv.text(point{margin, margin + titleSize + 14}, textSize, false, mutedColour, anchorStart, subtitle)
+
+	for i := range max(len(c.Items), 1) {
+		if i%2 == 1 {
+			cv.rect(margin, bodyTop+float64(i)*rowHeight, width-2*margin, rowHeight, stripeColour)
+		}
+	}
+	dates, layout := ticks(start, end)
+	for _, t := range dates {
+		cv.line(point{x(t), bodyTop - 4}, point{x(t), bodyBottom}, gridColour, 0.5, false)
+		cv.text(point{x(t), bodyTop - 8}, textSize, false, mutedColour, anchorMiddle, t.Format(layout))
+	}
+	cv.line(point{margin, bodyTop}, point{right, bodyTop}, defaultColour, 0.75, false)
+	cv.line(point{margin, bodyBottom}, point{right, bodyBottom}, defaultColour, 0.75, false)
+	if len(c.Items) == 0 {
+		cv.text(point{margin + 4, bodyTop + rowHeight/2 + 3}, textSize, false, mutedColour, anchorStart, "No gantt items")
+	}
+
+	rows := make(map[string]int, len(c.Items))
+	for i, item := range c.Items {
+		rows[item.ID] = i
+	}
+	middle := func(row int) float64 {
+		return bodyTop + float64(row)*rowHeight + rowHeight/2
+	}
+	for i, item := range c.Items {
+		y := middle(i)
+		cv.text(point{margin + 4, y + 3}, textSize, false, textColour, anchorStart, truncate(item.Name, textSize, labelWidth-8))
+		colour := item.Colour
+		if colour == "" {
+			colour = defaultColour
+		}
+		from, to := x(item.Start), x(item.End)
+		if !item.End.After(item.Start) {
+			const half = 5.0
+			cv.polygon([]point{{from, y - half}, {from + half, y}, {from, y + half}, {from - half, y}}, colour)
+			continue
+		}
+		cv.rect(from, y-barHeight/2, max(to-from, 1), barHeight, colour)
+		if progress := min(max(item.PercentComplete, 0), 100); progress > 0 {
+			cv.rect(from, y+barHeight/2-2.5, max(to-from, 1)*float64(progress)/100, 2.5, progressColour)
+		}
+	}
+
+	for i, item := range c.Items {
+		for _, dependency := range item.Dependencies {
+			row, ok := rows[dependency.PredecessorID]
+			if !ok {
+				continue
+			}
+			predecessor := c.Items[row]
+			fromX, toX, out, in := x(predecessor.End), x(item.Start), 6.0, 1.0
+			switch dependency.Type {
+			case schedule.StartToStart:
+				fromX, out = x(predecessor.Start), -6
+			case schedule.FinishToFinish:
+				toX, in = x(item.End), -1
+			}
+			fromY, toY := middle(row), middle(i)
+			if toY > fromY {
+				fromY += barHeight / 2
+			} else {
+				fromY -= barHeight / 2
+			}
+			elbow := fromX + out
+			if dependency.Type == schedule.FinishToFinish {
+				elbow = max(elbow, toX+6) //the arrow comes in from the right
+			}
+			points := []point{{fromX, fromY}, {elbow, fromY}}
+			if in > 0 && toX-4 < elbow { //no room to come in from the left, go around between the rows
+				between := toY - rowHeight/2
+				if toY < fromY {
+					between = toY + rowHeight/2
+				}
+				points = append(points, point{elbow, between}, point{toX - 10, between}, point{toX - 10, toY})
+			} else {
+				points = append(points, point{elbow, toY})
+			}
+			cv.polyline(append(points, point{toX - in*4, toY}), dependencyColour, 0.75)
+			cv.polygon(arrow(point{toX, toY}, in), dependencyColour)
+		}
+	}
+
+	if !c.Today.IsZero() && !c.Today.Before(start) && !c.Today.After(end) {
+		cv.line(point{x(c.Today), bodyTop}, point{x(c.Today), bodyBottom}, todayColour, 1, true)
+	}
+
+	c.drawLegend(cv, bodyBottom+24)
+}
+
+// arrow is the head of a dependency ending at the point, pointing right for a direction of 1 and left for -1
+func arrow(at point, direction float64) []point {
+	return []point{{at.x, at.y}, {at.x - direction*4, at.y - 2.5}, {at.x - direction*4, at.y + 2.5}}
+}
+
+func (c Chart) drawLegend(cv canvas, y float64) {
+	x := margin
+	label := func(value string) {
+		cv.text(point{x, y}, textSize, false, textColour, anchorStart, value)
+		x += textWidth(value, textSize) + 16
+	}
+	for _, entry := range c.Legend {
+		cv.rect(x, y-8, 10, 10, entry.Colour)
+		x += 14
+		label(entry.Label)
+	}
+	cv.rect(x, y-3, 14, 2.5, progressColour)
+	x += 18
+	label("Progress")
+	cv.polyline([]point{{x, y - 3}, {x + 11, y - 3}}, dependencyColour, 0.75)
+	cv.polygon(arrow(point{x + 14, y - 3}, 1), dependencyColour)
+	x += 18
+	label("Dependency")
+	if !c.Today.IsZero() {
+		cv.line(point{x + 5, y - 9}, point{x + 5, y + 2}, todayColour, 1, true)
+		x += 14
+		label("Today")
+	}
+}
+
+// truncate shortens a label to the width, ending it with dots
+func truncate(value string, size float64, width float64) string {
+	if textWidth(value, size) <= width {
+		return value
+	}
+	runes := []rune(value)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// helveticaWidths are the advance widths of the printable ASCII characters in Helvetica, in thousandths of the size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// textWidth measures text set in Helvetica, which both the SVG and the PDF use. Other characters count as wide
+// as a digit.
+func textWidth(value string, size float64) float64 {
+	total := 0
+	for _, r := range value {
+		if r >= ' ' && r <= '~' {
+			total += helveticaWidths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
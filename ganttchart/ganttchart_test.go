@@ -0,0 +1,94 @@
+package ganttchart
+
+import (
+	"bytes"
+	"flag"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+var chart = Chart{
+	Title: "Compilers & <Parsers>",
+	Today: date(10, 21),
+	Items: []Item{
+		{ID: "design", Name: "Design", Start: date(10, 7), End: date(10, 14), Colour: "#2C59C7", PercentComplete: 100},
+		{ID: "build", Name: "Implementation of the type checker and the code generator", Start: date(10, 14), End: date(11, 15),
+			Colour: "#F4A261", PercentComplete: 40, Dependencies: []Dependency{{PredecessorID: "design", Type: schedule.FinishToStart}}},
+		{ID: "poster", Name: "Poster (draft)", Start: date(10, 21), End: date(11, 1), Colour: "#2A9D39",
+			Dependencies: []Dependency{{PredecessorID: "build", Type: schedule.StartToStart}}},
+		{ID: "report", Name: "Report", Start: date(11, 4), End: date(11, 22), Colour: "#D62828",
+			Dependencies: []Dependency{{PredecessorID: "build", Type: schedule.FinishToFinish}}},
+		{ID: "demo", Name: "Démo day", Start: date(11, 25), End: date(11, 25), Colour: "#2A9D39"},
+	},
+	Legend: []LegendEntry{
+		{Label: "On track", Colour: "#2A9D39"},
+		{Label: "At risk", Colour: "#F4A261"},
+		{Label: "Blocked", Colour: "#D62828"},
+		{Label: "Complete", Colour: "#2C59C7"},
+	},
+}
+
+func golden(t *testing.T, name string, output []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, output, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file, run the tests with -update to create it: %v", err)
+	}
+	assert.Equal(t, string(expected), string(output))
+}
+
+func TestChart_SVG(t *testing.T) {
+	golden(t, "chart.svg", chart.SVG())
+	assert.Equal(t, chart.SVG(), chart.SVG())
+}
+
+func TestChart_PDF(t *testing.T) {
+	output := chart.PDF()
+	golden(t, "chart.pdf", output)
+	assert.True(t, bytes.HasPrefix(output, []byte("%PDF-1.4")))
+	assert.Contains(t, string(output), "(Compilers & <Parsers>)")
+	assert.Contains(t, string(output), "(D\xe9mo day)")
+}
+
+func TestChart_Empty(t *testing.T) {
+	output := Chart{Title: "Empty", Today: date(10, 21)}.SVG()
+	assert.Contains(t, string(output), "No gantt items")
+	assert.Contains(t, string(output), `height="144"`)
+}
+
+func TestTicks(t *testing.T) {
+	dates, layout := ticks(date(10, 7), date(10, 14))
+	assert.Len(t, dates, 8)
+	assert.Equal(t, "2 Jan", layout)
+
+	dates, _ = ticks(date(10, 9), date(11, 30))
+	assert.Equal(t, date(10, 14), dates[0]) //the first Monday
+	assert.Equal(t, time.Monday, dates[len(dates)-1].Weekday())
+
+	dates, layout = ticks(date(1, 15), date(9, 1))
+	assert.Equal(t, date(2, 1), dates[0])
+	assert.Len(t, dates, 8)
+	assert.Equal(t, "Jan 2006", layout)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "Design", truncate("Design", 8, 100))
+	shortened := truncate("Implementation of the type checker and the code generator", 8, 100)
+	assert.LessOrEqual(t, textWidth(shortened, 8), 100.0)
+	assert.Equal(t, "Implementation of the typ...", shortened)
+}
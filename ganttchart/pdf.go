@@ -0,0 +1,127 @@
+package ganttchart
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type pdfCanvas struct {
+	bytes.Buffer
+	height float64 // the origin of PDF is the bottom left corner, y is flipped
+}
+
+// PDF draws the chart on a single page of a PDF document. The standard Helvetica fonts are used so that nothing
+// is embedded, characters outside of Latin-1 are shown as question marks.
+func (c Chart) PDF() []byte {
+	width, height := c.size()
+	cv := pdfCanvas{height: height}
+	c.draw(&cv)
+	content := cv.Bytes()
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> /Contents 4 0 R >>",
+			num(width), num(height)),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title %s /Producer (fyp) >>", pdfString(c.Title)), //no creation date, the output would change
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return out.Bytes()
+}
+
+func (cv *pdfCanvas) rect(x, y, w, h float64, fill string) {
+	fmt.Fprintf(cv, "%s rg %s %s %s %s re f\n", pdfColour(fill), num(x), num(cv.height-y-h), num(w), num(h))
+}
+
+func (cv *pdfCanvas) line(from, to point, stroke string, width float64, dashed bool) {
+	dash := ""
+	if dashed {
+		dash = "[4 3] 0 d "
+	}
+	fmt.Fprintf(cv, "q %s RG %s w %s%s %s m %s %s l S Q\n", pdfColour(stroke), num(width), dash,
+		num(from.x), num(cv.height-from.y), num(to.x), num(cv.height-to.y))
+}
+
+func (cv *pdfCanvas) polyline(points []point, stroke string, width float64) {
+	fmt.Fprintf(cv, "q %s RG %s w %s S Q\n", pdfColour(stroke), num(width), cv.path(points))
+}
+
+func (cv *pdfCanvas) polygon(points []point, fill string) {
+	fmt.Fprintf(cv, "%s rg %s h f\n", pdfColour(fill), cv.path(points))
+}
+
+func (cv *pdfCanvas) text(at point, size float64, bold bool, colour string, anchor anchor, value string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	x := at.x
+	switch anchor {
+	case anchorMiddle:
+		x -= textWidth(value, size) / 2
+	case anchorEnd:
+		x -= textWidth(value, size)
+	}
+	fmt.Fprintf(cv, "%s rg BT /%s %s Tf %s %s Td %s Tj ET\n", pdfColour(colour), font, num(size), num(x), num(cv.height-at.y), pdfString(value))
+}
+
+func (cv *pdfCanvas) path(points []point) string {
+	var path strings.Builder
+	for i, p := range points {
+		operator := "l"
+		if i == 0 {
+			operator = "m"
+		}
+		fmt.Fprintf(&path, "%s %s %s ", num(p.x), num(cv.height-p.y), operator)
+	}
+	return strings.TrimSpace(path.String())
+}
+
+// pdfColour converts #RRGGBB into the components of a PDF colour
+func pdfColour(hex string) string {
+	value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return "0 0 0"
+	}
+	component := func(shift uint) string {
+		return strconv.FormatFloat(float64(value>>shift&0xFF)/255, 'f', 3, 64)
+	}
+	return component(16) + " " + component(8) + " " + component(0)
+}
+
+// pdfString encodes a literal string in the Latin-1 range of WinAnsiEncoding
+func pdfString(value string) string {
+	var encoded strings.Builder
+	encoded.WriteByte('(')
+	for _, r := range value {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			encoded.WriteByte('\\')
+			encoded.WriteRune(r)
+		case r >= ' ' && r <= '~', r >= 0xA0 && r <= 0xFF:
+			encoded.WriteByte(byte(r))
+		default:
+			encoded.WriteByte('?')
+		}
+	}
+	encoded.WriteByte(')')
+	return encoded.String()
+}
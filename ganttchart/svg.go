@@ -0,0 +1,80 @@
+package ganttchart
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type svgCanvas struct {
+	strings.Builder
+}
+
+// SVG draws the chart as a standalone SVG document
+func (c Chart) SVG() []byte {
+	width, height := c.size()
+	var cv svgCanvas
+	fmt.Fprintf(&cv, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		num(width), num(height), num(width), num(height))
+	fmt.Fprintf(&cv, "<title>%s</title>\n", svgEscaper.Replace(c.Title))
+	fmt.Fprintf(&cv, `<rect width="%s" height="%s" fill="#FFFFFF"/>`+"\n", num(width), num(height))
+	c.draw(&cv)
+	cv.WriteString("</svg>\n")
+	return []byte(cv.String())
+}
+
+func (cv *svgCanvas) rect(x, y, w, h float64, fill string) {
+	fmt.Fprintf(cv, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n", num(x), num(y), num(w), num(h), fill)
+}
+
+func (cv *svgCanvas) line(from, to point, stroke string, width float64, dashed bool) {
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="4 3"`
+	}
+	fmt.Fprintf(cv, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="%s"%s/>`+"\n",
+		num(from.x), num(from.y), num(to.x), num(to.y), stroke, num(width), dash)
+}
+
+func (cv *svgCanvas) polyline(points []point, stroke string, width float64) {
+	fmt.Fprintf(cv, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n", svgPoints(points), stroke, num(width))
+}
+
+func (cv *svgCanvas) polygon(points []point, fill string) {
+	fmt.Fprintf(cv, `<polygon points="%s" fill="%s"/>`+"\n", svgPoints(points), fill)
+}
+
+func (cv *svgCanvas) text(at point, size float64, bold bool, colour string, anchor anchor, value string) {
+	attributes := ""
+	if bold {
+		attributes += ` font-weight="bold"`
+	}
+	switch anchor {
+	case anchorMiddle:
+		attributes += ` text-anchor="middle"`
+	case anchorEnd:
+		attributes += ` text-anchor="end"`
+	}
+	fmt.Fprintf(cv, `<text x="%s" y="%s" font-size="%s" fill="%s"%s>%s</text>`+"\n",
+		num(at.x), num(at.y), num(size), colour, attributes, svgEscaper.Replace(value))
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func svgPoints(points []point) string {
+	values := make([]string, len(points))
+	for i, p := range points {
+		values[i] = num(p.x) + "," + num(p.y)
+	}
+	return strings.Join(values, " ")
+}
+
+// num writes a coordinate with at most two decimals so that the output does not depend on rounding noise
+func num(value float64) string {
+	value = math.Round(value*100) / 100
+	if value == 0 {
+		value = 0 //no negative zero
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
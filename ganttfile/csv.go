@@ -0,0 +1,225 @@
+package ganttfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns are written as the header of a CSV file, the other names are understood when reading one
+var csvColumns = []string{"id", "name", "start", "end", "percentComplete", "predecessors", "description", "links"}
+
+var csvAliases = map[string]string{
+	"id": "id", "ref": "id", "#": "id",
+	"name": "name", "ganttname": "name", "task": "name", "taskname": "name",
+	"start": "start", "startdate": "start",
+	"end": "end", "enddate": "end", "finish": "end", "finishdate": "end",
+	"percentcomplete": "percentComplete", "%complete": "percentComplete", "progress": "percentComplete",
+	"predecessors": "predecessors", "dependencies": "predecessors",
+	"description": "description", "notes": "description",
+	"links": "links", "link": "links", "url": "links", "hyperlink": "links",
+}
+
+// predecessorPattern reads a predecessor the way spreadsheets and planning tools show it, e.g. 3, 3SS or 3FS+2d
+var predecessorPattern = regexp.MustCompile(`(?i)^(.+?)(?:(FS|SS|FF|SF)\s*(?:([+-]\s*\d+)\s*(?:d|days?)?)?)?$`)
+
+// formulaPrefixes start cells that spreadsheets run as formulas, such cells are escaped with a quote when written
+const formulaPrefixes = "=+-@"
+
+func readCSV(r io.Reader, location *time.Location) ([]Task, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("%w: the header row is missing", ErrUnreadable)
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") //spreadsheets save UTF-8 with a byte order mark
+		}
+		key := strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.TrimSpace(name)))
+		if column, ok := csvAliases[key]; ok {
+			if _, duplicate := columns[column]; !duplicate {
+				columns[column] = i
+			}
+		}
+	}
+	var missing []string
+	for _, column := range []string{"name", "start", "end"} {
+		if _, ok := columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("%w: the header has no %s column", ErrUnreadable, strings.Join(missing, ", "))
+	}
+
+	var (
+		tasks     []Task
+		rowErrors []RowError
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		row, _ := reader.FieldPos(0)
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		task := Task{
+			Ref:         cell("id"),
+			Row:         row,
+			Name:        unescapeCell(cell("name")),
+			Description: unescapeCell(cell("description")),
+			Links:       unescapeCell(cell("links")),
+		}
+		if task.Ref == "" {
+			task.Ref = fmt.Sprint(len(tasks) + 1)
+		}
+		if task.Start, err = parseDate(cell("start"), location); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "start", Message: err.Error()})
+		}
+		if task.End, err = parseDate(cell("end"), location); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "end", Message: err.Error()})
+		}
+		if percent := strings.TrimSuffix(cell("percentComplete"), "%"); percent != "" {
+			if task.PercentComplete, err = strconv.Atoi(strings.TrimSpace(percent)); err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Field: "percentComplete", Message: "must be a whole number"})
+			}
+		}
+		var linkErrors []string
+		task.Predecessors, linkErrors = parsePredecessors(cell("predecessors"))
+		for _, message := range linkErrors {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "predecessors", Message: message})
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrors, nil
+}
+
+// parsePredecessors reads a list of predecessors separated by semicolons or commas
+func parsePredecessors(value string) ([]Link, []string) {
+	var (
+		links    []Link
+		messages []string
+	)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		match := predecessorPattern.FindStringSubmatch(entry)
+		if match == nil {
+			messages = append(messages, fmt.Sprintf("%q is not a predecessor such as 3, 3SS or 3FS+2d", entry))
+			continue
+		}
+		link := Link{Ref: strings.TrimSpace(match[1]), Type: schedule.FinishToStart}
+		switch strings.ToUpper(match[2]) {
+		case "SS":
+			link.Type = schedule.StartToStart
+		case "FF":
+			link.Type = schedule.FinishToFinish
+		case "SF":
+			messages = append(messages, fmt.Sprintf("%q is a start to finish link, which is not supported", entry))
+			continue
+		}
+		if match[3] != "" {
+			link.LagDays, _ = strconv.Atoi(strings.ReplaceAll(match[3], " ", ""))
+		}
+		links = append(links, link)
+	}
+	return links, messages
+}
+
+func writeCSV(w io.Writer, tasks []Task, numbers map[string]string, location *time.Location) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		var predecessors []string
+		for _, link := range task.Predecessors {
+			predecessors = append(predecessors, formatPredecessor(numbers[link.Ref], link))
+		}
+		record := []string{
+			numbers[task.Ref],
+			escapeCell(task.Name),
+			formatDate(task.Start, location),
+			formatDate(task.End, location),
+			strconv.Itoa(task.PercentComplete),
+			strings.Join(predecessors, ";"),
+			escapeCell(task.Description),
+			escapeCell(task.Links),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatPredecessor(number string, link Link) string {
+	suffix := ""
+	switch link.Type {
+	case schedule.StartToStart:
+		suffix = "SS"
+	case schedule.FinishToFinish:
+		suffix = "FF"
+	default:
+		if link.LagDays != 0 {
+			suffix = "FS"
+		}
+	}
+	if link.LagDays != 0 {
+		suffix += fmt.Sprintf("%+dd", link.LagDays)
+	}
+	return number + suffix
+}
+
+// formatDate writes dates at midnight as a date only
+func formatDate(date time.Time, location *time.Location) string {
+	date = date.In(location)
+	if date.Equal(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)) {
+		return date.Format(time.DateOnly)
+	}
+	return date.Format(time.RFC3339)
+}
+
+// escapeCell keeps spreadsheets from running text as a formula
+func escapeCell(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
@@ -0,0 +1,185 @@
+// Package ganttfile reads and writes the items of a gantt chart as CSV or Microsoft Project XML so that plans can be
+// moved between spreadsheets, planning tools such as ProjectLibre and the site.
+package ganttfile
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format is a file format plans are exchanged in
+type Format string
+
+const (
+	CSV       Format = "csv"
+	MSProject Format = "msproject" // the XML interchange format of Microsoft Project, ProjectLibre reads and writes it too
+)
+
+// ErrUnknownFormat is returned for a format that plans cannot be exchanged in
+var ErrUnknownFormat = errors.New("format must be csv or msproject")
+
+// ErrUnreadable is returned when a file cannot be read at all, problems with single tasks are returned as RowError
+var ErrUnreadable = errors.New("file cannot be read")
+
+// ParseFormat converts a string into a Format, CSV when it is empty
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(value) {
+	case "", "csv":
+		return CSV, nil
+	case "msproject", "xml":
+		return MSProject, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, value)
+}
+
+// ContentType is the media type files of the format are sent as
+func (f Format) ContentType() string {
+	if f == MSProject {
+		return "application/xml; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension is the file name extension of the format including the dot
+func (f Format) Extension() string {
+	if f == MSProject {
+		return ".xml"
+	}
+	return ".csv"
+}
+
+// Task is an item of a plan
+type Task struct {
+	Ref             string // identifies the task within the file, links refer to it
+	Row             int    // where the task was read from, the line of a CSV file or the ID of a Microsoft Project task
+	Name            string
+	Start           time.Time
+	End             time.Time
+	Description     string
+	Links           string
+	PercentComplete int
+	Predecessors    []Link
+}
+
+// Link makes a task wait for another task of the same file
+type Link struct {
+	Ref     string
+	Type    schedule.DependencyType
+	LagDays int
+}
+
+// RowError is a problem with one task of a file, row 0 is the file as a whole
+type RowError struct {
+	Row     int
+	Field   string
+	Message string
+}
+
+func (e RowError) Error() string {
+	if e.Row == 0 {
+		return fmt.Sprintf("%s %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s %s", e.Row, e.Field, e.Message)
+}
+
+// Read parses a plan, dates without a time zone are taken in the location. Tasks that cannot be imported are
+// reported as row errors, all tasks are returned even then so that they can be shown next to the errors.
+func Read(r io.Reader, format Format, location *time.Location) ([]Task, []RowError, error) {
+	var (
+		tasks     []Task
+		rowErrors []RowError
+		err       error
+	)
+	if format == MSProject {
+		tasks, rowErrors, err = readMSProject(r, location)
+	} else {
+		tasks, rowErrors, err = readCSV(r, location)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tasks) == 0 && len(rowErrors) == 0 {
+		rowErrors = append(rowErrors, RowError{Field: "tasks", Message: "are missing, the file has no tasks"})
+	}
+	return tasks, append(rowErrors, validate(tasks)...), nil
+}
+
+// Write encodes the tasks of a plan. Tasks are numbered from 1 in the file, dates are written in the location.
+func Write(w io.Writer, format Format, name string, tasks []Task, location *time.Location) error {
+	numbers := make(map[string]string, len(tasks))
+	for i, task := range tasks {
+		numbers[task.Ref] = fmt.Sprint(i + 1)
+	}
+	if format == MSProject {
+		return writeMSProject(w, name, tasks, numbers, location)
+	}
+	return writeCSV(w, tasks, numbers, location)
+}
+
+// validate checks what the readers leave to the file, dates that could not be parsed are already reported
+func validate(tasks []Task) []RowError {
+	var rowErrors []RowError
+	rows := make(map[string]int, len(tasks))
+	for _, task := range tasks {
+		if strings.TrimSpace(task.Name) == "" {
+			rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "name", Message: "is required"})
+		}
+		if !task.Start.IsZero() && !task.End.IsZero() && task.End.Before(task.Start) {
+			rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "end", Message: "must not be before start"})
+		}
+		if task.PercentComplete < 0 || task.PercentComplete > 100 {
+			rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "percentComplete", Message: "must be between 0 and 100"})
+		}
+		if row, ok := rows[task.Ref]; ok {
+			rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "id", Message: fmt.Sprintf("%q is already used in row %d", task.Ref, row)})
+			continue
+		}
+		rows[task.Ref] = task.Row
+	}
+
+	var (
+		scheduleTasks []schedule.Task
+		dependencies  []schedule.Dependency
+	)
+	for _, task := range tasks {
+		scheduleTasks = append(scheduleTasks, schedule.Task{ID: task.Ref})
+		for _, link := range task.Predecessors {
+			if _, ok := rows[link.Ref]; !ok {
+				rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "predecessors", Message: fmt.Sprintf("refer to %q which is not in the file", link.Ref)})
+				continue
+			}
+			if link.Ref == task.Ref {
+				rowErrors = append(rowErrors, RowError{Row: task.Row, Field: "predecessors", Message: "must not include the task itself"})
+				continue
+			}
+			dependencies = append(dependencies, schedule.Dependency{PredecessorID: link.Ref, SuccessorID: task.Ref, Type: link.Type})
+		}
+	}
+	if len(rows) == len(tasks) {
+		if err := schedule.CheckCycle(scheduleTasks, dependencies); err != nil {
+			rowErrors = append(rowErrors, RowError{Field: "predecessors", Message: err.Error()})
+		}
+	}
+	return rowErrors
+}
+
+// parseDate accepts the dates of spreadsheets and planning tools, a timestamp with or without a zone or a date only
+func parseDate(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly} {
+		if date, err := time.ParseInLocation(layout, value, location); err == nil {
+			return date.UTC(), nil
+		}
+	}
+	return time.Time{}, errors.New("must be an ISO-8601 date (YYYY-MM-DD) or timestamp")
+}
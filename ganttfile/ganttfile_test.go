@@ -0,0 +1,137 @@
+package ganttfile
+
+import (
+	"bytes"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(n int) time.Time {
+	return time.Date(2024, 10, 7+n, 0, 0, 0, 0, time.UTC)
+}
+
+var plan = []Task{
+	{Ref: "design-uuid", Name: "Design", Start: day(0), End: day(5), PercentComplete: 100, Description: "=SUM(A1)"},
+	{Ref: "build-uuid", Name: "Implementation", Start: day(5), End: day(15), Links: "https://example.com/repo",
+		Predecessors: []Link{{Ref: "design-uuid", Type: schedule.FinishToStart}}},
+	{Ref: "report-uuid", Name: "Report, final", Start: day(10).Add(9 * time.Hour), End: day(20),
+		Predecessors: []Link{{Ref: "build-uuid", Type: schedule.StartToStart, LagDays: 2}, {Ref: "design-uuid", Type: schedule.FinishToFinish, LagDays: -1}}},
+}
+
+func TestWrite_CSV(t *testing.T) {
+	var out bytes.Buffer
+	err := Write(&out, CSV, "Compilers", plan, time.UTC)
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name,start,end,percentComplete,predecessors,description,links\n"+
+		"1,Design,2024-10-07,2024-10-12,100,,'=SUM(A1),\n"+
+		"2,Implementation,2024-10-12,2024-10-22,0,1,,https://example.com/repo\n"+
+		"3,\"Report, final\",2024-10-17T09:00:00Z,2024-10-27,0,2SS+2d;1FF-1d,,\n", out.String())
+}
+
+func TestRead_RoundTrip(t *testing.T) {
+	for _, format := range []Format{CSV, MSProject} {
+		var out bytes.Buffer
+		assert.Nil(t, Write(&out, format, "Compilers", plan, time.UTC))
+
+		tasks, rowErrors, err := Read(&out, format, time.UTC)
+		assert.Nil(t, err, format)
+		assert.Empty(t, rowErrors, format)
+		if assert.Len(t, tasks, 3, format) {
+			for i, task := range tasks {
+				assert.Equal(t, plan[i].Name, task.Name, format)
+				assert.True(t, plan[i].Start.Equal(task.Start), format)
+				assert.True(t, plan[i].End.Equal(task.End), format)
+				assert.Equal(t, plan[i].Description, task.Description, format)
+				assert.Equal(t, plan[i].PercentComplete, task.PercentComplete, format)
+			}
+			assert.Equal(t, []Link{{Ref: "2", Type: schedule.StartToStart, LagDays: 2}, {Ref: "1", Type: schedule.FinishToFinish, LagDays: -1}}, tasks[2].Predecessors, format)
+		}
+	}
+}
+
+func TestRead_CSVFromSpreadsheet(t *testing.T) {
+	file := "\ufeffTask Name,Start Date,Finish,% Complete,Notes\n" +
+		"Literature review,2024-10-07,2024-10-18,50%,\n" +
+		",,,,\n" +
+		"Prototype,2024-10-21 09:00,2024-11-01,0,\"two\nlines\"\n"
+	dublin, _ := time.LoadLocation("Europe/Dublin")
+
+	tasks, rowErrors, err := Read(strings.NewReader(file), CSV, dublin)
+	assert.Nil(t, err)
+	assert.Empty(t, rowErrors)
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, Task{Ref: "1", Row: 2, Name: "Literature review", Start: time.Date(2024, 10, 6, 23, 0, 0, 0, time.UTC),
+			End: time.Date(2024, 10, 17, 23, 0, 0, 0, time.UTC), PercentComplete: 50}, tasks[0])
+		assert.Equal(t, "2", tasks[1].Ref)
+		assert.Equal(t, 4, tasks[1].Row)
+		assert.Equal(t, time.Date(2024, 10, 21, 8, 0, 0, 0, time.UTC), tasks[1].Start)
+		assert.Equal(t, "two\nlines", tasks[1].Description)
+	}
+}
+
+func TestRead_RowErrors(t *testing.T) {
+	file := "id,name,start,end,percentComplete,predecessors\n" +
+		"a,Design,2024-10-07,2024-10-01,0,\n" +
+		"b,,07/10/2024,2024-10-20,120,a;x\n" +
+		"c,Poster,2024-10-07,2024-10-20,0,d\n" +
+		"d,Print,2024-10-07,2024-10-20,0,c;bSF\n"
+
+	tasks, rowErrors, err := Read(strings.NewReader(file), CSV, time.UTC)
+	assert.Nil(t, err)
+	assert.Len(t, tasks, 4)
+	assert.Equal(t, []RowError{
+		{Row: 3, Field: "start", Message: "must be an ISO-8601 date (YYYY-MM-DD) or timestamp"},
+		{Row: 5, Field: "predecessors", Message: `"bSF" is a start to finish link, which is not supported`},
+		{Row: 2, Field: "end", Message: "must not be before start"},
+		{Row: 3, Field: "name", Message: "is required"},
+		{Row: 3, Field: "percentComplete", Message: "must be between 0 and 100"},
+		{Row: 3, Field: "predecessors", Message: `refer to "x" which is not in the file`},
+		{Field: "predecessors", Message: "dependencies form a cycle: c, d"},
+	}, rowErrors)
+}
+
+func TestRead_Unreadable(t *testing.T) {
+	_, _, err := Read(strings.NewReader("title,due\nDesign,2024-10-07\n"), CSV, time.UTC)
+	assert.ErrorIs(t, err, ErrUnreadable)
+
+	_, _, err = Read(strings.NewReader("<Project><Tasks><Task>"), MSProject, time.UTC)
+	assert.ErrorIs(t, err, ErrUnreadable)
+}
+
+func TestRead_MSProject(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Project xmlns="http://schemas.microsoft.com/project">
+  <Name>plan.xml</Name>
+  <Tasks>
+    <Task><UID>0</UID><ID>0</ID><Name>plan</Name><Summary>1</Summary><Start>2024-10-07T08:00:00</Start><Finish>2024-10-25T17:00:00</Finish></Task>
+    <Task><UID>7</UID><ID>1</ID><Name>Design</Name><Start>2024-10-07T08:00:00</Start><Finish>2024-10-11T17:00:00</Finish><PercentComplete>20</PercentComplete></Task>
+    <Task><UID>8</UID><ID>2</ID><IsNull>1</IsNull></Task>
+    <Task><UID>9</UID><ID>3</ID><Name>Build</Name><Start>2024-10-14T08:00:00</Start><Finish>2024-10-25T17:00:00</Finish>
+      <PredecessorLink><PredecessorUID>7</PredecessorUID><Type>1</Type><LinkLag>14400</LinkLag><LagFormat>8</LagFormat></PredecessorLink>
+      <PredecessorLink><PredecessorUID>7</PredecessorUID><Type>2</Type></PredecessorLink>
+    </Task>
+  </Tasks>
+</Project>`
+
+	tasks, rowErrors, err := Read(strings.NewReader(file), MSProject, time.UTC)
+	assert.Nil(t, err)
+	assert.Equal(t, []RowError{{Row: 3, Field: "predecessors", Message: "link type 2 is not supported"}}, rowErrors)
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, Task{Ref: "7", Row: 1, Name: "Design", Start: time.Date(2024, 10, 7, 8, 0, 0, 0, time.UTC),
+			End: time.Date(2024, 10, 11, 17, 0, 0, 0, time.UTC), PercentComplete: 20}, tasks[0])
+		assert.Equal(t, []Link{{Ref: "7", Type: schedule.FinishToStart, LagDays: 1}}, tasks[1].Predecessors)
+	}
+}
+
+func TestWrite_MSProject(t *testing.T) {
+	var out bytes.Buffer
+	err := Write(&out, MSProject, "Compilers", plan[:2], time.UTC)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `<Project xmlns="http://schemas.microsoft.com/project">`)
+	assert.Contains(t, out.String(), "<UID>2</UID>")
+	assert.Contains(t, out.String(), "<PredecessorUID>1</PredecessorUID>")
+	assert.Contains(t, out.String(), "<Start>2024-10-12T00:00:00</Start>")
+}
@@ -0,0 +1,172 @@
+package ganttfile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	msProjectNamespace = "http://schemas.microsoft.com/project"
+	msProjectTime      = "2006-01-02T15:04:05" // local time of the project, there is no zone
+	msLagPerDay        = 8 * 60 * 10           // lags are tenths of a minute, a working day has eight hours
+	msLagPerElapsedDay = 24 * 60 * 10
+	msLagElapsedDays   = "8" // the lag format of elapsed days, other formats are counted in working days
+)
+
+// msLinkTypes are the link types of Microsoft Project, 2 is start to finish which gantt items cannot have
+var msLinkTypes = map[string]schedule.DependencyType{
+	"0": schedule.FinishToFinish,
+	"1": schedule.FinishToStart,
+	"3": schedule.StartToStart,
+}
+
+type msProject struct {
+	XMLName xml.Name `xml:"Project"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Name    string   `xml:"Name,omitempty"`
+	Tasks   []msTask `xml:"Tasks>Task"`
+}
+
+type msTask struct {
+	UID              string   `xml:"UID"`
+	ID               string   `xml:"ID"`
+	Name             string   `xml:"Name"`
+	IsNull           string   `xml:"IsNull,omitempty"`
+	Manual           string   `xml:"Manual,omitempty"` // manually scheduled tasks keep their dates when opened
+	Summary          string   `xml:"Summary,omitempty"`
+	Milestone        string   `xml:"Milestone,omitempty"`
+	Start            string   `xml:"Start"`
+	Finish           string   `xml:"Finish"`
+	PercentComplete  string   `xml:"PercentComplete"`
+	Notes            string   `xml:"Notes,omitempty"`
+	HyperlinkAddress string   `xml:"HyperlinkAddress,omitempty"`
+	PredecessorLinks []msLink `xml:"PredecessorLink"`
+}
+
+type msLink struct {
+	PredecessorUID string `xml:"PredecessorUID"`
+	Type           string `xml:"Type"`
+	LinkLag        string `xml:"LinkLag"`
+	LagFormat      string `xml:"LagFormat"`
+}
+
+// readMSProject reads the tasks of a Microsoft Project XML file. The project summary task, summary tasks and blank
+// lines are not items of the plan and are skipped.
+func readMSProject(r io.Reader, location *time.Location) ([]Task, []RowError, error) {
+	var project msProject
+	if err := xml.NewDecoder(r).Decode(&project); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+
+	var (
+		tasks     []Task
+		rowErrors []RowError
+	)
+	for i, element := range project.Tasks {
+		if element.UID == "0" || element.Summary == "1" || element.IsNull == "1" || strings.TrimSpace(element.Name) == "" {
+			continue
+		}
+		row, err := strconv.Atoi(element.ID)
+		if err != nil {
+			row = i + 1
+		}
+		task := Task{
+			Ref:         strings.TrimSpace(element.UID),
+			Row:         row,
+			Name:        strings.TrimSpace(element.Name),
+			Description: strings.TrimSpace(element.Notes),
+			Links:       strings.TrimSpace(element.HyperlinkAddress),
+		}
+		if task.Ref == "" {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "UID", Message: "is required"})
+		}
+		if task.Start, err = parseDate(element.Start, location); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "start", Message: err.Error()})
+		}
+		if task.End, err = parseDate(element.Finish, location); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Field: "end", Message: err.Error()})
+		}
+		if element.PercentComplete != "" {
+			if task.PercentComplete, err = strconv.Atoi(strings.TrimSpace(element.PercentComplete)); err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Field: "percentComplete", Message: "must be a whole number"})
+			}
+		}
+		for _, element := range element.PredecessorLinks {
+			link := Link{Ref: strings.TrimSpace(element.PredecessorUID), Type: schedule.FinishToStart}
+			if element.Type != "" {
+				var ok bool
+				if link.Type, ok = msLinkTypes[strings.TrimSpace(element.Type)]; !ok {
+					rowErrors = append(rowErrors, RowError{Row: row, Field: "predecessors", Message: fmt.Sprintf("link type %s is not supported", element.Type)})
+					continue
+				}
+			}
+			if lag, err := strconv.Atoi(strings.TrimSpace(element.LinkLag)); err == nil && lag != 0 {
+				perDay := msLagPerDay
+				if strings.TrimSpace(element.LagFormat) == msLagElapsedDays {
+					perDay = msLagPerElapsedDay
+				}
+				link.LagDays = (lag + sign(lag)*perDay/2) / perDay
+			}
+			task.Predecessors = append(task.Predecessors, link)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrors, nil
+}
+
+func writeMSProject(w io.Writer, name string, tasks []Task, numbers map[string]string, location *time.Location) error {
+	project := msProject{
+		Xmlns: msProjectNamespace,
+		Name:  name,
+	}
+	for _, task := range tasks {
+		element := msTask{
+			UID:              numbers[task.Ref],
+			ID:               numbers[task.Ref],
+			Name:             task.Name,
+			Manual:           "1",
+			Start:            task.Start.In(location).Format(msProjectTime),
+			Finish:           task.End.In(location).Format(msProjectTime),
+			PercentComplete:  strconv.Itoa(task.PercentComplete),
+			Notes:            task.Description,
+			HyperlinkAddress: task.Links,
+		}
+		if task.Start.Equal(task.End) {
+			element.Milestone = "1"
+		}
+		for _, link := range task.Predecessors {
+			linkType := "1"
+			for value, kind := range msLinkTypes {
+				if kind == link.Type {
+					linkType = value
+				}
+			}
+			element.PredecessorLinks = append(element.PredecessorLinks, msLink{
+				PredecessorUID: numbers[link.Ref],
+				Type:           linkType,
+				LinkLag:        strconv.Itoa(link.LagDays * msLagPerDay),
+				LagFormat:      "7", // days
+			})
+		}
+		project.Tasks = append(project.Tasks, element)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(project)
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
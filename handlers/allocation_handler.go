@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/allocation"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"slices"
+)
+
+func (c Controller) SetPreferencesHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.PreferencesRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	if len(request.SupervisorIDs) > c.maxPreferences {
+		message := model.ErrorMessage{
+			Message: fmt.Sprintf("at most %d supervisors can be ranked", c.maxPreferences),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	if err = validateRanking(request.SupervisorIDs); err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.SetStudentPreferences(ctx.Context(), authority.UserID, request.SupervisorIDs)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetPreferencesHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetStudentPreferences(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) SetApplicantRankingHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.RankingRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	if err = validateRanking(request.StudentIDs); err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.SetApplicantRanking(ctx.Context(), authority.UserID, request.StudentIDs)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetApplicantRankingHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetApplicantRanking(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// PreviewAllocationHandler computes an allocation without storing it, capacities in the body replace the
+// remaining capacity of the given supervisors for a what-if comparison
+func (c Controller) PreviewAllocationHandler(ctx *fiber.Ctx) error {
+	var request model.AllocationPreviewRequest
+	if len(ctx.Body()) > 0 {
+		err := json.Unmarshal(ctx.Body(), &request)
+		if err != nil {
+			message := model.ErrorMessage{
+				Message: err.Error(),
+			}
+			return ctx.Status(400).JSON(message)
+		}
+	}
+
+	students, supervisors, err := c.dbClient.GetAllocationInput(ctx.Context())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	for i := range supervisors {
+		if capacity, ok := request.Capacities[supervisors[i].ID]; ok {
+			supervisors[i].Capacity = max(capacity, 0)
+		}
+	}
+
+	result := allocation.Match(students, supervisors)
+	return ctx.Status(200).JSON(toAllocationRun(result))
+}
+
+func (c Controller) RunAllocationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	students, supervisors, err := c.dbClient.GetAllocationInput(ctx.Context())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	result := allocation.Match(students, supervisors)
+	runID, err := c.dbClient.SaveAllocationRun(ctx.Context(), authority.UserID, result)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	response, err := c.dbClient.GetAllocationRun(ctx.Context(), runID)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.Status(201).JSON(response)
+}
+
+func (c Controller) GetAllocationRunHandler(ctx *fiber.Ctx) error {
+	response, err := c.dbClient.GetAllocationRun(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) CommitAllocationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.CommitAllocationRun(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func validateRanking(ids []string) error {
+	for i, id := range ids {
+		if id == "" {
+			return errors.New("ranking cannot contain empty ids")
+		}
+		if slices.Contains(ids[:i], id) {
+			return fmt.Errorf("%s is ranked more than once", id)
+		}
+	}
+	return nil
+}
+
+func toAllocationRun(result allocation.Result) model.AllocationRun {
+	run := model.AllocationRun{
+		Assignments: []model.AllocationAssignment{},
+		Unassigned:  result.Unassigned,
+	}
+	for _, assignment := range result.Assignments {
+		run.Assignments = append(run.Assignments, model.AllocationAssignment{
+			StudentID:    assignment.StudentID,
+			SupervisorID: assignment.SupervisorID,
+			StudentRank:  assignment.StudentRank,
+		})
+	}
+	return run
+}
+
+// allocationError maps allocation errors to a response
+func allocationError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrInvalidPreferences), errors.Is(err, db.ErrNotSupervisor):
+		return ctx.Status(400).JSON(message)
+	case errors.Is(err, db.ErrAllocationRunNotFound), errors.Is(err, db.ErrReaderRunNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrAllocationCommitted), errors.Is(err, db.ErrStudentAlreadyPlaced),
+		errors.Is(err, db.ErrReaderRunCommitted), errors.Is(err, db.ErrReaderAlreadyAssigned),
+		errors.Is(err, db.ErrConflictOfInterest):
+		return ctx.Status(409).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
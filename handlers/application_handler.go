@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/Simplyphotons/fyp.git/auth0"
 	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
 	"github.com/Simplyphotons/fyp.git/model"
 	"github.com/Simplyphotons/fyp.git/security"
 	"github.com/gofiber/fiber/v2"
@@ -28,12 +29,17 @@ func (c Controller) GetApplicationsHandler(ctx *fiber.Ctx) error { //get all gan
 
 	fmt.Printf("%s\n", authority.UserID)
 
-	response, err := c.dbClient.GetApplications(ctx.Context(), authority.UserID)
+	opts, err := parseListOptions(ctx)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetApplications(ctx.Context(), authority.UserID, opts)
+	if err != nil {
+		return listError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 }
@@ -54,12 +60,17 @@ func (c Controller) GetAllAcceptedRequestsHandler(ctx *fiber.Ctx) error { //get
 
 	fmt.Printf("%s\n", authority.UserID)
 
-	response, err := c.dbClient.GetAllAcceptedRequests(ctx.Context())
+	opts, err := parseListOptions(ctx)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetAllAcceptedRequests(ctx.Context(), opts)
+	if err != nil {
+		return listError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 }
@@ -112,7 +123,7 @@ func (c Controller) GetUsernameHandler(ctx *fiber.Ctx) error {
 	return ctx.Status(200).JSON(response)
 }
 
-func (c Controller) GetProjectsHandler(ctx *fiber.Ctx) error { //get all projects
+func (c Controller) GetProjectsHandler(ctx *fiber.Ctx) error { //get all projects the user is a member of, filter with role
 
 	var (
 		authority security.Authority
@@ -128,38 +139,17 @@ func (c Controller) GetProjectsHandler(ctx *fiber.Ctx) error { //get all project
 
 	fmt.Printf("%s\n", authority.UserID)
 
-	response, err := c.dbClient.GetProjects(ctx.Context(), authority.UserID)
+	opts, err := parseListOptions(ctx)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
-	}
-	return ctx.Status(200).JSON(response)
-}
-
-func (c Controller) GetSecondProjectsHandler(ctx *fiber.Ctx) error { //get all projects
-
-	var (
-		authority security.Authority
-		ok        bool
-	)
-	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
-		message := model.ErrorMessage{
-			Message: "cannot extract user id",
-		}
-
-		return ctx.Status(401).JSON(message)
+		return ctx.Status(400).JSON(message)
 	}
 
-	fmt.Printf("%s\n", authority.UserID)
-
-	response, err := c.dbClient.GetSecondProjects(ctx.Context(), authority.UserID)
+	response, err := c.dbClient.GetProjects(ctx.Context(), authority.UserID, opts)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return listError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 }
@@ -181,15 +171,13 @@ func (c Controller) AddSecondReaderHandler(ctx *fiber.Ctx) error {
 
 	err := c.dbClient.AddSecondReader(ctx.Context(), authority.UserID, id)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return projectError(ctx, err)
 	}
 	return ctx.Status(200).JSON("successful added second reader")
 }
 
-func (c Controller) GetProjectIDHandler(ctx *fiber.Ctx) error { //get all projects
+// GetMyProjectsHandler returns every project the user is a member of, students in group projects have more than one
+func (c Controller) GetMyProjectsHandler(ctx *fiber.Ctx) error {
 
 	var (
 		authority security.Authority
@@ -203,9 +191,7 @@ func (c Controller) GetProjectIDHandler(ctx *fiber.Ctx) error { //get all projec
 		return ctx.Status(401).JSON(message)
 	}
 
-	fmt.Printf("%s\n", authority.UserID)
-
-	response, err := c.dbClient.GetProjectID(ctx.Context(), authority.UserID)
+	response, err := c.dbClient.GetMyProjects(ctx.Context(), authority.UserID)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
@@ -227,8 +213,7 @@ func (c Controller) GetProjectNameHandler(ctx *fiber.Ctx) error { //get all proj
 	return ctx.Status(200).JSON(response)
 }
 
-func (c Controller) GetSecondReaderStatusHandler(ctx *fiber.Ctx) error { //get all projects
-
+func (c Controller) CreateApplicationHandler(ctx *fiber.Ctx) error {
 	var (
 		authority security.Authority
 		ok        bool
@@ -241,35 +226,27 @@ func (c Controller) GetSecondReaderStatusHandler(ctx *fiber.Ctx) error { //get a
 		return ctx.Status(401).JSON(message)
 	}
 
-	response, err := c.dbClient.GetSecondReaderStatus(ctx.Context(), ctx.Params("id"), authority.UserID)
-	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
-	}
-	return ctx.Status(200).JSON(response)
-}
+	fmt.Printf("%s", authority.UserID)
 
-func (c Controller) CreateApplicationHandler(ctx *fiber.Ctx) error {
+	// Read the request body, multipart requests carry the application JSON in the application field next to the files
 	var (
-		authority security.Authority
-		ok        bool
+		body  []byte
+		files []spooledFile
 	)
-	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
-		message := model.ErrorMessage{
-			Message: "cannot extract user id",
+	if isMultipart(ctx) {
+		upload, err := c.readUpload(ctx)
+		if err != nil {
+			return uploadError(ctx, err)
 		}
-
-		return ctx.Status(401).JSON(message)
+		defer upload.cleanup()
+		body, files = upload.fields["application"], upload.files
+	} else {
+		body = ctx.Body()
 	}
 
-	fmt.Printf("%s", authority.UserID)
-
-	// Read the request body
 	var application model.ApplicationData
 
-	err := json.Unmarshal(ctx.Body(), &application)
+	err := json.Unmarshal(body, &application)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
@@ -277,6 +254,11 @@ func (c Controller) CreateApplicationHandler(ctx *fiber.Ctx) error {
 		return ctx.Status(400).JSON(message)
 	}
 
+	attachments, err := c.storeUploads(ctx.Context(), files)
+	if err != nil {
+		return uploadError(ctx, err)
+	}
+
 	// Translate it to the db request
 	applicationRequest := db.Application{
 		ID:           application.ID,
@@ -284,17 +266,18 @@ func (c Controller) CreateApplicationHandler(ctx *fiber.Ctx) error {
 		SupervisorID: application.SupervisorID,
 		Heading:      application.Heading,
 		Description:  application.Description,
-		Accepted:     false,
-		Declined:     false,
+		Status:       lifecycle.Status(application.Status), //only draft is honoured, everything else is submitted
+		Waitlist:     application.Waitlist,
+		ProposalID:   application.ProposalID,
+		Attachments:  attachments,
+		Teammates:    application.Teammates,
 	}
 	println(ctx)
 	// Execute db request
 	err = c.dbClient.CreateApplication(ctx.Context(), applicationRequest, authority.UserID)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		c.discardAttachments(ctx.Context(), attachments)
+		return uploadError(ctx, err)
 	}
 
 	return ctx.SendStatus(204)
@@ -455,6 +438,18 @@ func (c Controller) CreateStudentHandler(ctx *fiber.Ctx) error {
 //}
 
 func (c Controller) DeclineApplicationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
 	var application model.ApplicationData
 
 	err := json.Unmarshal(ctx.Body(), &application)
@@ -472,17 +467,13 @@ func (c Controller) DeclineApplicationHandler(ctx *fiber.Ctx) error {
 		SupervisorID: application.SupervisorID,
 		Heading:      application.Heading,
 		Description:  application.Description,
-		Accepted:     false,
-		Declined:     false,
+		Reason:       application.Reason,
 	}
 
 	// Execute db request
-	err = c.dbClient.DeclineApplication(ctx.Context(), applicationRequest)
+	err = c.dbClient.DeclineApplication(ctx.Context(), applicationRequest, authority.UserID)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return applicationError(ctx, err)
 	}
 
 	return ctx.SendStatus(204)
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TransitionApplicationHandler returns a handler moving the application given by the id parameter to the status,
+// an optional JSON body can carry the reason for the change
+func (c Controller) TransitionApplicationHandler(to lifecycle.Status) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var (
+			authority security.Authority
+			ok        bool
+		)
+		if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+			message := model.ErrorMessage{
+				Message: "cannot extract user id",
+			}
+
+			return ctx.Status(401).JSON(message)
+		}
+
+		var request model.TransitionRequest
+		if len(ctx.Body()) > 0 {
+			err := json.Unmarshal(ctx.Body(), &request)
+			if err != nil {
+				message := model.ErrorMessage{
+					Message: err.Error(),
+				}
+				return ctx.Status(400).JSON(message)
+			}
+		}
+
+		application, err := c.dbClient.TransitionApplication(ctx.Context(), ctx.Params("id"), to, authority.UserID, request.Reason)
+		if err != nil {
+			return applicationError(ctx, err)
+		}
+
+		return ctx.Status(200).JSON(model.ApplicationData{
+			ID:           application.ID,
+			StudentID:    application.StudentID,
+			SupervisorID: application.SupervisorID,
+			Heading:      application.Heading,
+			Description:  application.Description,
+			Status:       string(application.Status),
+			Accepted:     application.Status == lifecycle.Accepted,
+			Declined:     application.Status == lifecycle.Declined,
+			Reason:       application.Reason,
+		})
+	}
+}
+
+func (c Controller) GetApplicationHistoryHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetApplicationHistory(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// applicationError maps application lifecycle errors to a response
+func applicationError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrApplicationNotFound), errors.Is(err, db.ErrProposalNotFound), errors.Is(err, db.ErrProjectNotFound),
+		errors.Is(err, db.ErrInviteNotFound), errors.Is(err, db.ErrTemplateNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrNotApplicationParticipant), errors.Is(err, lifecycle.ErrActorNotAllowed), errors.Is(err, db.ErrNotProjectMember):
+		return ctx.Status(403).JSON(message)
+	case errors.Is(err, lifecycle.ErrInvalidTransition), errors.Is(err, db.ErrSupervisorFull),
+		errors.Is(err, db.ErrProposalNotPublished), errors.Is(err, db.ErrProposalFull),
+		errors.Is(err, db.ErrRoundClosed), errors.Is(err, db.ErrApplicationCapReached), errors.Is(err, db.ErrResponseDeadlinePassed),
+		errors.Is(err, db.ErrThreadClosed), errors.Is(err, db.ErrTeamIncomplete):
+		return ctx.Status(409).JSON(message)
+	case errors.Is(err, db.ErrNotSupervisor), errors.Is(err, db.ErrTeamTooLarge), errors.Is(err, db.ErrInvalidTeammate):
+		return ctx.Status(400).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/Simplyphotons/fyp.git/storage"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxUploadField = 64 << 10 //form fields next to files, e.g. the application JSON
+
+var (
+	errUploadTooLarge = errors.New("file is too large")
+	errUploadType     = errors.New("file type is not allowed")
+	errUploadInvalid  = errors.New("invalid upload")
+	errNoStorage      = errors.New("attachments are not enabled")
+)
+
+// spooledFile is an uploaded file that passed the limits and waits in a temporary file until it is stored
+type spooledFile struct {
+	file        *os.File
+	name        string
+	contentType string
+	size        int64
+	sha256      string
+}
+
+type upload struct {
+	fields map[string][]byte
+	files  []spooledFile
+}
+
+func (u *upload) cleanup() {
+	for _, f := range u.files {
+		f.file.Close()
+		os.Remove(f.file.Name())
+	}
+}
+
+func isMultipart(ctx *fiber.Ctx) bool {
+	return strings.HasPrefix(string(ctx.Request().Header.ContentType()), fiber.MIMEMultipartForm)
+}
+
+// readUpload streams a multipart body part by part. Files are sniffed, size limited and hashed on the way into
+// a temporary file so that large uploads are never held in memory.
+func (c Controller) readUpload(ctx *fiber.Ctx) (*upload, error) {
+	_, params, err := mime.ParseMediaType(string(ctx.Request().Header.ContentType()))
+	if err != nil || params["boundary"] == "" {
+		return nil, fmt.Errorf("%w: missing multipart boundary", errUploadInvalid)
+	}
+
+	body := ctx.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(ctx.Body())
+	}
+	reader := multipart.NewReader(body, params["boundary"])
+
+	result := &upload{fields: map[string][]byte{}}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			result.cleanup()
+			return nil, fmt.Errorf("%w: %v", errUploadInvalid, err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadField+1))
+			if err == nil && len(value) > maxUploadField {
+				err = fmt.Errorf("%w: field %s is too large", errUploadInvalid, part.FormName())
+			}
+			if err != nil {
+				result.cleanup()
+				return nil, err
+			}
+			result.fields[part.FormName()] = value
+			continue
+		}
+
+		if len(result.files) == db.MaxAttachments {
+			result.cleanup()
+			return nil, db.ErrTooManyAttachments
+		}
+		file, err := c.spool(part)
+		if err != nil {
+			result.cleanup()
+			return nil, err
+		}
+		result.files = append(result.files, *file)
+	}
+}
+
+func (c Controller) spool(part *multipart.Part) (*spooledFile, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", errUploadInvalid, err)
+	}
+	head = head[:n]
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head)) //the declared type is not trusted
+	if !slices.Contains(c.attachmentTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s, allowed are %s", errUploadType, contentType, strings.Join(c.attachmentTypes, ", "))
+	}
+
+	file, err := os.CreateTemp(c.spoolDir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(io.MultiReader(bytes.NewReader(head), part), c.attachmentMaxBytes+1))
+	if err == nil && size > c.attachmentMaxBytes {
+		err = fmt.Errorf("%w: the limit is %d bytes", errUploadTooLarge, c.attachmentMaxBytes)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &spooledFile{
+		file:        file,
+		name:        filepath.Base(part.FileName()),
+		contentType: contentType,
+		size:        size,
+		sha256:      hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// storeUploads moves the spooled files into the blob store, nothing is left behind when one of them fails
+func (c Controller) storeUploads(ctx context.Context, files []spooledFile) ([]db.Attachment, error) {
+	if len(files) > 0 && c.store == nil {
+		return nil, errNoStorage
+	}
+	attachments := []db.Attachment{}
+	for _, f := range files {
+		id := db.GenerateUUID()
+		attachment := db.Attachment{
+			ID:          id,
+			FileName:    f.name,
+			ContentType: f.contentType,
+			Size:        f.size,
+			SHA256:      f.sha256,
+			StorageKey:  "attachments/" + id,
+		}
+		if err := c.store.Put(ctx, attachment.StorageKey, f.file, f.size, f.contentType); err != nil {
+			c.discardAttachments(ctx, attachments)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (c Controller) discardAttachments(ctx context.Context, attachments []db.Attachment) {
+	for _, attachment := range attachments {
+		if err := c.store.Delete(ctx, attachment.StorageKey); err != nil {
+			slog.Error("cannot delete orphaned attachment", "key", attachment.StorageKey, "error", err)
+		}
+	}
+}
+
+func (c Controller) AddAttachmentHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	if !isMultipart(ctx) {
+		message := model.ErrorMessage{
+			Message: "expected a multipart/form-data upload",
+		}
+		return ctx.Status(415).JSON(message)
+	}
+	upload, err := c.readUpload(ctx)
+	if err != nil {
+		return uploadError(ctx, err)
+	}
+	defer upload.cleanup()
+	if len(upload.files) == 0 {
+		return uploadError(ctx, fmt.Errorf("%w: no file in the request", errUploadInvalid))
+	}
+
+	attachments, err := c.storeUploads(ctx.Context(), upload.files)
+	if err != nil {
+		return uploadError(ctx, err)
+	}
+	err = c.dbClient.AddAttachments(ctx.Context(), ctx.Params("id"), authority.UserID, attachments)
+	if err != nil {
+		c.discardAttachments(ctx.Context(), attachments)
+		return uploadError(ctx, err)
+	}
+	return ctx.Status(201).JSON(c.toAttachments(attachments, ctx.Params("id")))
+}
+
+// GetAttachmentsHandler lists the files of an application with download links that expire after a while
+func (c Controller) GetAttachmentsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	attachments, err := c.dbClient.GetAttachments(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(c.toAttachments(attachments, ctx.Params("id")))
+}
+
+// DownloadAttachmentHandler serves a file to anyone holding a valid link, the signature is the authorization
+func (c Controller) DownloadAttachmentHandler(ctx *fiber.Ctx) error {
+	forbidden := model.ErrorMessage{
+		Message: "download link is invalid or has expired",
+	}
+	expires, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ctx.Status(403).JSON(forbidden)
+	}
+
+	attachment, err := c.dbClient.GetAttachment(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, db.ErrAttachmentNotFound) {
+			return ctx.Status(403).JSON(forbidden) //do not reveal which ids exist
+		}
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	expected := c.signDownload(attachment.ID, attachment.ApplicationID, expires)
+	if !hmac.Equal([]byte(expected), []byte(ctx.Query("signature"))) {
+		return ctx.Status(403).JSON(forbidden)
+	}
+
+	if c.store == nil {
+		return uploadError(ctx, errNoStorage)
+	}
+	r, err := c.store.Get(ctx.Context(), attachment.StorageKey)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return ctx.Status(404).JSON(message)
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	ctx.Set(fiber.HeaderContentType, attachment.ContentType)
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	ctx.Set(fiber.HeaderETag, `"`+attachment.SHA256+`"`)
+	ctx.Set("X-Checksum-Sha256", attachment.SHA256)
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	return ctx.SendStream(r, int(attachment.Size))
+}
+
+// signDownload binds a download link to the attachment, its application and the expiry time
+func (c Controller) signDownload(attachmentID string, applicationID string, expires int64) string {
+	mac := hmac.New(sha256.New, c.downloadSecret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", attachmentID, applicationID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c Controller) toAttachments(attachments []db.Attachment, applicationID string) []model.Attachment {
+	expires := time.Now().Add(c.downloadTTL).Unix()
+	result := []model.Attachment{}
+	for _, attachment := range attachments {
+		query := url.Values{
+			"expires":   {strconv.FormatInt(expires, 10)},
+			"signature": {c.signDownload(attachment.ID, applicationID, expires)},
+		}
+		result = append(result, model.Attachment{
+			ID:          attachment.ID,
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			SHA256:      attachment.SHA256,
+			UploadedBy:  attachment.UploadedBy,
+			DownloadURL: "/downloadAttachment/" + attachment.ID + "?" + query.Encode(),
+			ExpiresAt:   time.Unix(expires, 0).UTC(),
+		})
+	}
+	return result
+}
+
+func uploadError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, errUploadTooLarge):
+		return ctx.Status(413).JSON(message)
+	case errors.Is(err, errUploadType):
+		return ctx.Status(415).JSON(message)
+	case errors.Is(err, errUploadInvalid), errors.Is(err, db.ErrTooManyAttachments):
+		return ctx.Status(400).JSON(message)
+	case errors.Is(err, errNoStorage):
+		return ctx.Status(501).JSON(message)
+	default:
+		return applicationError(ctx, err)
+	}
+}
@@ -2,42 +2,124 @@ package handlers
 
 import (
 	"context"
+	"github.com/Simplyphotons/fyp.git/allocation"
 	"github.com/Simplyphotons/fyp.git/auth0"
 	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/ganttfile"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
 	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/storage"
+	"os"
+	"time"
 )
 
 type DBClient interface {
 	GetGanttItem(ctx context.Context, milestoneIdentifier string) ([]model.Gantt, error)
 	CreateProject(ctx context.Context, project db.Application, supervisor_id string) error
 	CreateApplication(ctx context.Context, application db.Application, student_id string) error
-	DeclineApplication(ctx context.Context, application db.Application) error
-	GetQuestions(ctx context.Context) ([]model.Question, error)
-	GetSupervisors(ctx context.Context) ([]model.UserData, error)
+	DeclineApplication(ctx context.Context, application db.Application, userID string) error
+	TransitionApplication(ctx context.Context, appID string, to lifecycle.Status, actorID string, reason string) (*db.Application, error)
+	GetApplicationHistory(ctx context.Context, appID string, userID string) ([]model.ApplicationTransition, error)
+	GetQuestions(ctx context.Context, opts db.ListOptions) (*model.Page[model.Question], error)
+	GetSupervisors(ctx context.Context, opts db.ListOptions) (*model.Page[model.UserData], error)
 	GetHasProjectStatus(ctx context.Context, userID string) (bool, error)
-	GetApplications(ctx context.Context, supervisor_id string) ([]model.ApplicationData, error)
+	GetApplications(ctx context.Context, supervisor_id string, opts db.ListOptions) (*model.Page[model.ApplicationData], error)
 	GetApplicationsForStudent(ctx context.Context, student_id string) ([]model.ApplicationData, error)
 	GetSpecificApplications(ctx context.Context, appID string) ([]model.ApplicationData, error)
-	GetProjects(ctx context.Context, supervisor_id string) ([]model.ProjectData, error)
-	GetProjectID(ctx context.Context, userID string) (*model.ProjectData, error)
+	GetProjects(ctx context.Context, userID string, opts db.ListOptions) (*model.Page[model.ProjectData], error)
+	GetMyProjects(ctx context.Context, userID string) ([]model.ProjectData, error)
 	GetProjectName(ctx context.Context, projectID string) (*model.ProjectData, error)
 	GetFeedback(ctx context.Context, ganttID string) (string, error)
 	GetUsername(ctx context.Context, userId string) (string, error)
 	NewQuestion(ctx context.Context, question db.Question) error
 	NewAnswer(ctx context.Context) error
 	GetGantt(ctx context.Context, projectIdentifier string) ([]model.GanttChartRow, error)
-	CreateGanttItem(ctx context.Context, gantt db.Gantt) error
-	UpdateFeedback(ctx context.Context, gantt db.Gantt, userID string) error
-	DisableAlert(ctx context.Context, userID string, ganttID string) error
-	DeleteGanttItem(id string) error
+	CreateGanttItem(ctx context.Context, gantt db.Gantt, userID string) error
+	GetProjectGantt(ctx context.Context, projectID string, userID string) (string, []model.GanttChartRow, error)
+	GetAnyProjectGantt(ctx context.Context, projectID string) (string, []model.GanttChartRow, error)
+	ImportGantt(ctx context.Context, projectID string, userID string, tasks []ganttfile.Task) ([]string, error)
+	UpdateFeedback(ctx context.Context, gantt db.Gantt, userID string) (int, error)
+	MarkFeedbackRead(ctx context.Context, itemID string, userID string) error
+	CreateCalendarFeed(ctx context.Context, userID string) (string, *model.CalendarFeed, error)
+	GetCalendarFeed(ctx context.Context, userID string) (*model.CalendarFeed, error)
+	RevokeCalendarFeed(ctx context.Context, userID string) error
+	GetCalendarFeedProjects(ctx context.Context, token string) ([]model.ProjectData, error)
+	MarkAllFeedbackRead(ctx context.Context, userID string, projectID string) error
+	GetUnreadFeedback(ctx context.Context, userID string) (*model.UnreadFeedback, error)
+	GetProjectUnreadFeedback(ctx context.Context, projectID string, userID string) (*model.UnreadFeedback, error)
+	DeleteGanttItem(ctx context.Context, id string, userID string, version int) error
 	AddSecondReader(ctx context.Context, readerID string, appID string) error
-	GetAllAcceptedRequests(ctx context.Context) ([]model.ApplicationData, error)
+	GetAllAcceptedRequests(ctx context.Context, opts db.ListOptions) (*model.Page[model.ApplicationData], error)
 	CreateSupervisorUser(ctx context.Context, user db.User) error
 	CreateStudentUser(ctx context.Context, user db.User) error
-	GetSecondProjects(ctx context.Context, supervisor_id string) ([]model.ProjectData, error)
-	GetSecondReaderStatus(ctx context.Context, ProjectID string, userID string) (bool, error)
-	CompleteGanttItem(ctx context.Context, gantt db.Gantt) error
+	GetProjectMembers(ctx context.Context, projectID string, userID string) ([]model.ProjectMember, error)
+	AddProjectMember(ctx context.Context, projectID string, actorID string, userID string, role db.ProjectRole) error
+	RemoveProjectMember(ctx context.Context, projectID string, actorID string, userID string) error
+	RespondTeamInvite(ctx context.Context, appID string, studentID string, confirm bool) error
+	GetTeamInvites(ctx context.Context, studentID string) ([]model.TeamInvite, error)
+	GetApplicationTeam(ctx context.Context, appID string, userID string) ([]model.TeamMember, error)
+	SetGanttContributions(ctx context.Context, itemID string, userID string, contributions []db.Contribution, version int) (int, error)
+	AddGanttDependency(ctx context.Context, dependency db.Dependency, userID string) error
+	RemoveGanttDependency(ctx context.Context, predecessorID string, successorID string, userID string) error
+	CompleteGanttItem(ctx context.Context, gantt db.Gantt, userID string) (int, error)
+	SetGanttStatus(ctx context.Context, itemID string, userID string, status db.GanttStatus, percentComplete int, version int) (int, error)
+	EditGanttItem(ctx context.Context, itemID string, userID string, patch db.GanttPatch, version int) error
+	AddFeedbackComment(ctx context.Context, itemID string, userID string, parentID string, body string, version int) (*model.FeedbackComment, int, error)
+	GetFeedbackComments(ctx context.Context, itemID string, userID string, opts db.ListOptions) (*model.Page[model.FeedbackComment], error)
+	EditFeedbackComment(ctx context.Context, itemID string, commentID string, userID string, body string) error
+	DeleteFeedbackComment(ctx context.Context, itemID string, commentID string, userID string) error
 	Verify(ctx context.Context, userID string) (*model.Verify, error)
+	SetStudentPreferences(ctx context.Context, studentID string, supervisorIDs []string) error
+	GetStudentPreferences(ctx context.Context, studentID string) ([]model.Preference, error)
+	SetApplicantRanking(ctx context.Context, supervisorID string, studentIDs []string) error
+	GetApplicantRanking(ctx context.Context, supervisorID string) ([]model.Preference, error)
+	GetAllocationInput(ctx context.Context) ([]allocation.Student, []allocation.Supervisor, error)
+	SaveAllocationRun(ctx context.Context, createdBy string, result allocation.Result) (string, error)
+	GetAllocationRun(ctx context.Context, runID string) (*model.AllocationRun, error)
+	CommitAllocationRun(ctx context.Context, runID string, committedBy string) error
+	SetExpertise(ctx context.Context, supervisorID string, tags []string) error
+	GetExpertise(ctx context.Context, supervisorID string) ([]string, error)
+	GetReaderAllocationInput(ctx context.Context) ([]allocation.ReadingProject, []allocation.Reader, error)
+	SaveReaderAllocationRun(ctx context.Context, createdBy string, result allocation.ReaderResult) (string, error)
+	GetReaderAllocationRun(ctx context.Context, runID string) (*model.ReaderAllocationRun, error)
+	CommitReaderAllocationRun(ctx context.Context, runID string, committedBy string) error
+	DeclareConflict(ctx context.Context, staffID string, userID string, reason string) error
+	WithdrawConflict(ctx context.Context, staffID string, userID string) error
+	GetConflicts(ctx context.Context, staffID string) ([]model.Conflict, error)
+	AssignSecondReader(ctx context.Context, projectID string, readerID string, coordinatorID string, overrideReason string) error
+	GetAuditLog(ctx context.Context, opts db.ListOptions) (*model.Page[model.AuditEntry], error)
+	SetSupervisorCapacity(ctx context.Context, supervisorID string, academicYear string, capacity int) error
+	GetSupervisorCapacity(ctx context.Context, supervisorID string) ([]model.Capacity, error)
+	GetNotifications(ctx context.Context, userID string, opts db.ListOptions) (*model.Page[model.Notification], error)
+	ReadNotification(ctx context.Context, userID string, notificationID string) error
+	CreateProposal(ctx context.Context, proposal db.Proposal) (string, error)
+	UpdateProposal(ctx context.Context, proposal db.Proposal) error
+	SetProposalStatus(ctx context.Context, proposalID string, supervisorID string, to db.ProposalStatus) error
+	DeleteProposal(ctx context.Context, proposalID string, supervisorID string) error
+	GetProposal(ctx context.Context, proposalID string, userID string) (*model.Proposal, error)
+	GetProposals(ctx context.Context, userID string, mine bool, opts db.ListOptions) (*model.Page[model.Proposal], error)
+	AddAttachments(ctx context.Context, appID string, studentID string, attachments []db.Attachment) error
+	GetAttachments(ctx context.Context, appID string, userID string) ([]db.Attachment, error)
+	GetAttachment(ctx context.Context, attachmentID string) (*db.Attachment, error)
+	CreateRound(ctx context.Context, round db.Round) (string, error)
+	UpdateRound(ctx context.Context, round db.Round) error
+	GetRounds(ctx context.Context) ([]model.Round, error)
+	GetRoundStatus(ctx context.Context, userID string) (*model.RoundStatus, error)
+	CreateMilestoneTemplate(ctx context.Context, template db.MilestoneTemplate, coordinatorID string) (string, error)
+	UpdateMilestoneTemplate(ctx context.Context, template db.MilestoneTemplate, coordinatorID string) (*model.TemplateUpdate, error)
+	DeleteMilestoneTemplate(ctx context.Context, templateID string, coordinatorID string) error
+	GetMilestoneTemplates(ctx context.Context) ([]model.MilestoneTemplate, error)
+	SetTemplateFollow(ctx context.Context, projectID string, userID string, follow bool) error
+	SendApplicationMessage(ctx context.Context, appID string, authorID string, body string) (*model.Message, error)
+	GetApplicationMessages(ctx context.Context, appID string, userID string, opts db.ListOptions) (*model.Page[model.Message], error)
+	GetProjectMessages(ctx context.Context, projectID string, userID string, opts db.ListOptions) (*model.Page[model.Message], error)
+	ReadApplicationMessages(ctx context.Context, appID string, userID string) error
+	CreateInterviewSlots(ctx context.Context, supervisorID string, slots []db.InterviewSlot) ([]string, error)
+	DeleteInterviewSlot(ctx context.Context, slotID string, supervisorID string) error
+	GetInterviewSlots(ctx context.Context, userID string, opts db.ListOptions) (*model.Page[model.InterviewSlot], error)
+	GetInterviewSlot(ctx context.Context, slotID string, userID string) (*model.InterviewSlot, error)
+	BookInterviewSlot(ctx context.Context, slotID string, appID string, studentID string) (*model.InterviewSlot, error)
+	CancelInterviewBooking(ctx context.Context, slotID string, userID string) error
 }
 
 type Auth0Client interface {
@@ -47,15 +129,65 @@ type Auth0Client interface {
 }
 
 type Controller struct {
-	dbClient         DBClient
-	auth0Client      Auth0Client
-	supervisorRoleID string
+	dbClient           DBClient
+	auth0Client        Auth0Client
+	supervisorRoleID   string
+	maxPreferences     int
+	store              storage.Store
+	spoolDir           string
+	attachmentMaxBytes int64
+	attachmentTypes    []string
+	downloadSecret     []byte
+	downloadTTL        time.Duration
 }
 
-func New(client DBClient, auth0Client Auth0Client, supervisorRoleID string) *Controller {
-	return &Controller{
-		dbClient:         client,
-		auth0Client:      auth0Client,
-		supervisorRoleID: supervisorRoleID,
+// Option type for configuring optional controller settings
+type Option func(*Controller)
+
+// MaxPreferences limits how many supervisors a student may rank
+func MaxPreferences(n int) Option {
+	return func(c *Controller) {
+		c.maxPreferences = n
+	}
+}
+
+// Storage sets where attachments are kept, uploads are spooled to spoolDir before they are stored
+func Storage(store storage.Store, spoolDir string) Option {
+	return func(c *Controller) {
+		c.store = store
+		c.spoolDir = spoolDir
+	}
+}
+
+// AttachmentLimits sets the maximum size of an attachment and the content types accepted
+func AttachmentLimits(maxBytes int64, contentTypes []string) Option {
+	return func(c *Controller) {
+		c.attachmentMaxBytes = maxBytes
+		c.attachmentTypes = contentTypes
+	}
+}
+
+// DownloadLinks sets the key signing attachment download links and how long a link stays valid
+func DownloadLinks(secret []byte, ttl time.Duration) Option {
+	return func(c *Controller) {
+		c.downloadSecret = secret
+		c.downloadTTL = ttl
+	}
+}
+
+func New(client DBClient, auth0Client Auth0Client, supervisorRoleID string, opts ...Option) *Controller {
+	c := &Controller{
+		dbClient:           client,
+		auth0Client:        auth0Client,
+		supervisorRoleID:   supervisorRoleID,
+		maxPreferences:     5,
+		spoolDir:           os.TempDir(),
+		attachmentMaxBytes: 10 << 20,
+		attachmentTypes:    []string{"application/pdf"},
+		downloadTTL:        15 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
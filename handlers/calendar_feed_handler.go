@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/calendar"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// milestoneReminder is how long before the end of an item calendars remind of it
+const milestoneReminder = 24 * time.Hour
+
+// CreateCalendarFeedHandler gives the user a new secret feed url, a url created before stops working
+func (c Controller) CreateCalendarFeedHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	token, response, err := c.dbClient.CreateCalendarFeed(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	response.URL = "/calendar/" + token + ".ics"
+	return ctx.Status(201).JSON(response)
+}
+
+func (c Controller) GetCalendarFeedHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetCalendarFeed(ctx.Context(), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) RevokeCalendarFeedHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.RevokeCalendarFeed(ctx.Context(), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// CalendarFeedHandler serves the milestones of every project of the feed owner to calendar applications, the
+// secret token in the url stands in for the login they cannot do
+func (c Controller) CalendarFeedHandler(ctx *fiber.Ctx) error {
+	projects, err := c.dbClient.GetCalendarFeedProjects(ctx.Context(), ctx.Params("token"))
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	feed := calendar.Calendar{
+		Name:   "Project milestones",
+		Method: calendar.Publish,
+	}
+	now := time.Now()
+	for _, project := range projects {
+		rows, err := c.dbClient.GetGantt(ctx.Context(), project.ID)
+		if err != nil {
+			message := model.ErrorMessage{
+				Message: err.Error(),
+			}
+			return ctx.Status(500).JSON(message)
+		}
+		for _, row := range rows {
+			for _, item := range row.Content {
+				feed.Events = append(feed.Events, milestoneEvent(project, item, now))
+			}
+		}
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	ctx.Set(fiber.HeaderCacheControl, "private, max-age=900")
+	return ctx.Status(200).Send(feed.Marshal())
+}
+
+// milestoneEvent turns a gantt item into an event. The uid stays the same when the item changes so calendars move
+// the event, the version of the item serves as the sequence.
+func milestoneEvent(project model.ProjectData, item model.Gantt, stamp time.Time) calendar.Event {
+	details := []string{"Project: " + project.Name, fmt.Sprintf("Status: %s, %d%% complete", strings.ReplaceAll(item.Status, "_", " "), item.PercentComplete)}
+	if description := strings.TrimSpace(item.Description); description != "" {
+		details = append(details, description)
+	}
+	links := strings.TrimSpace(item.Links)
+	if links != "" {
+		details = append(details, "Links: "+links)
+	}
+
+	event := calendar.Event{
+		UID:         "gantt-" + item.ID + "@fyp",
+		Sequence:    item.Version,
+		Stamp:       stamp,
+		Start:       item.StartDate,
+		End:         item.EndDate,
+		Summary:     project.Name + ": " + item.GanttName,
+		Description: strings.Join(details, "\n"),
+	}
+	if link, err := url.Parse(links); err == nil && (link.Scheme == "http" || link.Scheme == "https") && !strings.ContainsAny(links, " \n") {
+		event.URL = link.String()
+	}
+	if item.Status != string(db.GanttComplete) {
+		event.Alarms = []calendar.Alarm{{Before: milestoneReminder, FromEnd: true, Description: item.GanttName + " is due tomorrow"}}
+	}
+	return event
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Simplyphotons/fyp.git/academic"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (c Controller) SetCapacityHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.Capacity
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	if request.AcademicYear == "" {
+		request.AcademicYear = academic.Current()
+	}
+	fieldErrors := []model.FieldError{}
+	if _, err = academic.Start(request.AcademicYear); err != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "academicYear", Message: err.Error()})
+	}
+	if request.Capacity < 0 {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "capacity", Message: "must not be negative"})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid capacity",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.SetSupervisorCapacity(ctx.Context(), authority.UserID, request.AcademicYear, request.Capacity)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetCapacityHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetSupervisorCapacity(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
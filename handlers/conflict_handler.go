@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+const maxConflictReason = 500
+
+// DeclareConflictHandler records a student or supervisor the caller must not second read for
+func (c Controller) DeclareConflictHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.ConflictRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	fieldErrors := []model.FieldError{}
+	if request.UserID == "" || request.UserID == authority.UserID {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "userID", Message: "is required and cannot be yourself"})
+	}
+	if request.Reason == "" || len(request.Reason) > maxConflictReason {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "reason", Message: fmt.Sprintf("is required and at most %d characters", maxConflictReason)})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid conflict of interest",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.DeclareConflict(ctx.Context(), authority.UserID, request.UserID, request.Reason)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetConflictsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetConflicts(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) WithdrawConflictHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.WithdrawConflict(ctx.Context(), authority.UserID, ctx.Params("userID"))
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// AssignSecondReaderHandler lets a coordinator pick the second reader of a project, an override reason assigns
+// a reader despite a conflict of interest
+func (c Controller) AssignSecondReaderHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.AssignReaderRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	request.OverrideReason = strings.TrimSpace(request.OverrideReason)
+	fieldErrors := []model.FieldError{}
+	if request.ReaderID == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "readerID", Message: "is required"})
+	}
+	if len(request.OverrideReason) > maxConflictReason {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "overrideReason", Message: fmt.Sprintf("must be at most %d characters", maxConflictReason)})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid second reader",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.AssignSecondReader(ctx.Context(), ctx.Params("id"), request.ReaderID, authority.UserID, request.OverrideReason)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetAuditLogHandler(ctx *fiber.Ctx) error {
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetAuditLog(ctx.Context(), opts)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
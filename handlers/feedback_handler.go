@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/markdown"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateCommentBody trims a comment and checks its length, comments are as long as messages at most
+func validateCommentBody(body string) (string, []model.FieldError) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > maxMessageLength {
+		return body, []model.FieldError{{Field: "body", Message: fmt.Sprintf("must be between 1 and %d characters", maxMessageLength)}}
+	}
+	return body, nil
+}
+
+// AddFeedbackCommentHandler adds a comment to a gantt item, or a reply to one of its comments
+func (c Controller) AddFeedbackCommentHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	var request model.FeedbackCommentRequest
+	err = json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	body, fieldErrors := validateCommentBody(request.Body)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid comment",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, version, err := c.dbClient.AddFeedbackComment(ctx.Context(), ctx.Params("id"), authority.UserID, request.ParentID, body, version)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	response.HTML = markdown.ToHTML(response.Body)
+	ctx.Set(fiber.HeaderETag, ganttETag(version))
+	return ctx.Status(201).JSON(response)
+}
+
+func (c Controller) GetFeedbackCommentsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetFeedbackComments(ctx.Context(), ctx.Params("id"), authority.UserID, opts)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidListOptions) {
+			return listError(ctx, err)
+		}
+		return projectError(ctx, err)
+	}
+	for i := range response.Items {
+		response.Items[i].HTML = markdown.ToHTML(response.Items[i].Body)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// EditFeedbackCommentHandler lets the author of a comment rewrite it, the comment is marked as edited
+func (c Controller) EditFeedbackCommentHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.FeedbackCommentRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	body, fieldErrors := validateCommentBody(request.Body)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid comment",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.EditFeedbackComment(ctx.Context(), ctx.Params("id"), ctx.Params("commentID"), authority.UserID, body)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) DeleteFeedbackCommentHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.DeleteFeedbackComment(ctx.Context(), ctx.Params("id"), ctx.Params("commentID"), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// GetUnreadFeedbackHandler counts the comments the user has not read yet in each of their projects
+func (c Controller) GetUnreadFeedbackHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetUnreadFeedback(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// GetProjectUnreadFeedbackHandler counts the comments the user has not read yet on each item of a project
+func (c Controller) GetProjectUnreadFeedbackHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetProjectUnreadFeedback(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// MarkAllFeedbackReadHandler marks every comment as read by the user, only in one project with ?projectID=
+func (c Controller) MarkAllFeedbackReadHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.MarkAllFeedbackRead(ctx.Context(), authority.UserID, ctx.Query("projectID"))
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/ganttchart"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"mime"
+	"slices"
+	"time"
+)
+
+// coordinatorScope lets coordinators see the chart of every project, others only see projects they are members of
+const coordinatorScope = "read:admin"
+
+// GetGanttChartHandler draws the gantt chart of a project as an SVG image or, with ?format=pdf, as a PDF document
+// for attaching to reports
+func (c Controller) GetGanttChartHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	format := ctx.Query("format", "svg")
+	if format != "svg" && format != "pdf" {
+		message := model.ValidationErrorMessage{
+			Message: "invalid chart",
+			Fields:  []model.FieldError{{Field: "format", Message: "must be svg or pdf"}},
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	var (
+		name string
+		rows []model.GanttChartRow
+		err  error
+	)
+	if slices.Contains(authority.Scopes, coordinatorScope) {
+		name, rows, err = c.dbClient.GetAnyProjectGantt(ctx.Context(), ctx.Params("id"))
+	} else {
+		name, rows, err = c.dbClient.GetProjectGantt(ctx.Context(), ctx.Params("id"), authority.UserID)
+	}
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	chart := ganttChart(name, rows, time.Now().UTC().Truncate(24*time.Hour))
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("inline", map[string]string{"filename": name + "." + format}))
+	if format == "pdf" {
+		ctx.Set(fiber.HeaderContentType, "application/pdf")
+		return ctx.Status(200).Send(chart.PDF())
+	}
+	ctx.Set(fiber.HeaderContentType, "image/svg+xml")
+	return ctx.Status(200).Send(chart.SVG())
+}
+
+func ganttChart(name string, rows []model.GanttChartRow, today time.Time) ganttchart.Chart {
+	chart := ganttchart.Chart{
+		Title: name,
+		Today: today,
+	}
+	for _, row := range rows {
+		for _, item := range row.Content {
+			bar := ganttchart.Item{
+				ID:              item.ID,
+				Name:            item.GanttName,
+				Start:           item.StartDate,
+				End:             item.EndDate,
+				Colour:          item.Colour,
+				PercentComplete: item.PercentComplete,
+			}
+			for _, dependency := range item.Dependencies {
+				bar.Dependencies = append(bar.Dependencies, ganttchart.Dependency{
+					PredecessorID: dependency.PredecessorID,
+					Type:          schedule.DependencyType(dependency.Type),
+				})
+			}
+			chart.Items = append(chart.Items, bar)
+		}
+	}
+	for _, status := range db.GanttStatuses() {
+		chart.Legend = append(chart.Legend, ganttchart.LegendEntry{Label: status.Label(), Colour: status.Colour()})
+	}
+	return chart
+}
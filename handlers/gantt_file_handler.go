@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/Simplyphotons/fyp.git/ganttfile"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"mime"
+	"strings"
+	"time"
+)
+
+// maxGanttImport limits the size of an imported plan, a year of milestones takes a few kilobytes
+const maxGanttImport = 1 << 20
+
+// ganttFileOptions reads the format and the time zone of a plan file from the query, the format of an upload can also
+// be told from its content type
+func ganttFileOptions(ctx *fiber.Ctx) (ganttfile.Format, *time.Location, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+
+	value := ctx.Query("format")
+	if value == "" && strings.Contains(string(ctx.Request().Header.ContentType()), "xml") {
+		value = string(ganttfile.MSProject)
+	}
+	format, err := ganttfile.ParseFormat(value)
+	if err != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "format", Message: "must be csv or msproject"})
+	}
+
+	location := time.UTC
+	if timeZone := ctx.Query("timeZone"); timeZone != "" {
+		loaded, err := time.LoadLocation(timeZone)
+		if err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: "timeZone", Message: "is not a known IANA time zone"})
+		} else {
+			location = loaded
+		}
+	}
+	return format, location, fieldErrors
+}
+
+// ExportGanttHandler downloads the gantt chart of a project as CSV or Microsoft Project XML
+func (c Controller) ExportGanttHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	format, location, fieldErrors := ganttFileOptions(ctx)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid export",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	name, rows, err := c.dbClient.GetProjectGantt(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	var tasks []ganttfile.Task
+	for _, row := range rows {
+		for _, item := range row.Content {
+			task := ganttfile.Task{
+				Ref:             item.ID,
+				Name:            item.GanttName,
+				Start:           item.StartDate,
+				End:             item.EndDate,
+				Description:     item.Description,
+				Links:           item.Links,
+				PercentComplete: item.PercentComplete,
+			}
+			for _, dependency := range item.Dependencies {
+				task.Predecessors = append(task.Predecessors, ganttfile.Link{
+					Ref:     dependency.PredecessorID,
+					Type:    schedule.DependencyType(dependency.Type),
+					LagDays: dependency.LagDays,
+				})
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	var file bytes.Buffer
+	if err = ganttfile.Write(&file, format, name, tasks, location); err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	ctx.Set(fiber.HeaderContentType, format.ContentType())
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": name + format.Extension()}))
+	return ctx.Status(200).Send(file.Bytes())
+}
+
+// ImportGanttHandler reads a plan file sent as the body and creates its tasks as items of the project. With
+// ?preview=true the tasks are only checked and returned. A file with errors creates nothing, every error names the
+// row of the file it was found in.
+func (c Controller) ImportGanttHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	format, location, fieldErrors := ganttFileOptions(ctx)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid import",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	body := ctx.Body()
+	if len(body) > maxGanttImport {
+		message := model.ErrorMessage{
+			Message: "file is too large",
+		}
+		return ctx.Status(413).JSON(message)
+	}
+
+	tasks, rowErrors, err := ganttfile.Read(bytes.NewReader(body), format, location)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	result := model.GanttImport{
+		Preview: ctx.QueryBool("preview"),
+		Items:   []model.GanttImportItem{},
+	}
+	for _, task := range tasks {
+		item := model.GanttImportItem{
+			Row:             task.Row,
+			Ref:             task.Ref,
+			GanttName:       task.Name,
+			StartDate:       task.Start,
+			EndDate:         task.End,
+			Description:     task.Description,
+			Links:           task.Links,
+			PercentComplete: task.PercentComplete,
+		}
+		for _, link := range task.Predecessors {
+			item.Dependencies = append(item.Dependencies, model.Dependency{PredecessorID: link.Ref, Type: string(link.Type), LagDays: link.LagDays})
+		}
+		result.Items = append(result.Items, item)
+	}
+	for _, rowError := range rowErrors {
+		result.Errors = append(result.Errors, model.ImportRowError{Row: rowError.Row, Field: rowError.Field, Message: rowError.Message})
+	}
+	if len(result.Errors) > 0 {
+		return ctx.Status(400).JSON(result)
+	}
+	if result.Preview {
+		return ctx.Status(200).JSON(result)
+	}
+
+	ids, err := c.dbClient.ImportGantt(ctx.Context(), ctx.Params("id"), authority.UserID, tasks)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	for i := range result.Items {
+		result.Items[i].ID = ids[i]
+	}
+	return ctx.Status(201).JSON(result)
+}
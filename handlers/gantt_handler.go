@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/Simplyphotons/fyp.git/db"
 	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
 	"github.com/Simplyphotons/fyp.git/security"
 	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
 )
 
 func (c Controller) GetGanttItem(ctx *fiber.Ctx) error {
@@ -16,6 +21,9 @@ func (c Controller) GetGanttItem(ctx *fiber.Ctx) error {
 		}
 		return ctx.Status(500).JSON(message)
 	}
+	if len(response) == 1 {
+		ctx.Set(fiber.HeaderETag, ganttETag(response[0].Version))
+	}
 	return ctx.Status(200).JSON(response)
 }
 
@@ -33,10 +41,7 @@ func (c Controller) GetGantt(ctx *fiber.Ctx) error { //get all gantt item for a
 func (c Controller) GetFeedback(ctx *fiber.Ctx) error { //get all gantt item for a particular project
 	response, err := c.dbClient.GetFeedback(ctx.Context(), ctx.Params("id"))
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return projectError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 }
@@ -68,28 +73,38 @@ func (c Controller) CreateProjectHandler(ctx *fiber.Ctx) error {
 
 	// Translate it to the db request
 	projectRequest := db.Application{
-		ID:           application.ID,
-		StudentName:  application.StudentID, //these two swap for some reason to reswapping fixes the issue
-		StudentID:    application.StudentName,
-		SupervisorID: authority.UserID,
+		ID:             application.ID,
+		StudentName:    application.StudentID, //these two swap for some reason to reswapping fixes the issue
+		StudentID:      application.StudentName,
+		SupervisorID:   authority.UserID,
+		TemplateID:     application.TemplateID,
+		FollowTemplate: application.FollowTemplate,
 	}
 
 	// Execute db request
 	err = c.dbClient.CreateProject(ctx.Context(), projectRequest, authority.UserID)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return applicationError(ctx, err)
 	}
 
 	return ctx.SendStatus(204)
 }
 
 func (c Controller) CreateGanttItemHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
 
 	// Read the request body
-	var gantt model.Gantt
+	var gantt model.CreateGanttItemRequest
 
 	err := json.Unmarshal(ctx.Body(), &gantt)
 	if err != nil {
@@ -99,25 +114,20 @@ func (c Controller) CreateGanttItemHandler(ctx *fiber.Ctx) error {
 		return ctx.Status(400).JSON(message)
 	}
 
-	// Translate it to the db request
-	ganttRequest := db.Gantt{
-		Id:          gantt.ID,
-		ProjectID:   gantt.ProjectID,
-		StartDate:   gantt.StartDate,
-		EndDate:     gantt.EndDate,
-		Description: gantt.Description,
-		Links:       gantt.Links,
-		Feedback:    gantt.Feedback,
-		GanttName:   gantt.GanttName,
+	// Validate and translate it to the db request
+	ganttRequest, fieldErrors := validateGanttItem(gantt)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid gantt item",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
 	}
 
 	// Execute db request
-	err = c.dbClient.CreateGanttItem(ctx.Context(), ganttRequest)
+	err = c.dbClient.CreateGanttItem(ctx.Context(), ganttRequest, authority.UserID)
 	if err != nil {
-		message := model.ErrorMessage{
-			Message: err.Error(),
-		}
-		return ctx.Status(500).JSON(message)
+		return projectError(ctx, err)
 	}
 
 	return ctx.SendStatus(204)
@@ -139,37 +149,95 @@ func (c Controller) AddFeedbackHandler(ctx *fiber.Ctx) error {
 		return ctx.Status(401).JSON(message)
 	}
 
-	err := json.Unmarshal(ctx.Body(), &gantt)
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	err = json.Unmarshal(ctx.Body(), &gantt)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
 		return ctx.Status(400).JSON(message)
 	}
+	if strings.TrimSpace(gantt.NewFeedback) == "" {
+		message := model.ValidationErrorMessage{
+			Message: "invalid feedback",
+			Fields:  []model.FieldError{{Field: "newFeedback", Message: "is required"}},
+		}
+		return ctx.Status(400).JSON(message)
+	}
 
-	// Translate it to the db request
-
+	// Translate it to the db request, the feedback is appended to what is stored rather than to what the client read
 	ganttRequest := db.Gantt{
 		Id:          gantt.ID,
-		Feedback:    gantt.Feedback,
 		NewFeedBack: gantt.NewFeedback,
+		Version:     version,
 	}
 
-	err = c.dbClient.UpdateFeedback(ctx.Context(), ganttRequest, authority.UserID)
+	version, err = c.dbClient.UpdateFeedback(ctx.Context(), ganttRequest, authority.UserID)
 	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderETag, ganttETag(version))
+	return ctx.SendStatus(204)
+}
+
+// MarkFeedbackReadHandler marks the comments on an item as read by the user
+func (c Controller) MarkFeedbackReadHandler(ctx *fiber.Ctx) error {
+
+	id := ctx.Params("id")
+
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
 		message := model.ErrorMessage{
-			Message: err.Error(),
+			Message: "cannot extract user id",
 		}
-		return ctx.Status(500).JSON(message)
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.MarkFeedbackRead(ctx.Context(), id, authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
 	}
 
 	return ctx.SendStatus(204)
 }
 
-func (c Controller) DisableAlertHandler(ctx *fiber.Ctx) error {
+func (c Controller) DeleteGanttItemHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
 
 	id := ctx.Params("id")
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	err = c.dbClient.DeleteGanttItem(ctx.Context(), id, authority.UserID, version)
+	if err != nil {
+		return projectError(ctx, err)
+	}
 
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) CompleteGanttItemHandler(ctx *fiber.Ctx) error {
 	var (
 		authority security.Authority
 		ok        bool
@@ -182,37 +250,136 @@ func (c Controller) DisableAlertHandler(ctx *fiber.Ctx) error {
 		return ctx.Status(401).JSON(message)
 	}
 
-	err := c.dbClient.DisableAlert(ctx.Context(), authority.UserID, id)
+	var gantt model.Gantt
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	err = json.Unmarshal(ctx.Body(), &gantt)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
 	}
 
+	// Translate it to the db request
+	ganttRequest := db.Gantt{
+		Id:      gantt.ID,
+		Version: version,
+	}
+
+	version, err = c.dbClient.CompleteGanttItem(ctx.Context(), ganttRequest, authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderETag, ganttETag(version))
 	return ctx.SendStatus(204)
 }
 
-func (c Controller) DeleteGanttItemHandler(ctx *fiber.Ctx) error {
+const maxDependencyLagDays = 365
 
-	id := ctx.Params("id")
+// AddGanttDependencyHandler makes an item wait for another item of the same project
+func (c Controller) AddGanttDependencyHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
 
-	err := c.dbClient.DeleteGanttItem(id)
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.DependencyRequest
+	err := json.Unmarshal(ctx.Body(), &request)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
+	}
+
+	fieldErrors := []model.FieldError{}
+	if request.PredecessorID == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "predecessorID", Message: "is required"})
+	}
+	if request.SuccessorID == "" || request.SuccessorID == request.PredecessorID {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "successorID", Message: "is required and must differ from predecessorID"})
+	}
+	kind, err := schedule.ParseDependencyType(request.Type)
+	if err != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "type", Message: "must be finish_to_start, start_to_start or finish_to_finish"})
+	}
+	if request.LagDays < -maxDependencyLagDays || request.LagDays > maxDependencyLagDays {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "lagDays", Message: fmt.Sprintf("must be between -%[1]d and %[1]d", maxDependencyLagDays)})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid dependency",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
 	}
 
+	dependency := db.Dependency{
+		PredecessorID: request.PredecessorID,
+		SuccessorID:   request.SuccessorID,
+		Type:          kind,
+		LagDays:       request.LagDays,
+	}
+	err = c.dbClient.AddGanttDependency(ctx.Context(), dependency, authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
 	return ctx.SendStatus(204)
 }
 
-func (c Controller) CompleteGanttItemHandler(ctx *fiber.Ctx) error {
+func (c Controller) RemoveGanttDependencyHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
 
-	var gantt model.Gantt
+		return ctx.Status(401).JSON(message)
+	}
 
-	err := json.Unmarshal(ctx.Body(), &gantt)
+	err := c.dbClient.RemoveGanttDependency(ctx.Context(), ctx.Params("predecessorID"), ctx.Params("successorID"), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// SetGanttStatusHandler lets a project member change the status and progress of an item, the colour follows
+func (c Controller) SetGanttStatusHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	var request model.GanttStatusRequest
+	err = json.Unmarshal(ctx.Body(), &request)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
@@ -220,18 +387,106 @@ func (c Controller) CompleteGanttItemHandler(ctx *fiber.Ctx) error {
 		return ctx.Status(400).JSON(message)
 	}
 
-	// Translate it to the db request
-	ganttRequest := db.Gantt{
-		Id: gantt.ID,
+	fieldErrors := []model.FieldError{}
+	status, err := db.ParseGanttStatus(request.Status)
+	if err != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "status", Message: "must be on_track, at_risk, blocked or complete"})
+	}
+	if request.PercentComplete < 0 || request.PercentComplete > 100 {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "percentComplete", Message: "must be between 0 and 100"})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid gantt status",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
 	}
 
-	err = c.dbClient.CompleteGanttItem(ctx.Context(), ganttRequest)
+	version, err = c.dbClient.SetGanttStatus(ctx.Context(), ctx.Params("id"), authority.UserID, status, request.PercentComplete, version)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	ctx.Set(fiber.HeaderETag, ganttETag(version))
+	return ctx.SendStatus(204)
+}
+
+// EditGanttItemHandler applies a JSON merge patch to an item and returns the edited item
+func (c Controller) EditGanttItemHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	patch, fieldErrors, err := validateGanttPatch(ctx.Body())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid gantt item",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.EditGanttItem(ctx.Context(), ctx.Params("id"), authority.UserID, patch, version)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	response, err := c.dbClient.GetGanttItem(ctx.Context(), ctx.Params("id"))
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
 		return ctx.Status(500).JSON(message)
 	}
+	if len(response) == 0 {
+		return projectError(ctx, db.ErrGanttItemNotFound)
+	}
+	ctx.Set(fiber.HeaderETag, ganttETag(response[0].Version))
+	return ctx.Status(200).JSON(response[0])
+}
 
-	return ctx.SendStatus(204)
+// ganttETag is the entity tag of a gantt item at a version
+func ganttETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// errVersionRequired is returned when a change to a gantt item does not say which version of the item it is based on
+var errVersionRequired = errors.New("send the ETag of the gantt item as If-Match to change it")
+
+// ifMatch returns the version of the gantt item the client read from the If-Match header. Changes must name the
+// version they are based on, so neither a missing header nor "*" is accepted. A tag the API did not hand out, a
+// weak one included, can never match.
+func ifMatch(ctx *fiber.Ctx) (int, error) {
+	value := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0, errVersionRequired
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return 0, db.ErrVersionMismatch
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, db.ErrVersionMismatch
+	}
+	return version, nil
 }
@@ -2,120 +2,90 @@ package handlers
 
 import (
 	"context"
-	"github.com/Simplyphotons/fyp.git/db"
+	"encoding/json"
 	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
 	"github.com/gofiber/fiber/v2"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
+// DBMock answers the calls a test sets up, the other methods of the client panic
 type DBMock struct {
+	DBClient
 	GetGanttItemResponse   []model.Gantt
 	GetGanttItemError      error
 	GetGanttItemCallNumber int
 }
 
-func (db DBMock) GetProjects(ctx context.Context, supervisor_id string) ([]model.ProjectData, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetUsername(ctx context.Context, userId string) (string, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) CreateGanttItem(ctx context.Context, gantt db.Gantt) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) UpdateFeedback(ctx context.Context, gantt db.Gantt, id string, feedback string) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) CreateApplication(ctx context.Context, application db.Application) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) AcceptApplication(ctx context.Context, application db.Application) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) DeclineApplication(ctx context.Context, application db.Application) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetQuestions(ctx context.Context) ([]model.Question, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetSupervisors(ctx context.Context) ([]model.UserData, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetApplications(ctx context.Context) ([]model.ApplicationData, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetSpecificApplications(ctx context.Context, appID string) ([]model.ApplicationData, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) NewQuestion(ctx context.Context) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) NewAnswer(ctx context.Context) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetGantt(ctx context.Context, projectIdentifier string) ([]model.Gantt, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (db DBMock) GetGanttItem(ctx context.Context, milestoneIdentifier string) ([]model.Gantt, error) {
+func (db *DBMock) GetGanttItem(ctx context.Context, milestoneIdentifier string) ([]model.Gantt, error) {
 	db.GetGanttItemCallNumber++
 	return db.GetGanttItemResponse, db.GetGanttItemError
 }
 
-func (db DBMock) CreateProject(ctx context.Context, project db.Project) error {
-	return nil
-}
-
 func TestGetGanttItem(t *testing.T) {
-	dbMock := DBMock{
+	dbMock := &DBMock{
 		GetGanttItemResponse: []model.Gantt{
 			{
 				ID:        "bc11d336-241d-4d69-8061-bfca6e39809e",
 				ProjectID: "1234",
 
-				StartDate:   "01/24",
-				EndDate:     "02/24",
+				StartDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:     time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
 				Description: "description text",
-			},
-			{
-				ID: "76906af6-26b2-4f9e-8c78-7201807f6a2b",
+				Version:     3,
 			},
 		},
 	}
 
-	testController := New(dbMock)
+	testController := New(dbMock, nil, "")
 
-	ctx := &fiber.Ctx{}
+	app := fiber.New()
+	app.Get("/getGanttItem/:id", testController.GetGanttItem)
 
-	err := testController.GetGantt(ctx)
+	resp, err := app.Test(httptest.NewRequest("GET", "/getGanttItem/bc11d336-241d-4d69-8061-bfca6e39809e", nil))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if etag := resp.Header.Get(fiber.HeaderETag); etag != ganttETag(3) {
+		t.Errorf("expected ETag %s, got %s", ganttETag(3), etag)
+	}
+	var items []model.Gantt
+	if err = json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !items[0].StartDate.Equal(dbMock.GetGanttItemResponse[0].StartDate) {
+		t.Errorf("unexpected items %v", items)
+	}
+	if dbMock.GetGanttItemCallNumber != 1 {
+		t.Errorf("expected one call to GetGanttItem, got %d", dbMock.GetGanttItemCallNumber)
+	}
+}
+
+func TestDeleteGanttItemRequiresIfMatch(t *testing.T) {
+	testController := New(&DBMock{}, nil, "")
+
+	app := fiber.New()
+	app.Delete("/deleteGanttItem/:id", func(ctx *fiber.Ctx) error {
+		ctx.SetUserContext(context.WithValue(ctx.UserContext(), security.AuthorityKey{}, security.Authority{UserID: "student-1"}))
+		return ctx.Next()
+	}, testController.DeleteGanttItemHandler)
+
+	for header, status := range map[string]int{"": 428, "*": 428, `W/"3"`: 412} {
+		req := httptest.NewRequest("DELETE", "/deleteGanttItem/bc11d336-241d-4d69-8061-bfca6e39809e", nil)
+		if header != "" {
+			req.Header.Set(fiber.HeaderIfMatch, header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("If-Match %q: expected status %d, got %d", header, status, resp.StatusCode)
+		}
 	}
 }
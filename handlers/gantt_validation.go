@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// validateGanttItem checks a gantt item request and converts its dates to UTC. Dates without a time are
+// taken as midnight in the requested time zone, or UTC when none is given.
+func validateGanttItem(request model.CreateGanttItemRequest) (db.Gantt, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+
+	if strings.TrimSpace(request.ProjectID) == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "projectID", Message: "is required"})
+	}
+	if strings.TrimSpace(request.GanttName) == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "ganttName", Message: "is required"})
+	}
+
+	location := time.UTC
+	if request.TimeZone != "" {
+		loaded, err := time.LoadLocation(request.TimeZone)
+		if err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: "timeZone", Message: "is not a known IANA time zone"})
+		} else {
+			location = loaded
+		}
+	}
+
+	startDate, startErr := parseGanttDate(request.StartDate, location)
+	if startErr != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "startDate", Message: startErr.Error()})
+	}
+	endDate, endErr := parseGanttDate(request.EndDate, location)
+	if endErr != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "endDate", Message: endErr.Error()})
+	}
+	if startErr == nil && endErr == nil && endDate.Before(startDate) {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "endDate", Message: "must not be before startDate"})
+	}
+
+	return db.Gantt{
+		ProjectID:   request.ProjectID,
+		GanttName:   request.GanttName,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Description: request.Description,
+		Links:       request.Links,
+	}, fieldErrors
+}
+
+func parseGanttDate(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), nil
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, value, location); err == nil {
+		return date.UTC(), nil
+	}
+	return time.Time{}, errors.New("must be an ISO-8601 date (YYYY-MM-DD) or timestamp")
+}
+
+// ganttPatchFields are the members of a merge patch for a gantt item, timeZone only applies to the dates of the patch
+var ganttPatchFields = []string{"ganttName", "startDate", "endDate", "timeZone", "description", "links", "deadlineLocked"}
+
+// validateGanttPatch reads a JSON merge patch (RFC 7396) for a gantt item. Members that are left out keep their
+// value, null clears the description, the links and the deadline lock while the name and the dates cannot be
+// cleared. Any other member is refused so that a typo does not silently do nothing.
+func validateGanttPatch(body []byte) (db.GanttPatch, []model.FieldError, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return db.GanttPatch{}, nil, err
+	}
+
+	var (
+		patch       db.GanttPatch
+		fieldErrors = []model.FieldError{}
+	)
+	text := func(field string, clearable bool) (*string, bool) {
+		raw, ok := members[field]
+		if !ok {
+			return nil, false
+		}
+		var value *string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field, Message: "must be a string"})
+			return nil, false
+		}
+		if value == nil {
+			if !clearable {
+				fieldErrors = append(fieldErrors, model.FieldError{Field: field, Message: "cannot be removed"})
+				return nil, false
+			}
+			value = new(string)
+		}
+		return value, true
+	}
+
+	if name, ok := text("ganttName", false); ok {
+		if strings.TrimSpace(*name) == "" {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: "ganttName", Message: "is required"})
+		}
+		patch.GanttName = name
+	}
+	patch.Description, _ = text("description", true)
+	patch.Links, _ = text("links", true)
+
+	location := time.UTC
+	if timeZone, ok := text("timeZone", true); ok && *timeZone != "" {
+		loaded, err := time.LoadLocation(*timeZone)
+		if err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: "timeZone", Message: "is not a known IANA time zone"})
+		} else {
+			location = loaded
+		}
+	}
+	for _, field := range []string{"startDate", "endDate"} {
+		value, ok := text(field, false)
+		if !ok {
+			continue
+		}
+		date, err := parseGanttDate(*value, location)
+		if err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field, Message: err.Error()})
+			continue
+		}
+		if field == "startDate" {
+			patch.StartDate = &date
+		} else {
+			patch.EndDate = &date
+		}
+	}
+
+	if raw, ok := members["deadlineLocked"]; ok {
+		var locked *bool
+		if err := json.Unmarshal(raw, &locked); err != nil {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: "deadlineLocked", Message: "must be a boolean"})
+		} else {
+			if locked == nil {
+				locked = new(bool)
+			}
+			patch.DeadlineLocked = locked
+		}
+	}
+
+	var unknown []string
+	for field := range members {
+		if !slices.Contains(ganttPatchFields, field) {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+	for _, field := range unknown {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: field, Message: "cannot be edited"})
+	}
+	return patch, fieldErrors, nil
+}
@@ -9,12 +9,17 @@ import (
 
 func (c Controller) GetQuestionsHandler(ctx *fiber.Ctx) error {
 
-	response, err := c.dbClient.GetQuestions(ctx.Context())
+	opts, err := parseListOptions(ctx)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetQuestions(ctx.Context(), opts)
+	if err != nil {
+		return listError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 
@@ -22,12 +27,17 @@ func (c Controller) GetQuestionsHandler(ctx *fiber.Ctx) error {
 
 func (c Controller) GetSupervisorHandler(ctx *fiber.Ctx) error {
 
-	response, err := c.dbClient.GetSupervisors(ctx.Context())
+	opts, err := parseListOptions(ctx)
 	if err != nil {
 		message := model.ErrorMessage{
 			Message: err.Error(),
 		}
-		return ctx.Status(500).JSON(message)
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetSupervisors(ctx.Context(), opts)
+	if err != nil {
+		return listError(ctx, err)
 	}
 	return ctx.Status(200).JSON(response)
 
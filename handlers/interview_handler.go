@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/calendar"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"time"
+)
+
+const (
+	maxInterviewSlots    = 50
+	maxInterviewLength   = 4 * time.Hour
+	maxInterviewLocation = 500
+)
+
+func (c Controller) CreateInterviewSlotsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.InterviewSlotsRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	slots, fieldErrors := validateInterviewSlots(request, time.Now())
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid interview slots",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	ids, err := c.dbClient.CreateInterviewSlots(ctx.Context(), authority.UserID, slots)
+	if err != nil {
+		return interviewError(ctx, err)
+	}
+	response := make([]model.InterviewSlot, len(slots))
+	for i, slot := range slots {
+		response[i] = model.InterviewSlot{
+			ID:           ids[i],
+			SupervisorID: authority.UserID,
+			StartsAt:     slot.StartsAt,
+			EndsAt:       slot.EndsAt,
+			Location:     slot.Location,
+		}
+	}
+	return ctx.Status(201).JSON(response)
+}
+
+func validateInterviewSlots(request model.InterviewSlotsRequest, now time.Time) ([]db.InterviewSlot, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+	if len(request.Slots) == 0 || len(request.Slots) > maxInterviewSlots {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "slots", Message: fmt.Sprintf("between 1 and %d slots are required", maxInterviewSlots)})
+	}
+
+	slots := make([]db.InterviewSlot, 0, len(request.Slots))
+	for i, requested := range request.Slots {
+		slot := db.InterviewSlot{
+			StartsAt: requested.StartsAt.UTC(),
+			EndsAt:   requested.EndsAt.UTC(),
+			Location: strings.TrimSpace(requested.Location),
+		}
+		field := fmt.Sprintf("slots[%d].", i)
+		if !slot.StartsAt.After(now) {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "startsAt", Message: "must be in the future"})
+		}
+		if !slot.EndsAt.After(slot.StartsAt) || slot.EndsAt.Sub(slot.StartsAt) > maxInterviewLength {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "endsAt", Message: fmt.Sprintf("must be after startsAt and at most %s later", maxInterviewLength)})
+		}
+		if len(slot.Location) > maxInterviewLocation {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "location", Message: fmt.Sprintf("must be at most %d characters", maxInterviewLocation)})
+		}
+		slots = append(slots, slot)
+	}
+	return slots, fieldErrors
+}
+
+func (c Controller) DeleteInterviewSlotHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.DeleteInterviewSlot(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return interviewError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// GetInterviewSlotsHandler lists the slots of a supervisor, students get the slots they can book and their bookings
+func (c Controller) GetInterviewSlotsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetInterviewSlots(ctx.Context(), authority.UserID, opts)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	for i := range response.Items {
+		setInviteURL(&response.Items[i])
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// BookInterviewSlotHandler books a slot for a shortlisted application, booking another slot reschedules the interview
+func (c Controller) BookInterviewSlotHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.BookInterviewRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	if request.ApplicationID == "" {
+		message := model.ValidationErrorMessage{
+			Message: "invalid booking",
+			Fields:  []model.FieldError{{Field: "applicationID", Message: "is required"}},
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	slot, err := c.dbClient.BookInterviewSlot(ctx.Context(), ctx.Params("id"), request.ApplicationID, authority.UserID)
+	if err != nil {
+		return interviewError(ctx, err)
+	}
+	setInviteURL(slot)
+	return ctx.Status(200).JSON(slot)
+}
+
+func (c Controller) CancelInterviewHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.CancelInterviewBooking(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return interviewError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// GetInterviewInviteHandler returns the iCalendar invite of a booked interview as a file download
+func (c Controller) GetInterviewInviteHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	slot, err := c.dbClient.GetInterviewSlot(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return interviewError(ctx, err)
+	}
+	if slot.ApplicationID == "" {
+		return interviewError(ctx, db.ErrSlotNotFound)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="interview.ics"`)
+	return ctx.Status(200).Send(interviewInvite(slot).Marshal())
+}
+
+func interviewInvite(slot *model.InterviewSlot) calendar.Calendar {
+	description := fmt.Sprintf("Interview for the application \"%s\"\nStudent: %s\nSupervisor: %s", slot.Heading, slot.StudentName, slot.SupervisorName)
+	return calendar.Calendar{
+		Method: calendar.Publish,
+		Events: []calendar.Event{{
+			// the uid stays the same when the interview is rescheduled so calendars move the event,
+			// the booking time only grows and serves as the sequence
+			UID:         "interview-" + slot.ApplicationID + "@fyp",
+			Sequence:    int(slot.BookedAt.Unix()),
+			Stamp:       *slot.BookedAt,
+			Start:       slot.StartsAt,
+			End:         slot.EndsAt,
+			Summary:     "Project interview: " + slot.Heading,
+			Description: description,
+			Location:    slot.Location,
+		}},
+	}
+}
+
+func setInviteURL(slot *model.InterviewSlot) {
+	if slot.ApplicationID != "" {
+		slot.InviteURL = "/getInterviewInvite/" + slot.ID
+	}
+}
+
+func interviewError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrSlotNotFound), errors.Is(err, db.ErrApplicationNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrNotSlotOwner), errors.Is(err, db.ErrNotApplicationParticipant):
+		return ctx.Status(403).JSON(message)
+	case errors.Is(err, db.ErrSlotOverlap), errors.Is(err, db.ErrSlotTaken), errors.Is(err, db.ErrSlotPassed),
+		errors.Is(err, db.ErrInterviewClash), errors.Is(err, db.ErrNotShortlisted):
+		return ctx.Status(409).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseListOptions reads the query parameters shared by all list endpoints:
+// limit, after, sort, status, accepted, declined, supervisor, from, to, q, tags and role
+func parseListOptions(ctx *fiber.Ctx) (db.ListOptions, error) {
+	opts := db.ListOptions{
+		After:        ctx.Query("after"),
+		Sort:         ctx.Query("sort"),
+		SupervisorID: ctx.Query("supervisor"),
+		Search:       strings.TrimSpace(ctx.Query("q")),
+	}
+
+	if value := ctx.Query("tags"); value != "" { //comma separated, every tag has to match
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				opts.Tags = append(opts.Tags, strings.ToLower(tag))
+			}
+		}
+	}
+
+	if value := ctx.Query("status"); value != "" {
+		status, err := lifecycle.Parse(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.Status = string(status)
+	}
+
+	if value := ctx.Query("role"); value != "" {
+		role, err := db.ParseProjectRole(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.Role = string(role)
+	}
+
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > db.MaxListLimit {
+			return opts, fmt.Errorf("limit must be a number between 1 and %d", db.MaxListLimit)
+		}
+		opts.Limit = limit
+	}
+
+	for name, target := range map[string]**bool{"accepted": &opts.Accepted, "declined": &opts.Declined} {
+		if value := ctx.Query(name); value != "" {
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be true or false", name)
+			}
+			*target = &flag
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"from": &opts.From, "to": &opts.To} {
+		if value := ctx.Query(name); value != "" {
+			date, err := parseQueryDate(value)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be an ISO-8601 date or timestamp", name)
+			}
+			*target = &date
+		}
+	}
+
+	if opts.From != nil && opts.To != nil && !opts.From.Before(*opts.To) {
+		return opts, errors.New("from must be before to")
+	}
+
+	return opts, nil
+}
+
+func parseQueryDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// listError maps errors returned by list queries to a response, invalid options are reported as bad requests
+func listError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	if errors.Is(err, db.ErrInvalidListOptions) {
+		return ctx.Status(400).JSON(message)
+	}
+	return ctx.Status(500).JSON(message)
+}
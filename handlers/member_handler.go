@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/schedule"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (c Controller) GetProjectMembersHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetProjectMembers(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// AddProjectMemberHandler lets the primary supervisor add a co-supervisor, second reader or external mentor
+func (c Controller) AddProjectMemberHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.ProjectMemberRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	fieldErrors := []model.FieldError{}
+	if request.UserID == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "userID", Message: "is required"})
+	}
+	role, err := db.ParseProjectRole(request.Role)
+	if err != nil {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "role", Message: "must be co_supervisor, second_reader or external_mentor"})
+	}
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid project member",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.AddProjectMember(ctx.Context(), ctx.Params("id"), authority.UserID, request.UserID, role)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) RemoveProjectMemberHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.RemoveProjectMember(ctx.Context(), ctx.Params("id"), authority.UserID, ctx.Params("userID"))
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func projectError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrProjectNotFound), errors.Is(err, db.ErrGanttItemNotFound),
+		errors.Is(err, db.ErrUserNotFound), errors.Is(err, db.ErrConflictNotFound), errors.Is(err, db.ErrDependencyNotFound),
+		errors.Is(err, db.ErrCommentNotFound), errors.Is(err, db.ErrFeedNotFound), errors.Is(err, db.ErrTemplateNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrNotProjectMember), errors.Is(err, db.ErrNotPrimarySupervisor), errors.Is(err, db.ErrDeadlineLocked),
+		errors.Is(err, db.ErrNotCommentAuthor), errors.Is(err, db.ErrTemplateFollowDenied):
+		return ctx.Status(403).JSON(message)
+	case errors.Is(err, db.ErrVersionMismatch):
+		return ctx.Status(412).JSON(message)
+	case errors.Is(err, errVersionRequired):
+		return ctx.Status(428).JSON(message)
+	case errors.Is(err, db.ErrAlreadyMember), errors.Is(err, db.ErrConflictOfInterest), errors.Is(err, schedule.ErrCycle):
+		return ctx.Status(409).JSON(message)
+	case errors.Is(err, db.ErrInvalidRole), errors.Is(err, db.ErrNotSupervisor), errors.Is(err, db.ErrInvalidContribution),
+		errors.Is(err, db.ErrInvalidDependency), errors.Is(err, db.ErrInvalidGanttStatus),
+		errors.Is(err, db.ErrInvalidGanttEdit):
+		return ctx.Status(400).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/markdown"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxMessageLength = 10000
+
+func (c Controller) SendApplicationMessageHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.MessageRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+	body := strings.TrimSpace(request.Body)
+	if body == "" || utf8.RuneCountInString(body) > maxMessageLength {
+		message := model.ValidationErrorMessage{
+			Message: "invalid message",
+			Fields:  []model.FieldError{{Field: "body", Message: fmt.Sprintf("must be between 1 and %d characters", maxMessageLength)}},
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.SendApplicationMessage(ctx.Context(), ctx.Params("id"), authority.UserID, body)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	response.HTML = markdown.ToHTML(response.Body)
+	return ctx.Status(201).JSON(response)
+}
+
+func (c Controller) GetApplicationMessagesHandler(ctx *fiber.Ctx) error {
+	return c.getMessages(ctx, c.dbClient.GetApplicationMessages)
+}
+
+// GetProjectMessagesHandler returns the application thread that was carried over into the project
+func (c Controller) GetProjectMessagesHandler(ctx *fiber.Ctx) error {
+	return c.getMessages(ctx, c.dbClient.GetProjectMessages)
+}
+
+func (c Controller) getMessages(ctx *fiber.Ctx, get func(ctx context.Context, id string, userID string, opts db.ListOptions) (*model.Page[model.Message], error)) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := get(ctx.Context(), ctx.Params("id"), authority.UserID, opts)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidListOptions) {
+			return listError(ctx, err)
+		}
+		return applicationError(ctx, err)
+	}
+	for i := range response.Items {
+		response.Items[i].HTML = markdown.ToHTML(response.Items[i].Body)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) ReadApplicationMessagesHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.ReadApplicationMessages(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (c Controller) GetNotificationsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetNotifications(ctx.Context(), authority.UserID, opts)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) ReadNotificationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.ReadNotification(ctx.Context(), authority.UserID, ctx.Params("id"))
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		if errors.Is(err, db.ErrNotificationNotFound) {
+			return ctx.Status(404).JSON(message)
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.SendStatus(204)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"slices"
+	"strings"
+)
+
+const (
+	maxProposalTags  = 10
+	maxProposalSlots = 20
+)
+
+func (c Controller) CreateProposalHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	proposal, err := readProposal(ctx)
+	if err != nil {
+		return err
+	}
+	if proposal == nil {
+		return nil
+	}
+	proposal.SupervisorID = authority.UserID
+
+	id, err := c.dbClient.CreateProposal(ctx.Context(), *proposal)
+	if err != nil {
+		return proposalError(ctx, err)
+	}
+	return ctx.Status(201).JSON(model.Proposal{ID: id})
+}
+
+func (c Controller) UpdateProposalHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	proposal, err := readProposal(ctx)
+	if err != nil {
+		return err
+	}
+	if proposal == nil {
+		return nil
+	}
+	proposal.ID = ctx.Params("id")
+	proposal.SupervisorID = authority.UserID
+
+	err = c.dbClient.UpdateProposal(ctx.Context(), *proposal)
+	if err != nil {
+		return proposalError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// ProposalStatusHandler returns a handler that publishes or archives the proposal in the path
+func (c Controller) ProposalStatusHandler(to db.ProposalStatus) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var (
+			authority security.Authority
+			ok        bool
+		)
+		if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+			message := model.ErrorMessage{
+				Message: "cannot extract user id",
+			}
+
+			return ctx.Status(401).JSON(message)
+		}
+
+		err := c.dbClient.SetProposalStatus(ctx.Context(), ctx.Params("id"), authority.UserID, to)
+		if err != nil {
+			return proposalError(ctx, err)
+		}
+		return ctx.SendStatus(204)
+	}
+}
+
+func (c Controller) DeleteProposalHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.DeleteProposal(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return proposalError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetProposalHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetProposal(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return proposalError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// GetProposalsHandler searches the published catalogue, mine=true lists the proposals of the caller in every status
+func (c Controller) GetProposalsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	opts, err := parseListOptions(ctx)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	response, err := c.dbClient.GetProposals(ctx.Context(), authority.UserID, ctx.QueryBool("mine"), opts)
+	if err != nil {
+		return listError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// readProposal parses and validates the request body, the response has been written when no proposal is returned
+func readProposal(ctx *fiber.Ctx) (*db.Proposal, error) {
+	var request model.ProposalRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+
+	proposal, fieldErrors := validateProposal(request)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid proposal",
+			Fields:  fieldErrors,
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+	return &proposal, nil
+}
+
+func validateProposal(request model.ProposalRequest) (db.Proposal, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+	proposal := db.Proposal{
+		Title:         strings.TrimSpace(request.Title),
+		Description:   strings.TrimSpace(request.Description),
+		Prerequisites: strings.TrimSpace(request.Prerequisites),
+		Tags:          []string{},
+		Slots:         request.Slots,
+	}
+
+	if proposal.Title == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "title", Message: "is required"})
+	}
+	if proposal.Description == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "description", Message: "is required"})
+	}
+	if proposal.Slots < 1 || proposal.Slots > maxProposalSlots {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "slots", Message: fmt.Sprintf("must be between 1 and %d", maxProposalSlots)})
+	}
+
+	for _, tag := range request.Tags { //tags are matched case insensitively
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(proposal.Tags, tag) {
+			proposal.Tags = append(proposal.Tags, tag)
+		}
+	}
+	if len(proposal.Tags) > maxProposalTags {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "tags", Message: fmt.Sprintf("at most %d tags are allowed", maxProposalTags)})
+	}
+	return proposal, fieldErrors
+}
+
+func proposalError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrProposalNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrNotProposalOwner):
+		return ctx.Status(403).JSON(message)
+	case errors.Is(err, db.ErrInvalidProposalStatus):
+		return ctx.Status(409).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
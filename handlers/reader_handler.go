@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/allocation"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"slices"
+	"strings"
+)
+
+const maxExpertiseTags = 20
+
+// SetExpertiseHandler replaces the topics the supervisor can second read, they are matched against proposal tags
+func (c Controller) SetExpertiseHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.ExpertiseRequest
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	tags := []string{}
+	for _, tag := range request.Tags { //same normalisation as proposal tags
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxExpertiseTags {
+		message := model.ValidationErrorMessage{
+			Message: "invalid expertise",
+			Fields:  []model.FieldError{{Field: "tags", Message: fmt.Sprintf("at most %d tags are allowed", maxExpertiseTags)}},
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err = c.dbClient.SetExpertise(ctx.Context(), authority.UserID, tags)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetExpertiseHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetExpertise(ctx.Context(), authority.UserID)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(model.ExpertiseRequest{Tags: response})
+}
+
+// RunReaderAllocationHandler proposes a second reader for every project of the year without one, nothing
+// changes on the projects until the run is committed
+func (c Controller) RunReaderAllocationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	projects, readers, err := c.dbClient.GetReaderAllocationInput(ctx.Context())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	result := allocation.AssignReaders(projects, readers)
+	runID, err := c.dbClient.SaveReaderAllocationRun(ctx.Context(), authority.UserID, result)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+
+	response, err := c.dbClient.GetReaderAllocationRun(ctx.Context(), runID)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.Status(201).JSON(response)
+}
+
+func (c Controller) GetReaderAllocationRunHandler(ctx *fiber.Ctx) error {
+	response, err := c.dbClient.GetReaderAllocationRun(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) CommitReaderAllocationHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.CommitReaderAllocationRun(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return allocationError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
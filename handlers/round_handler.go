@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+const (
+	maxExpiryDays = 365
+	maxStudentCap = 50
+)
+
+func (c Controller) CreateRoundHandler(ctx *fiber.Ctx) error {
+	round, err := readRound(ctx)
+	if err != nil || round == nil {
+		return err
+	}
+
+	id, err := c.dbClient.CreateRound(ctx.Context(), *round)
+	if err != nil {
+		return roundError(ctx, err)
+	}
+	return ctx.Status(201).JSON(model.Round{ID: id})
+}
+
+func (c Controller) UpdateRoundHandler(ctx *fiber.Ctx) error {
+	round, err := readRound(ctx)
+	if err != nil || round == nil {
+		return err
+	}
+	round.ID = ctx.Params("id")
+
+	err = c.dbClient.UpdateRound(ctx.Context(), *round)
+	if err != nil {
+		return roundError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetRoundsHandler(ctx *fiber.Ctx) error {
+	response, err := c.dbClient.GetRounds(ctx.Context())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// GetRoundStatusHandler shows whether applications are open and, for students, how many they have left
+func (c Controller) GetRoundStatusHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetRoundStatus(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// readRound parses and validates the request body, the response has been written when no round is returned
+func readRound(ctx *fiber.Ctx) (*db.Round, error) {
+	var request model.Round
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+
+	round, fieldErrors := validateRound(request)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid application round",
+			Fields:  fieldErrors,
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+	return &round, nil
+}
+
+func validateRound(request model.Round) (db.Round, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+	round := db.Round{
+		Name:              strings.TrimSpace(request.Name),
+		OpensAt:           request.OpensAt.UTC(),
+		ClosesAt:          request.ClosesAt.UTC(),
+		PendingExpiryDays: request.PendingExpiryDays,
+		OfferExpiryDays:   request.OfferExpiryDays,
+		StudentCap:        request.StudentCap,
+	}
+	if request.ResponseDeadline != nil {
+		deadline := request.ResponseDeadline.UTC()
+		round.ResponseDeadline = &deadline
+	}
+
+	if round.Name == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "name", Message: "is required"})
+	}
+	if round.OpensAt.IsZero() {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "opensAt", Message: "is required"})
+	}
+	if !round.ClosesAt.After(round.OpensAt) {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "closesAt", Message: "must be after opensAt"})
+	}
+	if round.StudentCap != nil && (*round.StudentCap < 1 || *round.StudentCap > maxStudentCap) {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "studentCap", Message: fmt.Sprintf("must be between 1 and %d", maxStudentCap)})
+	}
+	if round.ResponseDeadline != nil && !round.ResponseDeadline.After(round.OpensAt) {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "responseDeadline", Message: "must be after opensAt"})
+	}
+	expiries := []struct {
+		field string
+		days  *int
+	}{
+		{"pendingExpiryDays", round.PendingExpiryDays},
+		{"offerExpiryDays", round.OfferExpiryDays},
+	}
+	for _, expiry := range expiries {
+		if expiry.days != nil && (*expiry.days < 1 || *expiry.days > maxExpiryDays) {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: expiry.field, Message: fmt.Sprintf("must be between 1 and %d days", maxExpiryDays)})
+		}
+	}
+	return round, fieldErrors
+}
+
+func roundError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrRoundNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrRoundOverlap):
+		return ctx.Status(409).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+const maxContributionNote = 500
+
+// RespondTeamInviteHandler confirms or declines the invitation of the student to a joint application
+func (c Controller) RespondTeamInviteHandler(confirm bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var (
+			authority security.Authority
+			ok        bool
+		)
+		if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+			message := model.ErrorMessage{
+				Message: "cannot extract user id",
+			}
+
+			return ctx.Status(401).JSON(message)
+		}
+
+		err := c.dbClient.RespondTeamInvite(ctx.Context(), ctx.Params("id"), authority.UserID, confirm)
+		if err != nil {
+			return applicationError(ctx, err)
+		}
+		return ctx.SendStatus(204)
+	}
+}
+
+func (c Controller) GetTeamInvitesHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetTeamInvites(ctx.Context(), authority.UserID)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) GetApplicationTeamHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	response, err := c.dbClient.GetApplicationTeam(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return applicationError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// SetGanttContributionsHandler replaces the shares the student members had in a gantt item
+func (c Controller) SetGanttContributionsHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	version, err := ifMatch(ctx)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+
+	var request model.ContributionsRequest
+	err = json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	contributions, fieldErrors := validateContributions(request)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid contributions",
+			Fields:  fieldErrors,
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	version, err = c.dbClient.SetGanttContributions(ctx.Context(), ctx.Params("id"), authority.UserID, contributions, version)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	ctx.Set(fiber.HeaderETag, ganttETag(version))
+	return ctx.SendStatus(204)
+}
+
+func validateContributions(request model.ContributionsRequest) ([]db.Contribution, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+	contributions := make([]db.Contribution, 0, len(request.Contributions))
+	seen := map[string]bool{}
+	total := 0
+	for i, requested := range request.Contributions {
+		contribution := db.Contribution{
+			UserID: requested.UserID,
+			Share:  requested.Share,
+			Note:   strings.TrimSpace(requested.Note),
+		}
+		field := fmt.Sprintf("contributions[%d].", i)
+		if contribution.UserID == "" || seen[contribution.UserID] {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "userID", Message: "is required once per student"})
+		}
+		if contribution.Share < 1 || contribution.Share > 100 {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "share", Message: "must be between 1 and 100"})
+		}
+		if len(contribution.Note) > maxContributionNote {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + "note", Message: fmt.Sprintf("must be at most %d characters", maxContributionNote)})
+		}
+		seen[contribution.UserID] = true
+		total += contribution.Share
+		contributions = append(contributions, contribution)
+	}
+	if total > 100 {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "contributions", Message: "shares must add up to at most 100"})
+	}
+	return contributions, fieldErrors
+}
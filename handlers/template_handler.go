@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/model"
+	"github.com/Simplyphotons/fyp.git/security"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+// maxTemplateDay bounds milestones to the two academic years a project can run over
+const maxTemplateDay = 730
+
+func (c Controller) CreateMilestoneTemplateHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	template, err := readMilestoneTemplate(ctx)
+	if err != nil || template == nil {
+		return err
+	}
+
+	id, err := c.dbClient.CreateMilestoneTemplate(ctx.Context(), *template, authority.UserID)
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.Status(201).JSON(model.MilestoneTemplate{ID: id})
+}
+
+// UpdateMilestoneTemplateHandler replaces the template, projects following it take the changes over except for the
+// items edited in the project, which are listed in the response
+func (c Controller) UpdateMilestoneTemplateHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	template, err := readMilestoneTemplate(ctx)
+	if err != nil || template == nil {
+		return err
+	}
+	template.ID = ctx.Params("id")
+
+	response, err := c.dbClient.UpdateMilestoneTemplate(ctx.Context(), *template, authority.UserID)
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+func (c Controller) DeleteMilestoneTemplateHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	err := c.dbClient.DeleteMilestoneTemplate(ctx.Context(), ctx.Params("id"), authority.UserID)
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+func (c Controller) GetMilestoneTemplatesHandler(ctx *fiber.Ctx) error {
+	response, err := c.dbClient.GetMilestoneTemplates(ctx.Context())
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(500).JSON(message)
+	}
+	return ctx.Status(200).JSON(response)
+}
+
+// SetTemplateFollowHandler lets the primary supervisor opt the project in or out of template updates
+func (c Controller) SetTemplateFollowHandler(ctx *fiber.Ctx) error {
+	var (
+		authority security.Authority
+		ok        bool
+	)
+	if authority, ok = ctx.UserContext().Value(security.AuthorityKey{}).(security.Authority); !ok {
+		message := model.ErrorMessage{
+			Message: "cannot extract user id",
+		}
+
+		return ctx.Status(401).JSON(message)
+	}
+
+	var request model.TemplateFollowRequest
+	if err := json.Unmarshal(ctx.Body(), &request); err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return ctx.Status(400).JSON(message)
+	}
+
+	err := c.dbClient.SetTemplateFollow(ctx.Context(), ctx.Params("id"), authority.UserID, request.FollowUpdates)
+	if err != nil {
+		return projectError(ctx, err)
+	}
+	return ctx.SendStatus(204)
+}
+
+// readMilestoneTemplate parses and validates the request body, the response has been written when no template is
+// returned
+func readMilestoneTemplate(ctx *fiber.Ctx) (*db.MilestoneTemplate, error) {
+	var request model.MilestoneTemplate
+	err := json.Unmarshal(ctx.Body(), &request)
+	if err != nil {
+		message := model.ErrorMessage{
+			Message: err.Error(),
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+
+	template, fieldErrors := validateMilestoneTemplate(request)
+	if len(fieldErrors) > 0 {
+		message := model.ValidationErrorMessage{
+			Message: "invalid milestone template",
+			Fields:  fieldErrors,
+		}
+		return nil, ctx.Status(400).JSON(message)
+	}
+	return &template, nil
+}
+
+func validateMilestoneTemplate(request model.MilestoneTemplate) (db.MilestoneTemplate, []model.FieldError) {
+	fieldErrors := []model.FieldError{}
+	template := db.MilestoneTemplate{
+		Name:      strings.TrimSpace(request.Name),
+		IsDefault: request.IsDefault,
+	}
+
+	if template.Name == "" {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "name", Message: "is required"})
+	}
+	if len(request.Milestones) == 0 {
+		fieldErrors = append(fieldErrors, model.FieldError{Field: "milestones", Message: "must not be empty"})
+	}
+	for i, m := range request.Milestones {
+		milestone := db.TemplateMilestone{
+			ID:             m.ID,
+			Name:           strings.TrimSpace(m.Name),
+			Description:    m.Description,
+			Links:          m.Links,
+			StartDay:       m.StartDay,
+			EndDay:         m.EndDay,
+			DeadlineLocked: m.DeadlineLocked,
+		}
+		field := fmt.Sprintf("milestones[%d]", i)
+		if milestone.Name == "" {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + ".name", Message: "is required"})
+		}
+		if milestone.StartDay < 0 || milestone.StartDay > maxTemplateDay {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + ".startDay", Message: fmt.Sprintf("must be between 0 and %d", maxTemplateDay)})
+		}
+		if milestone.EndDay < milestone.StartDay || milestone.EndDay > maxTemplateDay {
+			fieldErrors = append(fieldErrors, model.FieldError{Field: field + ".endDay", Message: fmt.Sprintf("must be between startDay and %d", maxTemplateDay)})
+		}
+		template.Milestones = append(template.Milestones, milestone)
+	}
+	return template, fieldErrors
+}
+
+func templateError(ctx *fiber.Ctx, err error) error {
+	message := model.ErrorMessage{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, db.ErrTemplateNotFound):
+		return ctx.Status(404).JSON(message)
+	case errors.Is(err, db.ErrTemplateNameTaken):
+		return ctx.Status(409).JSON(message)
+	case errors.Is(err, db.ErrTemplateMilestoneNotFound):
+		return ctx.Status(400).JSON(message)
+	default:
+		return ctx.Status(500).JSON(message)
+	}
+}
@@ -0,0 +1,114 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Status is the lifecycle state of a project application
+type Status string
+
+const (
+	Draft       Status = "draft"
+	Submitted   Status = "submitted"
+	Waitlisted  Status = "waitlisted"
+	UnderReview Status = "under_review"
+	Shortlisted Status = "shortlisted"
+	Offered     Status = "offered"
+	Accepted    Status = "accepted"
+	Declined    Status = "declined"
+	Withdrawn   Status = "withdrawn"
+	Expired     Status = "expired"
+)
+
+// Actor is the party performing a transition
+type Actor string
+
+const (
+	Student    Actor = "student"
+	Supervisor Actor = "supervisor"
+	System     Actor = "system"
+)
+
+// ErrInvalidTransition is returned when the target status cannot be reached from the current one
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrActorNotAllowed is returned when the actor is not allowed to move an application into the target status
+var ErrActorNotAllowed = errors.New("actor not allowed to perform transition")
+
+// ErrUnknownStatus is returned when parsing a value which is not a lifecycle status
+var ErrUnknownStatus = errors.New("unknown application status")
+
+// transitions lists the statuses reachable from each status, terminal statuses have none
+var transitions = map[Status][]Status{
+	Draft:       {Submitted, Withdrawn},
+	Submitted:   {UnderReview, Shortlisted, Offered, Accepted, Declined, Withdrawn, Expired},
+	Waitlisted:  {Submitted, Declined, Withdrawn, Expired},
+	UnderReview: {Shortlisted, Offered, Accepted, Declined, Withdrawn, Expired},
+	Shortlisted: {Offered, Accepted, Declined, Withdrawn, Expired},
+	Offered:     {Accepted, Declined, Withdrawn, Expired},
+	Accepted:    {},
+	Declined:    {},
+	Withdrawn:   {},
+	Expired:     {},
+}
+
+// actors lists who may move an application into each status
+var actors = map[Status][]Actor{
+	Submitted:   {Student},
+	Waitlisted:  {System},
+	UnderReview: {Supervisor},
+	Shortlisted: {Supervisor},
+	Offered:     {Supervisor},
+	Accepted:    {Supervisor},
+	Declined:    {Supervisor},
+	Withdrawn:   {Student},
+	Expired:     {System},
+}
+
+// transition is a move from one status to another
+type transition struct {
+	from Status
+	to   Status
+}
+
+// transitionActors replaces the actors of the target status for particular transitions, students only accept
+// an application once the supervisor offered it
+var transitionActors = map[transition][]Actor{
+	{Offered, Accepted}: {Supervisor, Student},
+}
+
+// Parse converts a string into a Status
+func Parse(value string) (Status, error) {
+	status := Status(value)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownStatus, value)
+	}
+	return status, nil
+}
+
+// Check verifies that actor may move an application from one status to another
+func Check(from, to Status, actor Actor) error {
+	if !slices.Contains(transitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	allowed, ok := transitionActors[transition{from, to}]
+	if !ok {
+		allowed = actors[to]
+	}
+	if actor != System && !slices.Contains(allowed, actor) {
+		return fmt.Errorf("%w: %s cannot move an application from %s to %s", ErrActorNotAllowed, actor, from, to)
+	}
+	return nil
+}
+
+// Terminal reports whether no further transitions are possible from the status
+func (s Status) Terminal() bool {
+	return len(transitions[s]) == 0
+}
+
+// Pending reports whether the application is still waiting for an outcome
+func (s Status) Pending() bool {
+	return s != Draft && !s.Terminal()
+}
@@ -0,0 +1,69 @@
+package lifecycle
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  Status
+		to    Status
+		actor Actor
+		err   error
+	}{
+		{"student submits draft", Draft, Submitted, Student, nil},
+		{"supervisor reviews", Submitted, UnderReview, Supervisor, nil},
+		{"supervisor accepts directly", Submitted, Accepted, Supervisor, nil},
+		{"student accepts offer", Offered, Accepted, Student, nil},
+		{"student withdraws shortlisted", Shortlisted, Withdrawn, Student, nil},
+		{"system expires", UnderReview, Expired, System, nil},
+		{"system may act for anyone", Offered, Declined, System, nil},
+		{"student cannot decline", Submitted, Declined, Student, ErrActorNotAllowed},
+		{"student cannot accept a submitted application", Submitted, Accepted, Student, ErrActorNotAllowed},
+		{"student cannot accept an application under review", UnderReview, Accepted, Student, ErrActorNotAllowed},
+		{"student cannot accept a shortlisted application", Shortlisted, Accepted, Student, ErrActorNotAllowed},
+		{"supervisor accepts offer", Offered, Accepted, Supervisor, nil},
+		{"supervisor cannot withdraw", Submitted, Withdrawn, Supervisor, ErrActorNotAllowed},
+		{"supervisor cannot expire", Submitted, Expired, Supervisor, ErrActorNotAllowed},
+		{"accepted is terminal", Accepted, Declined, Supervisor, ErrInvalidTransition},
+		{"cannot go backwards", Offered, Shortlisted, Supervisor, ErrInvalidTransition},
+		{"draft is not visible to supervisors", Draft, UnderReview, Supervisor, ErrInvalidTransition},
+		{"same status", Submitted, Submitted, Student, ErrInvalidTransition},
+		{"system promotes waitlisted", Waitlisted, Submitted, System, nil},
+		{"student withdraws waitlisted", Waitlisted, Withdrawn, Student, nil},
+		{"waitlisted cannot be offered", Waitlisted, Offered, Supervisor, ErrInvalidTransition},
+		{"only the system waitlists", Submitted, Waitlisted, Supervisor, ErrInvalidTransition},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(test.from, test.to, test.actor)
+			if test.err == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	status, err := Parse("under_review")
+	assert.Nil(t, err)
+	assert.Equal(t, UnderReview, status)
+
+	_, err = Parse("pending")
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+}
+
+func TestPendingAndTerminal(t *testing.T) {
+	assert.True(t, Submitted.Pending())
+	assert.True(t, Offered.Pending())
+	assert.True(t, Waitlisted.Pending())
+	assert.False(t, Draft.Pending())
+	assert.False(t, Declined.Pending())
+	assert.True(t, Expired.Terminal())
+	assert.False(t, Shortlisted.Terminal())
+}
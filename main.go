@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
 	"github.com/Simplyphotons/fyp.git/auth0"
 	"github.com/Simplyphotons/fyp.git/db"
+	"github.com/Simplyphotons/fyp.git/expiry"
 	"github.com/Simplyphotons/fyp.git/handlers"
+	"github.com/Simplyphotons/fyp.git/lifecycle"
 	"github.com/Simplyphotons/fyp.git/oauth2"
+	"github.com/Simplyphotons/fyp.git/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata" // the scratch image has no zoneinfo, gantt dates may be given in any IANA time zone
 )
 
 func main() {
@@ -92,7 +101,99 @@ func main() {
 		os.Exit(2)
 	}
 
-	controller := handlers.New(dbClient, auth0Client, supervisorRoleID) //dependency injection
+	maxPreferences := 5
+	maxPreferencesStr := os.Getenv("MAX_PREFERENCES")
+	if maxPreferencesStr != "" {
+		maxPreferences, err = strconv.Atoi(maxPreferencesStr)
+		if err != nil || maxPreferences < 1 {
+			slog.Error("MAX_PREFERENCES must be a positive number")
+			os.Exit(1)
+		}
+	}
+
+	var store storage.Store
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		storagePath := os.Getenv("STORAGE_PATH")
+		if storagePath == "" {
+			storagePath = "attachments"
+		}
+		store, err = storage.NewLocal(storagePath)
+	case "s3":
+		store, err = storage.NewS3(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: strings.ToUpper(os.Getenv("S3_PATH_STYLE")) == "TRUE",
+		}, &http.Client{})
+	default:
+		err = fmt.Errorf("unknown backend %q, use local or s3", backend)
+	}
+	if err != nil {
+		log.Printf("cannot create attachment storage: %v", err)
+		os.Exit(2)
+	}
+
+	attachmentMaxBytes := int64(10 << 20)
+	if value := os.Getenv("ATTACHMENT_MAX_BYTES"); value != "" {
+		attachmentMaxBytes, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || attachmentMaxBytes < 1 {
+			slog.Error("ATTACHMENT_MAX_BYTES must be a positive number")
+			os.Exit(1)
+		}
+	}
+
+	attachmentTypes := []string{"application/pdf"}
+	if value := os.Getenv("ATTACHMENT_TYPES"); value != "" {
+		attachmentTypes = strings.Split(value, ",")
+	}
+
+	downloadSecret := []byte(os.Getenv("ATTACHMENT_URL_SECRET"))
+	if len(downloadSecret) == 0 {
+		slog.Warn("ATTACHMENT_URL_SECRET is not set, download links will not survive a restart")
+		downloadSecret = make([]byte, 32)
+		rand.Read(downloadSecret)
+	}
+
+	downloadTTL := 15 * time.Minute
+	if value := os.Getenv("ATTACHMENT_URL_TTL"); value != "" {
+		downloadTTL, err = time.ParseDuration(value)
+		if err != nil || downloadTTL <= 0 {
+			slog.Error("ATTACHMENT_URL_TTL must be a positive duration, e.g. 15m")
+			os.Exit(1)
+		}
+	}
+
+	expiryDays := map[string]int{"APPLICATION_EXPIRY_DAYS": 30, "OFFER_EXPIRY_DAYS": 7}
+	for name := range expiryDays {
+		if value := os.Getenv(name); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 1 {
+				slog.Error(name + " must be a positive number")
+				os.Exit(1)
+			}
+			expiryDays[name] = days
+		}
+	}
+
+	sweepInterval := time.Hour
+	if value := os.Getenv("EXPIRY_SWEEP_INTERVAL"); value != "" {
+		sweepInterval, err = time.ParseDuration(value)
+		if err != nil || sweepInterval <= 0 {
+			slog.Error("EXPIRY_SWEEP_INTERVAL must be a positive duration, e.g. 1h")
+			os.Exit(1)
+		}
+	}
+	go expiry.New(dbClient, sweepInterval, expiryDays["APPLICATION_EXPIRY_DAYS"], expiryDays["OFFER_EXPIRY_DAYS"]).Run(context.Background())
+
+	controller := handlers.New(dbClient, auth0Client, supervisorRoleID, //dependency injection
+		handlers.MaxPreferences(maxPreferences),
+		handlers.Storage(store, os.Getenv("ATTACHMENT_SPOOL_DIR")),
+		handlers.AttachmentLimits(attachmentMaxBytes, attachmentTypes),
+		handlers.DownloadLinks(downloadSecret, downloadTTL),
+	)
 
 	//Initialize oauth2 middleware
 	oauth2Config, err := oauth2.Build(
@@ -107,15 +208,19 @@ func main() {
 		os.Exit(2)
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		StreamRequestBody: true, //attachments are read part by part instead of being buffered
+	})
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: allowOrigins,
-		AllowMethods: allowMethods,
-		AllowHeaders: allowHeaders,
+		AllowOrigins:  allowOrigins,
+		AllowMethods:  allowMethods,
+		AllowHeaders:  allowHeaders,
+		ExposeHeaders: "ETag", //clients send it back as If-Match when changing gantt items
 	}))
 
 	app.Post("/authorize", controller.AuthorizeHandler)
-	app.Patch("/disableAlert/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.DisableAlertHandler)
+	app.Get("/calendar/:token.ics", controller.CalendarFeedHandler) //calendar applications authenticate with the secret token
+	app.Patch("/disableAlert/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.MarkFeedbackReadHandler)
 
 	app.Post("/newQuestion", oauth2Config.Authorize([]string{"read:student"}), controller.NewQuestion)                //creates new question
 	app.Post("/newAnswer", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.NewAnswer) //creates new answer for particular question and adds to db
@@ -127,23 +232,106 @@ func main() {
 	app.Get("/getSpecificApplications/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetSpecificApplicationsHandler) //retrieves one specific applications
 	app.Get("/getGanttItem/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetGanttItem)
 	app.Get("/getGantt/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetGantt)
+	app.Get("/projects/:id/gantt/chart", oauth2Config.Authorize([]string{"read:supervisor", "read:student", "read:admin"}), controller.GetGanttChartHandler) //coordinators see every project
+	app.Put("/projects/:id/template", oauth2Config.Authorize([]string{"read:supervisor"}), controller.SetTemplateFollowHandler)
+	app.Get("/projects/:id/gantt/export", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.ExportGanttHandler)
 	app.Get("/getSupervisors", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetSupervisorHandler)
 	app.Get("/getProjectStatus", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetHasProjectStatusHandler)
 	app.Get("/getProjects", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectsHandler)
-	app.Get("/getProjectID", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectIDHandler)
+	app.Get("/getMyProjects", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetMyProjectsHandler)
 	app.Get("/getFeedback/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetFeedback)
 	app.Get("/getProjectName/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectNameHandler)
 	app.Get("/getUsername/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetUsernameHandler)
-	app.Get("/getSecondProjects", oauth2Config.Authorize([]string{"read:supervisor"}), controller.GetSecondProjectsHandler)
-	app.Get("/getSecondReaderStatus/:id", oauth2Config.Authorize([]string{"read:student", "read:supervisor"}), controller.GetSecondReaderStatusHandler)
 	app.Get("/verify", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.VerifyHandler)
 	app.Post("/createProject", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CreateProjectHandler)         //post createproject
 	app.Post("/createApplication", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CreateApplicationHandler) //post createapplication
 	app.Post("/createSupervisorUser", oauth2Config.Authorize([]string{"read:admin"}), controller.CreateSupervisorHandler)
 	//patch acceptapplication
 	app.Patch("/declineApplication", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.DeclineApplicationHandler) //patch declineapplication
-	app.Patch("/addSecondReader/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.AddSecondReaderHandler)                   //patch declineapplication
+	app.Patch("/submitApplication/:id", oauth2Config.Authorize([]string{"read:student"}), controller.TransitionApplicationHandler(lifecycle.Submitted))
+	app.Patch("/reviewApplication/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.TransitionApplicationHandler(lifecycle.UnderReview))
+	app.Patch("/shortlistApplication/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.TransitionApplicationHandler(lifecycle.Shortlisted))
+	app.Patch("/offerApplication/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.TransitionApplicationHandler(lifecycle.Offered))
+	app.Patch("/acceptApplication/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.TransitionApplicationHandler(lifecycle.Accepted))
+	app.Patch("/declineApplication/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.TransitionApplicationHandler(lifecycle.Declined))
+	app.Patch("/withdrawApplication/:id", oauth2Config.Authorize([]string{"read:student"}), controller.TransitionApplicationHandler(lifecycle.Withdrawn))
+	app.Get("/getApplicationHistory/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetApplicationHistoryHandler)
+	app.Put("/setPreferences", oauth2Config.Authorize([]string{"read:student"}), controller.SetPreferencesHandler)
+	app.Get("/getPreferences", oauth2Config.Authorize([]string{"read:student"}), controller.GetPreferencesHandler)
+	app.Put("/setApplicantRanking", oauth2Config.Authorize([]string{"read:supervisor"}), controller.SetApplicantRankingHandler)
+	app.Get("/getApplicantRanking", oauth2Config.Authorize([]string{"read:supervisor"}), controller.GetApplicantRankingHandler)
+	app.Post("/previewAllocation", oauth2Config.Authorize([]string{"read:admin"}), controller.PreviewAllocationHandler) //what-if run, nothing is stored
+	app.Post("/runAllocation", oauth2Config.Authorize([]string{"read:admin"}), controller.RunAllocationHandler)
+	app.Get("/getAllocationRun/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.GetAllocationRunHandler)
+	app.Post("/commitAllocation/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.CommitAllocationHandler) //creates the projects of a run
+	app.Put("/setExpertise", oauth2Config.Authorize([]string{"read:supervisor"}), controller.SetExpertiseHandler)
+	app.Get("/getExpertise", oauth2Config.Authorize([]string{"read:supervisor"}), controller.GetExpertiseHandler)
+	app.Post("/runReaderAllocation", oauth2Config.Authorize([]string{"read:admin"}), controller.RunReaderAllocationHandler) //proposes second readers for review
+	app.Get("/getReaderAllocationRun/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.GetReaderAllocationRunHandler)
+	app.Post("/commitReaderAllocation/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.CommitReaderAllocationHandler)
+	app.Post("/declareConflict", oauth2Config.Authorize([]string{"read:supervisor"}), controller.DeclareConflictHandler)
+	app.Get("/getConflicts", oauth2Config.Authorize([]string{"read:supervisor"}), controller.GetConflictsHandler)
+	app.Delete("/withdrawConflict/:userID", oauth2Config.Authorize([]string{"read:supervisor"}), controller.WithdrawConflictHandler)
+	app.Post("/assignSecondReader/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.AssignSecondReaderHandler) //overrideReason is audited
+	app.Get("/getAuditLog", oauth2Config.Authorize([]string{"read:admin"}), controller.GetAuditLogHandler)
+	app.Put("/setCapacity", oauth2Config.Authorize([]string{"read:supervisor"}), controller.SetCapacityHandler)
+	app.Get("/getCapacity", oauth2Config.Authorize([]string{"read:supervisor"}), controller.GetCapacityHandler)
+	app.Get("/getNotifications", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetNotificationsHandler)
+	app.Patch("/readNotification/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.ReadNotificationHandler)
+	app.Post("/sendApplicationMessage/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.SendApplicationMessageHandler)
+	app.Get("/getApplicationMessages/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetApplicationMessagesHandler)
+	app.Patch("/readApplicationMessages/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.ReadApplicationMessagesHandler)
+	app.Get("/getProjectMessages/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectMessagesHandler)
+	app.Post("/createInterviewSlots", oauth2Config.Authorize([]string{"read:supervisor"}), controller.CreateInterviewSlotsHandler)
+	app.Delete("/deleteInterviewSlot/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.DeleteInterviewSlotHandler)
+	app.Get("/getInterviewSlots", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetInterviewSlotsHandler)
+	app.Post("/bookInterviewSlot/:id", oauth2Config.Authorize([]string{"read:student"}), controller.BookInterviewSlotHandler)
+	app.Patch("/cancelInterview/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CancelInterviewHandler)
+	app.Get("/getInterviewInvite/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetInterviewInviteHandler)
+	app.Get("/getProjectMembers/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectMembersHandler)
+	app.Post("/addProjectMember/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.AddProjectMemberHandler)
+	app.Delete("/removeProjectMember/:id/:userID", oauth2Config.Authorize([]string{"read:supervisor"}), controller.RemoveProjectMemberHandler)
+	app.Get("/getTeamInvites", oauth2Config.Authorize([]string{"read:student"}), controller.GetTeamInvitesHandler)
+	app.Patch("/confirmTeamInvite/:id", oauth2Config.Authorize([]string{"read:student"}), controller.RespondTeamInviteHandler(true))
+	app.Patch("/declineTeamInvite/:id", oauth2Config.Authorize([]string{"read:student"}), controller.RespondTeamInviteHandler(false))
+	app.Get("/getApplicationTeam/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetApplicationTeamHandler)
+	app.Put("/setGanttContributions/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.SetGanttContributionsHandler)
+	app.Post("/addGanttDependency", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.AddGanttDependencyHandler)
+	app.Delete("/removeGanttDependency/:predecessorID/:successorID", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.RemoveGanttDependencyHandler)
+	app.Post("/addAttachment/:id", oauth2Config.Authorize([]string{"read:student"}), controller.AddAttachmentHandler)
+	app.Get("/getAttachments/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetAttachmentsHandler)
+	app.Get("/downloadAttachment/:id", controller.DownloadAttachmentHandler) //authorized by the signed link from getAttachments
+	app.Get("/getRounds", oauth2Config.Authorize([]string{"read:supervisor", "read:student", "read:admin"}), controller.GetRoundsHandler)
+	app.Get("/getRoundStatus", oauth2Config.Authorize([]string{"read:supervisor", "read:student", "read:admin"}), controller.GetRoundStatusHandler)
+	app.Post("/createRound", oauth2Config.Authorize([]string{"read:admin"}), controller.CreateRoundHandler)
+	app.Put("/updateRound/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.UpdateRoundHandler)
+	app.Get("/getMilestoneTemplates", oauth2Config.Authorize([]string{"read:supervisor", "read:student", "read:admin"}), controller.GetMilestoneTemplatesHandler)
+	app.Post("/createMilestoneTemplate", oauth2Config.Authorize([]string{"read:admin"}), controller.CreateMilestoneTemplateHandler)
+	app.Put("/updateMilestoneTemplate/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.UpdateMilestoneTemplateHandler) //projects following the template are updated
+	app.Delete("/deleteMilestoneTemplate/:id", oauth2Config.Authorize([]string{"read:admin"}), controller.DeleteMilestoneTemplateHandler)
+	app.Get("/getProposals", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProposalsHandler)
+	app.Get("/getProposal/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProposalHandler)
+	app.Post("/createProposal", oauth2Config.Authorize([]string{"read:supervisor"}), controller.CreateProposalHandler)
+	app.Put("/updateProposal/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.UpdateProposalHandler)
+	app.Patch("/publishProposal/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.ProposalStatusHandler(db.ProposalPublished))
+	app.Patch("/archiveProposal/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.ProposalStatusHandler(db.ProposalArchived))
+	app.Delete("/deleteProposal/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.DeleteProposalHandler)  //drafts only
+	app.Patch("/addSecondReader/:id", oauth2Config.Authorize([]string{"read:supervisor"}), controller.AddSecondReaderHandler) //patch declineapplication
 	app.Patch("/completeGanttItem", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CompleteGanttItemHandler)
+	app.Patch("/setGanttStatus/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.SetGanttStatusHandler)
+	app.Patch("/gantt-items/:id", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.EditGanttItemHandler)
+	app.Get("/gantt-items/:id/comments", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetFeedbackCommentsHandler)
+	app.Post("/gantt-items/:id/comments", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.AddFeedbackCommentHandler)
+	app.Patch("/gantt-items/:id/comments/:commentID", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.EditFeedbackCommentHandler)
+	app.Delete("/gantt-items/:id/comments/:commentID", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.DeleteFeedbackCommentHandler)
+	app.Post("/gantt-items/:id/read", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.MarkFeedbackReadHandler)
+	app.Get("/unread-feedback", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetUnreadFeedbackHandler)
+	app.Get("/projects/:id/unread-feedback", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetProjectUnreadFeedbackHandler)
+	app.Post("/unread-feedback/read-all", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.MarkAllFeedbackReadHandler)
+	app.Post("/calendar-feed", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CreateCalendarFeedHandler)
+	app.Get("/calendar-feed", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.GetCalendarFeedHandler)
+	app.Delete("/calendar-feed", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.RevokeCalendarFeedHandler)
+	app.Post("/projects/:id/gantt/import", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.ImportGanttHandler)
 	app.Post("/createGanttItem", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CreateGanttItemHandler) //creates Gantt item in db
 	app.Patch("/updateFeedback", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.AddFeedbackHandler)
 	app.Post("/createStudentUser", oauth2Config.Authorize([]string{"read:supervisor", "read:student"}), controller.CreateStudentHandler)
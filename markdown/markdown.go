@@ -0,0 +1,123 @@
+// Package markdown renders the small subset of Markdown used in messages and comments to HTML. All input is
+// escaped first, so raw HTML in a message is shown as text, and only http, https and mailto links are kept.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	codeSpan = regexp.MustCompile("`([^`]+)`")
+	link     = regexp.MustCompile(`\[([^\]]+)\]\(((?:https?://|mailto:)[^\s)]+)\)`)
+	strong   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	emphasis = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:.*?\S)?)[*_]($|[^\w*])`)
+	heading  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	ordered  = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+)
+
+// ToHTML converts Markdown into HTML that is safe to embed in a page
+func ToHTML(source string) string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	var (
+		out       strings.Builder
+		paragraph []string
+		list      string // tag of the open list, if any
+	)
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		flushParagraph()
+		if list != tag {
+			closeList()
+			out.WriteString("<" + tag + ">")
+			list = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case heading.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			match := heading.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(match[1])))
+			out.WriteString("<h" + level + ">" + inline(match[2]) + "</h" + level + ">")
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			openList("ul")
+			out.WriteString("<li>" + inline(strings.TrimSpace(trimmed[2:])) + "</li>")
+		case ordered.MatchString(trimmed):
+			openList("ol")
+			out.WriteString("<li>" + inline(ordered.FindStringSubmatch(trimmed)[1]) + "</li>")
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			closeList()
+			out.WriteString("<blockquote>" + inline(strings.TrimSpace(trimmed[1:])) + "</blockquote>")
+		default:
+			closeList()
+			paragraph = append(paragraph, inline(trimmed))
+		}
+	}
+	flushParagraph()
+	closeList()
+	return out.String()
+}
+
+// inline renders code spans, links and emphasis, text inside code spans is left alone
+func inline(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, span := range codeSpan.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(formatText(text[last:span[0]]))
+		out.WriteString("<code>" + html.EscapeString(text[span[2]:span[3]]) + "</code>")
+		last = span[1]
+	}
+	out.WriteString(formatText(text[last:]))
+	return out.String()
+}
+
+// formatText renders links and emphasis, only the text around links and their label are emphasised so the markup
+// never ends up inside an href
+func formatText(text string) string {
+	text = html.EscapeString(text)
+	var out strings.Builder
+	last := 0
+	for _, span := range link.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(emphasise(text[last:span[0]]))
+		out.WriteString(`<a href="` + text[span[4]:span[5]] + `" rel="nofollow noopener" target="_blank">` + emphasise(text[span[2]:span[3]]) + "</a>")
+		last = span[1]
+	}
+	out.WriteString(emphasise(text[last:]))
+	return out.String()
+}
+
+func emphasise(text string) string {
+	text = strong.ReplaceAllString(text, "<strong>$1</strong>")
+	return emphasis.ReplaceAllString(text, "$1<em>$2</em>$3")
+}
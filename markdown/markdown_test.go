@@ -0,0 +1,33 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{"paragraphs", "first line\nsecond line\n\nnext", "<p>first line<br>second line</p><p>next</p>"},
+		{"emphasis", "a **bold** and *soft* word", "<p>a <strong>bold</strong> and <em>soft</em> word</p>"},
+		{"snake case is not emphasis", "use go_test_file here", "<p>use go_test_file here</p>"},
+		{"code span", "run `go test *./...*`", "<p>run <code>go test *./...*</code></p>"},
+		{"link", "see [the spec](https://example.com/a?b=1&c=2)", `<p>see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener" target="_blank">the spec</a></p>`},
+		{"underscores in link", "read [the *new* notes](https://a.b/some_path_here) _today_", `<p>read <a href="https://a.b/some_path_here" rel="nofollow noopener" target="_blank">the <em>new</em> notes</a> <em>today</em></p>`},
+		{"stars in link", "[x](https://a.b/a*b*c) and **bold**", `<p><a href="https://a.b/a*b*c" rel="nofollow noopener" target="_blank">x</a> and <strong>bold</strong></p>`},
+		{"script link is text", "[click](javascript:alert(1))", "<p>[click](javascript:alert(1))</p>"},
+		{"html is escaped", "<script>alert('x')</script>", "<p>&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;</p>"},
+		{"lists", "- one\n- two\n1. first", "<ul><li>one</li><li>two</li></ul><ol><li>first</li></ol>"},
+		{"heading and quote", "## Plan\n> quoted", "<h2>Plan</h2><blockquote>quoted</blockquote>"},
+		{"code block", "```\nif a < b {\n}\n```", "<pre><code>if a &lt; b {\n}</code></pre>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.html, ToHTML(test.markdown))
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type AuthorizationRequest struct { //400
 	Code         string `json:"code"`
 	RefreshToken string `json:"refresh_token"`
@@ -9,6 +11,21 @@ type ErrorMessage struct {
 	Message string `json:"message"`
 }
 
+type ValidationErrorMessage struct {
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Page[T any] struct { //envelope returned by all list endpoints
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"nextCursor"`
+}
+
 type GetQuestionsResponse struct {
 	Questions []Question `json:"questions"`
 }
@@ -30,16 +47,77 @@ type GetGanttResponse struct {
 }
 
 type Gantt struct {
-	ID          string `json:"id"`
+	ID              string         `json:"id"`
+	ProjectID       string         `json:"projectID"`
+	GanttName       string         `json:"ganttName"`
+	StartDate       time.Time      `json:"startDate"`
+	EndDate         time.Time      `json:"endDate"`
+	Description     string         `json:"description"`
+	Links           string         `json:"links"`
+	NewFeedback     string         `json:"newFeedback"`
+	Colour          string         `json:"colour"` //derived from the status
+	Status          string         `json:"status"`
+	PercentComplete int            `json:"percentComplete"`
+	DeadlineLocked  bool           `json:"deadlineLocked"` //only supervisors move the end date
+	Version         int            `json:"version"`        //also sent as the ETag of the item
+	Contributions   []Contribution `json:"contributions,omitempty"`
+	Dependencies    []Dependency   `json:"dependencies,omitempty"` //items this one waits for
+	SlackDays       float64        `json:"slackDays"`              //how far the item can slip before the plan ends later
+	Critical        bool           `json:"critical"`
+}
+
+type Dependency struct {
+	PredecessorID string `json:"predecessorID"`
+	Type          string `json:"type"`
+	LagDays       int    `json:"lagDays"`
+}
+
+type GanttStatusRequest struct {
+	Status          string `json:"status"` //on_track, at_risk, blocked or complete
+	PercentComplete int    `json:"percentComplete"`
+}
+
+type DependencyRequest struct {
+	PredecessorID string `json:"predecessorID"`
+	SuccessorID   string `json:"successorID"`
+	Type          string `json:"type"` //finish_to_start (default), start_to_start or finish_to_finish
+	LagDays       int    `json:"lagDays"`
+}
+
+type CreateGanttItemRequest struct { //dates are ISO-8601, either a full timestamp or a date interpreted in TimeZone
 	ProjectID   string `json:"projectID"`
 	GanttName   string `json:"ganttName"`
 	StartDate   string `json:"startDate"`
 	EndDate     string `json:"endDate"`
+	TimeZone    string `json:"timeZone"`
 	Description string `json:"description"`
 	Links       string `json:"links"`
-	Feedback    string `json:"feedback"`
-	NewFeedback string `json:"newFeedback"`
-	Colour      string `json:"colour"`
+}
+
+// GanttImportItem is a task of an imported plan file, dependencies name the id of the other task in the file
+type GanttImportItem struct {
+	Row             int          `json:"row"`
+	Ref             string       `json:"ref"`          //id of the task in the file
+	ID              string       `json:"id,omitempty"` //the created item, empty in a preview
+	GanttName       string       `json:"ganttName"`
+	StartDate       time.Time    `json:"startDate"`
+	EndDate         time.Time    `json:"endDate"`
+	Description     string       `json:"description"`
+	Links           string       `json:"links"`
+	PercentComplete int          `json:"percentComplete"`
+	Dependencies    []Dependency `json:"dependencies,omitempty"`
+}
+
+type ImportRowError struct {
+	Row     int    `json:"row"` //0 for the file as a whole
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type GanttImport struct {
+	Preview bool              `json:"preview"`
+	Items   []GanttImportItem `json:"items"`
+	Errors  []ImportRowError  `json:"errors,omitempty"` //nothing is created while there are errors
 }
 
 type GanttChartRow struct {
@@ -60,27 +138,65 @@ type QuestionData struct {
 }
 
 type ApplicationData struct {
-	ID           string `json:"id,omitempty"`
-	StudentID    string `json:"student_id"`
-	StudentName  string `json:"student_name"`
-	SupervisorID string `json:"supervisor_id"`
-	Heading      string `json:"heading"`
-	Description  string `json:"description"`
-	Accepted     bool   `json:"accepted"`
-	Declined     bool   `json:"declined"`
+	ID             string   `json:"id,omitempty"`
+	StudentID      string   `json:"student_id"`
+	StudentName    string   `json:"student_name"`
+	SupervisorID   string   `json:"supervisor_id"`
+	Heading        string   `json:"heading"`
+	Description    string   `json:"description"`
+	Status         string   `json:"status"`
+	Accepted       bool     `json:"accepted"` //derived from status, kept for existing clients
+	Declined       bool     `json:"declined"`
+	Reason         string   `json:"reason,omitempty"`
+	Waitlist       bool     `json:"waitlist,omitempty"` //join the waitlist when the supervisor is full instead of failing
+	ProposalID     string   `json:"proposalID,omitempty"`
+	Teammates      []string `json:"teammates,omitempty"`      //students invited to apply as a team, create only
+	TemplateID     string   `json:"templateID,omitempty"`     //milestone template of the new project, the default template when empty
+	FollowTemplate bool     `json:"followTemplate,omitempty"` //the project takes over later changes to the template
+}
+
+type TransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ApplicationTransition struct {
+	ID            string    `json:"id"`
+	ApplicationID string    `json:"applicationID"`
+	FromStatus    string    `json:"fromStatus,omitempty"`
+	ToStatus      string    `json:"toStatus"`
+	ActorID       string    `json:"actorID,omitempty"`
+	ActorRole     string    `json:"actorRole"`
+	Reason        string    `json:"reason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
 }
 
 type ProjectData struct {
-	ID           string `json:"id,omitempty"`
-	Name         string `json:"name"`
-	StudentID    string `json:"studentID"`
-	SupervisorID string `json:"supervisorID"`
+	ID           string          `json:"id,omitempty"`
+	Name         string          `json:"name"`
+	StudentID    string          `json:"studentID"`
+	SupervisorID string          `json:"supervisorID"` //primary supervisor
+	ProposalID   string          `json:"proposalID,omitempty"`
+	Role         string          `json:"role,omitempty"` //role of the caller in the project
+	Members      []ProjectMember `json:"members,omitempty"`
+}
+
+type ProjectMember struct {
+	UserID string `json:"userID"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+type ProjectMemberRequest struct {
+	UserID string `json:"userID"`
+	Role   string `json:"role"` //co_supervisor, second_reader or external_mentor
 }
 
 type UserData struct {
 	ID           string `json:"id"`
 	Name         string `json:"name"`
-	isSupervisor bool   `json:"isSupervisor"`
+	IsSupervisor bool   `json:"isSupervisor"`
+	Capacity     *int   `json:"capacity,omitempty"`  //supervisors only, for the current academic year
+	Remaining    *int   `json:"remaining,omitempty"` //capacity left after projects and open offers
 }
 
 type UserCreateRequest struct {
@@ -94,3 +210,299 @@ type Verify struct {
 	UserId string `json:"userId"`
 	Found  bool   `json:"found"`
 }
+
+type PreferencesRequest struct {
+	SupervisorIDs []string `json:"supervisorIDs"`
+}
+
+type RankingRequest struct {
+	StudentIDs []string `json:"studentIDs"`
+}
+
+type Preference struct {
+	Rank   int    `json:"rank"`
+	UserID string `json:"userID"`
+	Name   string `json:"name"`
+}
+
+type AllocationPreviewRequest struct { //what-if capacities override the remaining capacity of a supervisor
+	Capacities map[string]int `json:"capacities"`
+}
+
+type AllocationAssignment struct {
+	StudentID    string `json:"studentID"`
+	SupervisorID string `json:"supervisorID"`
+	StudentRank  int    `json:"studentRank"`
+}
+
+type AllocationRun struct {
+	ID          string                 `json:"id,omitempty"`
+	CreatedBy   string                 `json:"createdBy,omitempty"`
+	CreatedAt   *time.Time             `json:"createdAt,omitempty"`
+	CommittedBy string                 `json:"committedBy,omitempty"`
+	CommittedAt *time.Time             `json:"committedAt,omitempty"`
+	Assignments []AllocationAssignment `json:"assignments"`
+	Unassigned  []string               `json:"unassigned"`
+}
+
+type ReaderAssignment struct {
+	ProjectID    string   `json:"projectID"`
+	ProjectName  string   `json:"projectName"`
+	SupervisorID string   `json:"supervisorID"`
+	ReaderID     string   `json:"readerID"`
+	ReaderName   string   `json:"readerName"`
+	MatchedTags  []string `json:"matchedTags"` //topics of the project in the reader's expertise
+}
+
+type ReaderAllocationRun struct {
+	ID          string             `json:"id,omitempty"`
+	CreatedBy   string             `json:"createdBy,omitempty"`
+	CreatedAt   *time.Time         `json:"createdAt,omitempty"`
+	CommittedBy string             `json:"committedBy,omitempty"`
+	CommittedAt *time.Time         `json:"committedAt,omitempty"`
+	Assignments []ReaderAssignment `json:"assignments"`
+	Unassigned  []ProjectData      `json:"unassigned"` //projects nobody can read
+}
+
+type ExpertiseRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type Conflict struct {
+	UserID     string    `json:"userID"`
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	DeclaredAt time.Time `json:"declaredAt"`
+}
+
+type ConflictRequest struct {
+	UserID string `json:"userID"`
+	Reason string `json:"reason"`
+}
+
+type AssignReaderRequest struct {
+	ReaderID       string `json:"readerID"`
+	OverrideReason string `json:"overrideReason"` //assigns despite a conflict of interest, recorded in the audit log
+}
+
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	ActorID   string    `json:"actorID"`
+	Action    string    `json:"action"`
+	SubjectID string    `json:"subjectID"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Capacity struct {
+	AcademicYear string `json:"academicYear"`
+	Capacity     int    `json:"capacity"`
+}
+
+type Notification struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt"`
+}
+
+type ProposalRequest struct {
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Prerequisites string   `json:"prerequisites"`
+	Tags          []string `json:"tags"`
+	Slots         int      `json:"slots"`
+}
+
+type Proposal struct {
+	ID             string    `json:"id"`
+	SupervisorID   string    `json:"supervisorID"`
+	SupervisorName string    `json:"supervisorName"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	Prerequisites  string    `json:"prerequisites"`
+	Tags           []string  `json:"tags"`
+	Slots          int       `json:"slots"`
+	Remaining      int       `json:"remaining"` //slots left after projects and open offers
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedBy  string    `json:"uploadedBy,omitempty"`
+	DownloadURL string    `json:"downloadUrl"`
+	ExpiresAt   time.Time `json:"expiresAt"` //when the download link stops working
+}
+
+type Round struct {
+	ID                string     `json:"id,omitempty"`
+	Name              string     `json:"name"`
+	OpensAt           time.Time  `json:"opensAt"`
+	ClosesAt          time.Time  `json:"closesAt"`
+	PendingExpiryDays *int       `json:"pendingExpiryDays"` //days without activity before a pending application expires
+	OfferExpiryDays   *int       `json:"offerExpiryDays"`   //days a student has to respond to an offer
+	StudentCap        *int       `json:"studentCap"`        //applications a student may submit in the round
+	ResponseDeadline  *time.Time `json:"responseDeadline"`  //supervisors respond by then, later applications expire
+}
+
+type MilestoneTemplate struct {
+	ID         string              `json:"id,omitempty"`
+	Name       string              `json:"name"`
+	IsDefault  bool                `json:"isDefault"` //seeded into projects created without choosing a template
+	Milestones []TemplateMilestone `json:"milestones"`
+}
+
+type TemplateMilestone struct {
+	ID             string `json:"id,omitempty"` //keep the id when updating a template so projects update their item
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Links          string `json:"links"`
+	StartDay       int    `json:"startDay"` //days after the start of the academic year
+	EndDay         int    `json:"endDay"`
+	DeadlineLocked bool   `json:"deadlineLocked"`
+}
+
+type TemplateUpdate struct {
+	ProjectsUpdated int                   `json:"projectsUpdated"` //projects following the template whose items changed
+	Skipped         []SkippedTemplateItem `json:"skipped"`         //items edited in their project, they keep their content
+}
+
+type SkippedTemplateItem struct {
+	ProjectID string `json:"projectID"`
+	ItemID    string `json:"itemID"`
+	GanttName string `json:"ganttName"`
+}
+
+type TemplateFollowRequest struct {
+	FollowUpdates bool `json:"followUpdates"`
+}
+
+type RoundStatus struct {
+	Open                  bool   `json:"open"`
+	Round                 *Round `json:"round"`     //the open round
+	NextRound             *Round `json:"nextRound"` //the next round to open when none is open
+	ApplicationsUsed      *int   `json:"applicationsUsed,omitempty"`
+	ApplicationsRemaining *int   `json:"applicationsRemaining,omitempty"` //students only, when the round has a cap
+}
+
+type CalendarFeed struct {
+	URL        string     `json:"url,omitempty"` //only returned when the feed is created, it holds the secret token
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"` //when a calendar last fetched the feed
+}
+
+type UnreadFeedback struct { //comments written by other members since the last read marker of the user
+	Total    int             `json:"total"`
+	Projects []ProjectUnread `json:"projects,omitempty"`
+	Items    []ItemUnread    `json:"items,omitempty"`
+}
+
+type ProjectUnread struct {
+	ProjectID   string `json:"projectID"`
+	ProjectName string `json:"projectName"`
+	Unread      int    `json:"unread"`
+}
+
+type ItemUnread struct {
+	ItemID    string `json:"itemID"`
+	GanttName string `json:"ganttName"`
+	Unread    int    `json:"unread"`
+}
+
+type FeedbackCommentRequest struct {
+	Body     string `json:"body"`     //markdown
+	ParentID string `json:"parentID"` //the comment to reply to, empty for a new thread
+}
+
+type FeedbackComment struct {
+	ID         string     `json:"id"`
+	ItemID     string     `json:"itemID"`
+	ParentID   string     `json:"parentID,omitempty"`
+	AuthorID   string     `json:"authorID,omitempty"` //empty for imported feedback the author is not known for
+	AuthorName string     `json:"authorName,omitempty"`
+	Role       string     `json:"role,omitempty"`
+	Body       string     `json:"body"`     //empty once deleted
+	HTML       string     `json:"html"`     //body rendered from markdown
+	Imported   bool       `json:"imported"` //converted from the old feedback text, createdAt only keeps the order
+	Deleted    bool       `json:"deleted"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	EditedAt   *time.Time `json:"editedAt"`
+}
+
+type MessageRequest struct {
+	Body string `json:"body"` //markdown
+}
+
+type Message struct {
+	ID            string     `json:"id"`
+	ApplicationID string     `json:"applicationID"`
+	AuthorID      string     `json:"authorID"`
+	AuthorName    string     `json:"authorName,omitempty"`
+	Body          string     `json:"body"`
+	HTML          string     `json:"html"` //body rendered from markdown
+	CreatedAt     time.Time  `json:"createdAt"`
+	ReadAt        *time.Time `json:"readAt"`
+	Unread        bool       `json:"unread"` //received by the caller and not read yet
+}
+
+type InterviewSlotsRequest struct {
+	Slots []InterviewSlotRequest `json:"slots"`
+}
+
+type InterviewSlotRequest struct {
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+	Location string    `json:"location"` //room or meeting link
+}
+
+type BookInterviewRequest struct {
+	ApplicationID string `json:"applicationID"`
+}
+
+type InterviewSlot struct {
+	ID             string     `json:"id"`
+	SupervisorID   string     `json:"supervisorID"`
+	SupervisorName string     `json:"supervisorName"`
+	StartsAt       time.Time  `json:"startsAt"`
+	EndsAt         time.Time  `json:"endsAt"`
+	Location       string     `json:"location"`
+	ApplicationID  string     `json:"applicationID,omitempty"` //empty while the slot is free
+	StudentID      string     `json:"studentID,omitempty"`
+	StudentName    string     `json:"studentName,omitempty"`
+	Heading        string     `json:"heading,omitempty"`
+	BookedAt       *time.Time `json:"bookedAt,omitempty"`
+	InviteURL      string     `json:"inviteURL,omitempty"` //iCalendar invite of a booked slot
+}
+
+type TeamInvite struct {
+	ApplicationID     string    `json:"applicationID"`
+	Heading           string    `json:"heading"`
+	ApplicantID       string    `json:"applicantID"`
+	ApplicantName     string    `json:"applicantName"`
+	ApplicationStatus string    `json:"applicationStatus"`
+	Status            string    `json:"status"` //invited, confirmed or declined
+	InvitedAt         time.Time `json:"invitedAt"`
+}
+
+type TeamMember struct {
+	StudentID string `json:"studentID"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+}
+
+type Contribution struct {
+	UserID string `json:"userID"`
+	Name   string `json:"name,omitempty"`
+	Share  int    `json:"share"` //percent of the item
+	Note   string `json:"note"`
+}
+
+type ContributionsRequest struct {
+	Contributions []Contribution `json:"contributions"`
+}
@@ -0,0 +1,186 @@
+// Package schedule computes the critical path of gantt items linked by dependencies
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCycle is returned when the dependencies make an item wait for itself
+var ErrCycle = errors.New("dependencies form a cycle")
+
+type DependencyType string
+
+const (
+	FinishToStart  DependencyType = "finish_to_start"  // the successor starts after the predecessor finished
+	StartToStart   DependencyType = "start_to_start"   // the successor starts after the predecessor started
+	FinishToFinish DependencyType = "finish_to_finish" // the successor finishes after the predecessor finished
+)
+
+var dependencyTypes = []DependencyType{FinishToStart, StartToStart, FinishToFinish}
+
+// ParseDependencyType converts a string into a DependencyType, finish to start when it is empty
+func ParseDependencyType(value string) (DependencyType, error) {
+	if value == "" {
+		return FinishToStart, nil
+	}
+	kind := DependencyType(value)
+	if !slices.Contains(dependencyTypes, kind) {
+		return "", fmt.Errorf("unknown dependency type %q", value)
+	}
+	return kind, nil
+}
+
+// Task is an item of the plan, it cannot start before its planned start
+type Task struct {
+	ID    string
+	Start time.Time
+	End   time.Time
+}
+
+// Dependency constrains the successor relative to the predecessor, a negative lag lets them overlap
+type Dependency struct {
+	PredecessorID string
+	SuccessorID   string
+	Type          DependencyType
+	Lag           time.Duration
+}
+
+// Timing is the window a task can run in without moving the end of the plan
+type Timing struct {
+	EarlyStart  time.Time
+	EarlyFinish time.Time
+	LateStart   time.Time
+	LateFinish  time.Time
+	Slack       time.Duration // how far the task can slip before the plan finishes later
+	Critical    bool
+}
+
+// Analyze runs the critical path method over the tasks. The forward pass pushes tasks back as far as their
+// dependencies require, the backward pass pulls them forward from the end of the plan. Tasks without slack
+// are on the critical path. Dependencies on unknown tasks are ignored.
+func Analyze(tasks []Task, dependencies []Dependency) (map[string]Timing, error) {
+	byID := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	var known []Dependency
+	for _, dependency := range dependencies {
+		_, okPredecessor := byID[dependency.PredecessorID]
+		_, okSuccessor := byID[dependency.SuccessorID]
+		if okPredecessor && okSuccessor {
+			known = append(known, dependency)
+		}
+	}
+	order, err := topological(tasks, known)
+	if err != nil {
+		return nil, err
+	}
+
+	timings := make(map[string]Timing, len(tasks))
+	var finish time.Time
+	for _, id := range order {
+		task := byID[id]
+		duration := task.End.Sub(task.Start)
+		start := task.Start
+		for _, dependency := range known {
+			if dependency.SuccessorID != id {
+				continue
+			}
+			predecessor := timings[dependency.PredecessorID]
+			var earliest time.Time
+			switch dependency.Type {
+			case StartToStart:
+				earliest = predecessor.EarlyStart.Add(dependency.Lag)
+			case FinishToFinish:
+				earliest = predecessor.EarlyFinish.Add(dependency.Lag).Add(-duration)
+			default:
+				earliest = predecessor.EarlyFinish.Add(dependency.Lag)
+			}
+			if earliest.After(start) {
+				start = earliest
+			}
+		}
+		timings[id] = Timing{EarlyStart: start, EarlyFinish: start.Add(duration)}
+		if timings[id].EarlyFinish.After(finish) {
+			finish = timings[id].EarlyFinish
+		}
+	}
+
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		timing := timings[id]
+		duration := timing.EarlyFinish.Sub(timing.EarlyStart)
+		lateFinish := finish
+		for _, dependency := range known {
+			if dependency.PredecessorID != id {
+				continue
+			}
+			successor := timings[dependency.SuccessorID]
+			var latest time.Time
+			switch dependency.Type {
+			case StartToStart:
+				latest = successor.LateStart.Add(-dependency.Lag).Add(duration)
+			case FinishToFinish:
+				latest = successor.LateFinish.Add(-dependency.Lag)
+			default:
+				latest = successor.LateStart.Add(-dependency.Lag)
+			}
+			if latest.Before(lateFinish) {
+				lateFinish = latest
+			}
+		}
+		timing.LateFinish = lateFinish
+		timing.LateStart = lateFinish.Add(-duration)
+		timing.Slack = timing.LateStart.Sub(timing.EarlyStart)
+		timing.Critical = timing.Slack <= 0
+		timings[id] = timing
+	}
+	return timings, nil
+}
+
+// CheckCycle fails when the dependencies form a cycle, the error names the items waiting on it
+func CheckCycle(tasks []Task, dependencies []Dependency) error {
+	_, err := topological(tasks, dependencies)
+	return err
+}
+
+// topological orders the tasks so every predecessor comes before its successors, ties keep the input order
+func topological(tasks []Task, dependencies []Dependency) ([]string, error) {
+	waiting := make(map[string]int, len(tasks))
+	for _, dependency := range dependencies {
+		waiting[dependency.SuccessorID]++
+	}
+
+	order := make([]string, 0, len(tasks))
+	done := make(map[string]bool, len(tasks))
+	for len(order) < len(tasks) {
+		progress := false
+		for _, task := range tasks {
+			if done[task.ID] || waiting[task.ID] > 0 {
+				continue
+			}
+			done[task.ID] = true
+			order = append(order, task.ID)
+			progress = true
+			for _, dependency := range dependencies {
+				if dependency.PredecessorID == task.ID {
+					waiting[dependency.SuccessorID]--
+				}
+			}
+		}
+		if !progress {
+			var stuck []string
+			for _, task := range tasks {
+				if !done[task.ID] {
+					stuck = append(stuck, task.ID)
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(stuck, ", "))
+		}
+	}
+	return order, nil
+}
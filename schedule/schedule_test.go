@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var monday = time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time {
+	return monday.AddDate(0, 0, n)
+}
+
+func TestAnalyzeFindsCriticalPath(t *testing.T) {
+	tasks := []Task{
+		{ID: "design", Start: day(0), End: day(5)},
+		{ID: "implementation", Start: day(5), End: day(20)},
+		{ID: "poster", Start: day(5), End: day(8)},
+		{ID: "report", Start: day(20), End: day(27)},
+	}
+	dependencies := []Dependency{
+		{PredecessorID: "design", SuccessorID: "implementation", Type: FinishToStart},
+		{PredecessorID: "design", SuccessorID: "poster", Type: FinishToStart},
+		{PredecessorID: "implementation", SuccessorID: "report", Type: FinishToStart},
+	}
+
+	timings, err := Analyze(tasks, dependencies)
+
+	assert.Nil(t, err)
+	assert.True(t, timings["design"].Critical)
+	assert.True(t, timings["implementation"].Critical)
+	assert.True(t, timings["report"].Critical)
+	assert.False(t, timings["poster"].Critical)
+	assert.Equal(t, 19*24*time.Hour, timings["poster"].Slack)
+	assert.Equal(t, day(27), timings["poster"].LateFinish)
+}
+
+func TestAnalyzePushesSuccessorsBack(t *testing.T) {
+	tasks := []Task{
+		{ID: "design", Start: day(0), End: day(10)},
+		{ID: "implementation", Start: day(5), End: day(15)}, //planned to overlap with the design
+		{ID: "testing", Start: day(0), End: day(2)},
+	}
+	dependencies := []Dependency{
+		{PredecessorID: "design", SuccessorID: "implementation", Type: FinishToStart, Lag: 48 * time.Hour},
+		{PredecessorID: "implementation", SuccessorID: "testing", Type: FinishToFinish},
+	}
+
+	timings, err := Analyze(tasks, dependencies)
+
+	assert.Nil(t, err)
+	assert.Equal(t, day(12), timings["implementation"].EarlyStart)
+	assert.Equal(t, day(22), timings["implementation"].EarlyFinish)
+	assert.Equal(t, day(20), timings["testing"].EarlyStart)
+	assert.Equal(t, day(22), timings["testing"].EarlyFinish)
+	assert.True(t, timings["testing"].Critical)
+}
+
+func TestAnalyzeStartToStart(t *testing.T) {
+	tasks := []Task{
+		{ID: "writing", Start: day(0), End: day(20)},
+		{ID: "proofreading", Start: day(0), End: day(5)},
+	}
+	dependencies := []Dependency{
+		{PredecessorID: "writing", SuccessorID: "proofreading", Type: StartToStart, Lag: 7 * 24 * time.Hour},
+	}
+
+	timings, err := Analyze(tasks, dependencies)
+
+	assert.Nil(t, err)
+	assert.Equal(t, day(7), timings["proofreading"].EarlyStart)
+	assert.Equal(t, 8*24*time.Hour, timings["proofreading"].Slack)
+	assert.True(t, timings["writing"].Critical)
+}
+
+func TestAnalyzeRejectsCycles(t *testing.T) {
+	tasks := []Task{
+		{ID: "a", Start: day(0), End: day(1)},
+		{ID: "b", Start: day(1), End: day(2)},
+		{ID: "c", Start: day(2), End: day(3)},
+	}
+	dependencies := []Dependency{
+		{PredecessorID: "a", SuccessorID: "b"},
+		{PredecessorID: "b", SuccessorID: "c"},
+		{PredecessorID: "c", SuccessorID: "b"},
+	}
+
+	_, err := Analyze(tasks, dependencies)
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.EqualError(t, CheckCycle(tasks, dependencies), "dependencies form a cycle: b, c")
+	assert.Nil(t, CheckCycle(tasks, dependencies[:2]))
+}
+
+func TestParseDependencyType(t *testing.T) {
+	kind, err := ParseDependencyType("")
+	assert.Nil(t, err)
+	assert.Equal(t, FinishToStart, kind)
+
+	_, err = ParseDependencyType("start_to_finish")
+	assert.NotNil(t, err)
+}